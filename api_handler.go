@@ -15,8 +15,12 @@ import (
 func APIHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	/** APIs
 	 * /api/hatchet/v1.0/hatchets/{hatchet}/logs/all
+	 * /api/hatchet/v1.0/hatchets/{hatchet}/logs/events
 	 * /api/hatchet/v1.0/hatchets/{hatchet}/logs/slowops
+	 * /api/hatchet/v1.0/hatchets/{hatchet}/stats/indexes
+	 * /api/hatchet/v1.0/hatchets/{hatchet}/stats/sharding
 	 * /api/hatchet/v1.0/hatchets/{hatchet}/stats/slowops
+	 * /api/hatchet/v1.0/hatchets/{hatchet}/stats/transactions
 	 */
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
@@ -38,11 +42,56 @@ func APIHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params
 		if orderBy == "" {
 			orderBy = "avg_ms"
 		}
-		ops, err := dbase.GetSlowOps(orderBy, "DESC", false)
+		groupBy := SLOWOP_GROUPS[r.URL.Query().Get("groupBy")]
+		ops, err := dbase.GetSlowOps(orderBy, "DESC", false, groupBy,
+			fmt.Sprintf("duration=%v", r.URL.Query().Get("duration")), fmt.Sprintf("marker=%v", r.URL.Query().Get("marker")))
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
 		}
-		doc := map[string]interface{}{"hatchet": hatchetName, "has_more": false, "offset": 0, "limit": len(ops), "ops": ops}
+		doc := map[string]interface{}{"hatchet": hatchetName, "has_more": false, "offset": 0, "limit": len(ops), "ops": ops,
+			"pipeline_stages": GetPipelineStageStats(ops)}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+		} else {
+			w.Write(b)
+		}
+		return
+	} else if category == "stats" && attr == "indexes" {
+		ops, err := dbase.GetSlowOps("total_ms", "DESC", false, "")
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+			return
+		}
+		doc := map[string]interface{}{"hatchet": hatchetName, "indexes": GetIndexAdvice(ops)}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+		} else {
+			w.Write(b)
+		}
+		return
+	} else if category == "stats" && attr == "transactions" {
+		stats, err := dbase.GetTransactionStats()
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+			return
+		}
+		doc := map[string]interface{}{"hatchet": hatchetName, "transactions": stats}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+		} else {
+			w.Write(b)
+		}
+		return
+	} else if category == "stats" && attr == "sharding" {
+		stats, err := dbase.GetShardingStats()
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+			return
+		}
+		doc := map[string]interface{}{"hatchet": hatchetName, "sharding": stats}
 		b, err := json.Marshal(doc)
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
@@ -55,7 +104,12 @@ func APIHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
 		}
-		doc := map[string]interface{}{"hatchet": hatchetName, "audit": data}
+		startups, err := dbase.GetStartupDigests()
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+			return
+		}
+		doc := map[string]interface{}{"hatchet": hatchetName, "audit": data, "startups": startups}
 		b, err := json.Marshal(doc)
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
@@ -80,19 +134,41 @@ func APIHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params
 			w.Write(b)
 		}
 		return
+	} else if category == "logs" && attr == "events" {
+		events, err := dbase.GetReplEvents(r.URL.Query().Get("type"), r.URL.Query().Get("duration"),
+			r.URL.Query().Get("marker"))
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+			return
+		}
+		lifetimes, err := dbase.GetProcessLifetimes()
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+			return
+		}
+		doc := map[string]interface{}{"hatchet": hatchetName, "events": events, "lifetimes": lifetimes}
+		b, err := json.Marshal(doc)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
+		} else {
+			w.Write(b)
+		}
+		return
 	} else if category == "logs" && attr == "all" {
 		var hasMore bool
 		component := r.URL.Query().Get("component")
 		context := r.URL.Query().Get("context")
 		severity := r.URL.Query().Get("severity")
 		duration := r.URL.Query().Get("duration")
+		marker := r.URL.Query().Get("marker")
 		limit := r.URL.Query().Get("limit")
 		if limit == "" {
 			limit = fmt.Sprintf("%v", LIMIT)
 		}
 		offset, nlimit := GetOffsetLimit(limit)
 		logs, err := dbase.GetLogs(fmt.Sprintf("component=%v", component), fmt.Sprintf("limit=%v", limit),
-			fmt.Sprintf("context=%v", context), fmt.Sprintf("severity=%v", severity), fmt.Sprintf("duration=%v", duration))
+			fmt.Sprintf("context=%v", context), fmt.Sprintf("severity=%v", severity), fmt.Sprintf("duration=%v", duration),
+			fmt.Sprintf("marker=%v", marker))
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]interface{}{"ok": 0, "error": err.Error()})
 			return
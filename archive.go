@@ -0,0 +1,215 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * archive.go
+ */
+
+package hatchet
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/simagix/gox"
+)
+
+const (
+	TAR_ARCHIVE = "tar"
+	ZIP_ARCHIVE = "zip"
+
+	MAX_EXTRACTED_BYTES = 32 << 30 // decompressed bytes of members extracted from an archive
+	SNIFF_SIZE          = 64 << 10 // leading bytes of a member to tell if it is a MongoDB log
+)
+
+// getArchiveType returns the archive type of a tar, tar.gz or zip file, or
+// an empty string if the file is not an archive
+func getArchiveType(filename string) string {
+	file, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	buf := make([]byte, 4)
+	if _, err = io.ReadFull(file, buf); err != nil {
+		return ""
+	}
+	if bytes.Equal(buf, []byte("PK\x03\x04")) {
+		return ZIP_ARCHIVE
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	reader, err := gox.NewReader(file)
+	if err != nil {
+		return ""
+	}
+	header := make([]byte, 512)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return ""
+	}
+	if string(header[257:262]) == "ustar" {
+		return TAR_ARCHIVE
+	}
+	return ""
+}
+
+// ExtractArchive extracts MongoDB logs and FTDC files of an archive to a directory and
+// returns their relative paths in archive order.  Members are sniffed while streamed, so
+// that other files are never written.  Hidden files are skipped, and an error is returned
+// of a duplicate member or when more than limit bytes would be extracted.
+func ExtractArchive(filename string, archiveType string, dir string, limit int64) ([]string, error) {
+	var members []string
+	var extracted int64
+	seen := map[string]bool{}
+	extract := func(name string, reader io.Reader) error {
+		name = strings.TrimPrefix(filepath.Clean("/"+name), "/") // prevent paths outside of dir
+		base := filepath.Base(name)
+		if strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX") {
+			return nil
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate member %v in archive %v", name, filename)
+		}
+		seen[name] = true
+		breader := bufio.NewReaderSize(reader, SNIFF_SIZE)
+		head, _ := breader.Peek(SNIFF_SIZE)
+		if !strings.HasPrefix(base, "metrics.") && !isMongoDBLogHead(head) {
+			log.Printf("skipping %s (not a MongoDB log)", name)
+			return nil
+		}
+		fullPath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return err
+		}
+		file, err := os.Create(fullPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		n, err := io.CopyN(file, breader, limit-extracted+1)
+		extracted += n
+		if extracted > limit {
+			return fmt.Errorf("archive %v exceeds %v bytes extracted", filename, limit)
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		members = append(members, name)
+		return nil
+	}
+
+	if archiveType == ZIP_ARCHIVE {
+		zreader, err := zip.OpenReader(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer zreader.Close()
+		for _, zfile := range zreader.File {
+			if !zfile.Mode().IsRegular() {
+				continue
+			}
+			reader, err := zfile.Open()
+			if err != nil {
+				return members, err
+			}
+			err = extract(zfile.Name, reader)
+			reader.Close()
+			if err != nil {
+				return members, err
+			}
+		}
+		return members, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gox.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	treader := tar.NewReader(reader)
+	for {
+		header, err := treader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return members, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err = extract(header.Name, treader); err != nil {
+			return members, err
+		}
+	}
+	return members, nil
+}
+
+// isMongoDBLogHead checks if leading bytes, compressed or not, begin with a MongoDB log line
+func isMongoDBLogHead(head []byte) bool {
+	reader, err := NewStreamReader(bytes.NewReader(head))
+	if err != nil {
+		return false
+	}
+	return isMongoDBLogReader(reader)
+}
+
+// analyzeArchive analyzes MongoDB logs in an archive, each as its own hatchet
+// or all into one hatchet when merging
+func (ptr *Logv2) analyzeArchive(logname string, archiveType string, marker int) error {
+	tmpDir, err := os.MkdirTemp("", "hatchet_archive")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	log.Println("extracting", archiveType, "archive", logname)
+	members, err := ExtractArchive(logname, archiveType, tmpDir, MAX_EXTRACTED_BYTES)
+	if err != nil {
+		return err
+	}
+	// name members as if the archive were a directory, e.g. replica_rs1 for rs1.log in replica.tar.gz
+	base := filepath.Base(logname)
+	for _, ext := range []string{".gz", ".tgz", ".tar", ".zip"} {
+		base = strings.TrimSuffix(base, ext)
+	}
+	var segments []LogSegment
+	for _, member := range members {
+		if !ptr.isIncluded(member) {
+			continue
+		}
+		segments = append(segments, LogSegment{Filename: filepath.Join(tmpDir, member), Path: filepath.Join(base, member)})
+	}
+	fileCount := 0
+	if ptr.merge {
+		for _, segment := range segments {
+			if !isMongoDBLog(segment.Filename) {
+				log.Printf("skipping %s (not a MongoDB log)", segment.Path)
+				continue
+			}
+			fileCount++
+			if err = ptr.Analyze(segment.Filename, marker); err != nil {
+				return err
+			}
+		}
+	} else {
+		fileCount = ptr.analyzeSegments(segments)
+	}
+	if fileCount == 0 {
+		log.Printf("no MongoDB log files found in archive %s", logname)
+	}
+	if !ptr.merge {
+		// Clear hatchetName so caller knows not to print summary again
+		ptr.hatchetName = ""
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * archive_test.go
+ */
+
+package hatchet
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testArchiveLog = `{"t":{"$date":"2021-07-25T09:56:00.691+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn1","msg":"Test message"}` + "\n"
+
+func TestExtractTarGz(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "bundle.tar.gz")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(file)
+	tw := tar.NewWriter(zw)
+	tw.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, name := range []string{"logs/rs1.log", "logs/._rs1.log", "../rs2.log"} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(testArchiveLog))})
+		tw.Write([]byte(testArchiveLog))
+	}
+	tw.WriteHeader(&tar.Header{Name: "logs/notes.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 10})
+	tw.Write([]byte("not a log\n"))
+	tw.Close()
+	zw.Close()
+	file.Close()
+
+	archiveType := getArchiveType(filename)
+	if archiveType != TAR_ARCHIVE {
+		t.Fatalf("expected %v, got %v", TAR_ARCHIVE, archiveType)
+	}
+	outDir := filepath.Join(tmpDir, "out")
+	members, err := ExtractArchive(filename, archiveType, outDir, MAX_EXTRACTED_BYTES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"logs/rs1.log", "rs2.log"}
+	if !reflect.DeepEqual(members, expected) {
+		t.Fatalf("expected %v, got %v", expected, members)
+	}
+	for _, member := range members {
+		if !isMongoDBLog(filepath.Join(outDir, member)) {
+			t.Fatalf("expected %v to be a MongoDB log", member)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(outDir, "logs", "notes.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected a file other than logs not extracted, got %v", err)
+	}
+}
+
+func TestExtractArchiveLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "bundle.tar")
+	writeTar := func(names ...string) {
+		file, err := os.Create(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		tw := tar.NewWriter(file)
+		defer tw.Close()
+		for _, name := range names {
+			tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(testArchiveLog))})
+			tw.Write([]byte(testArchiveLog))
+		}
+	}
+	writeTar("rs1.log", "rs2.log", "./rs1.log")
+	if _, err := ExtractArchive(filename, TAR_ARCHIVE, filepath.Join(tmpDir, "dup"), MAX_EXTRACTED_BYTES); err == nil ||
+		!strings.Contains(err.Error(), "duplicate member rs1.log") {
+		t.Fatalf("expected an error of a duplicate member, got %v", err)
+	}
+	writeTar("rs1.log", "rs2.log")
+	limit := int64(len(testArchiveLog) + 10)
+	if _, err := ExtractArchive(filename, TAR_ARCHIVE, filepath.Join(tmpDir, "big"), limit); err == nil ||
+		!strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected an error of exceeding the limit, got %v", err)
+	}
+	if members, err := ExtractArchive(filename, TAR_ARCHIVE, filepath.Join(tmpDir, "ok"), 2*int64(len(testArchiveLog))); err != nil ||
+		len(members) != 2 {
+		t.Fatalf("expected 2 members within the limit, got %v %v", members, err)
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "bundle.zip")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(file)
+	for _, name := range []string{"mongod.log", "__MACOSX/mongod.log", "README.txt"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(testArchiveLog))
+	}
+	zw.Close()
+	file.Close()
+
+	archiveType := getArchiveType(filename)
+	if archiveType != ZIP_ARCHIVE {
+		t.Fatalf("expected %v, got %v", ZIP_ARCHIVE, archiveType)
+	}
+	members, err := ExtractArchive(filename, archiveType, tmpDir, MAX_EXTRACTED_BYTES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"mongod.log", "README.txt"}
+	if !reflect.DeepEqual(members, expected) {
+		t.Fatalf("expected %v, got %v", expected, members)
+	}
+	if archiveType = getArchiveType(filepath.Join(tmpDir, "mongod.log")); archiveType != "" {
+		t.Fatalf("expected a log file not to be an archive, got %v", archiveType)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * atlas.go
+ */
+
+package hatchet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ATLAS_API_URL       = "https://cloud.mongodb.com/api/atlas/v2"
+	ATLAS_ACCEPT_GZIP   = "application/vnd.atlas.2023-02-01+gzip"
+	ATLAS_ACCEPT_JSON   = "application/vnd.atlas.2023-01-01+json"
+	ATLAS_LOG_RETENTION = 30 * 24 * time.Hour // Atlas keeps logs of the last 30 days
+	ATLAS_MAX_RETRIES   = 5
+	ATLAS_PAGE_SIZE     = 500
+	ATLAS_WINDOW        = time.Hour
+)
+
+// AtlasProcess is a mongod or mongos process of an Atlas project
+type AtlasProcess struct {
+	Hostname       string `json:"hostname"`
+	Port           int    `json:"port"`
+	ReplicaSetName string `json:"replicaSetName"`
+	TypeName       string `json:"typeName"`
+	UserAlias      string `json:"userAlias"`
+}
+
+// AtlasClient downloads logs of a cluster from the Atlas Admin API
+type AtlasClient struct {
+	backoff    time.Duration // first retry delay, doubled on each retry
+	baseURL    string
+	client     *http.Client
+	cluster    string
+	groupID    string
+	hosts      []string
+	pageSize   int
+	privateKey string
+	publicKey  string
+	retries    int
+	window     time.Duration
+}
+
+// NewAtlasClient returns an Atlas client of a project/cluster, e.g. 5f1a2b3c4d5e6f7a8b9c0d1e/Cluster0,
+// authenticated by a public:private API key pair and limited to comma-separated hosts if given
+func NewAtlasClient(baseURL string, atlas string, user string, hosts string) (*AtlasClient, error) {
+	toks := strings.Split(atlas, "/")
+	if len(toks) != 2 || toks[0] == "" || toks[1] == "" {
+		return nil, fmt.Errorf("invalid Atlas project ID and cluster name %v, expected <project ID>/<cluster>", atlas)
+	}
+	keys := strings.Split(user, ":")
+	if len(keys) != 2 {
+		return nil, errors.New("Atlas API keys are required, use -user <public key>:<private key>")
+	}
+	client := &AtlasClient{backoff: time.Second, baseURL: strings.TrimSuffix(baseURL, "/"),
+		client: newHTTPClient(HTTP_TIMEOUT), groupID: toks[0], cluster: toks[1], pageSize: ATLAS_PAGE_SIZE, publicKey: keys[0], privateKey: keys[1],
+		retries: ATLAS_MAX_RETRIES, window: ATLAS_WINDOW}
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			client.hosts = append(client.hosts, host)
+		}
+	}
+	return client, nil
+}
+
+// SetWindow sets the time span of each log download
+func (ptr *AtlasClient) SetWindow(window time.Duration) {
+	if window > 0 {
+		ptr.window = window
+	}
+}
+
+// SetTimeout sets the connect and response timeout of requests
+func (ptr *AtlasClient) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		ptr.client = newHTTPClient(timeout)
+	}
+}
+
+// GetProcesses returns processes of the cluster, page by page, limited to the hosts if given
+func (ptr *AtlasClient) GetProcesses() ([]AtlasProcess, error) {
+	var processes []AtlasProcess
+	for pageNum, count := 1, 0; ; pageNum++ {
+		url := fmt.Sprintf("%v/groups/%v/processes?pageNum=%d&itemsPerPage=%d", ptr.baseURL, ptr.groupID, pageNum, ptr.pageSize)
+		resp, err := ptr.get(url, ATLAS_ACCEPT_JSON)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Results    []AtlasProcess `json:"results"`
+			TotalCount int            `json:"totalCount"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, process := range page.Results {
+			if ptr.isOfCluster(process) && ptr.isSelected(process) {
+				processes = append(processes, process)
+			}
+		}
+		count += len(page.Results)
+		if len(page.Results) == 0 || count >= page.TotalCount {
+			break
+		}
+	}
+	if len(processes) == 0 {
+		return nil, fmt.Errorf("no processes found of cluster %v in project %v", ptr.cluster, ptr.groupID)
+	}
+	return processes, nil
+}
+
+// isOfCluster checks if a process belongs to the cluster by its hostname or user alias, as hostnames
+// of newer clusters, e.g. atlas-abc123-shard-00-00, don't begin with the cluster name
+func (ptr *AtlasClient) isOfCluster(process AtlasProcess) bool {
+	prefix := strings.ToLower(ptr.cluster) + "-"
+	return strings.HasPrefix(strings.ToLower(process.Hostname), prefix) ||
+		strings.HasPrefix(strings.ToLower(process.UserAlias), prefix)
+}
+
+// isSelected checks if a process is in the host list, by its hostname, user alias or their
+// first labels, e.g. cluster0-shard-00-00
+func (ptr *AtlasClient) isSelected(process AtlasProcess) bool {
+	if len(ptr.hosts) == 0 {
+		return true
+	}
+	for _, host := range ptr.hosts {
+		for _, name := range []string{process.Hostname, process.UserAlias} {
+			if name != "" && (strings.EqualFold(host, name) || strings.EqualFold(host, strings.Split(name, ".")[0])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getAtlasNodeName returns the first label of the user alias, or of the hostname without one
+func getAtlasNodeName(process AtlasProcess) string {
+	if process.UserAlias != "" {
+		return strings.Split(process.UserAlias, ".")[0]
+	}
+	return strings.Split(process.Hostname, ".")[0]
+}
+
+// DownloadLogs writes gzip logs of a process between start and end to a writer, window by
+// window, and returns the number of bytes written.  Concatenated gzip members read as one log.
+func (ptr *AtlasClient) DownloadLogs(process AtlasProcess, start time.Time, end time.Time, writer io.Writer) (int64, error) {
+	logName := "mongodb"
+	if process.TypeName == "SHARD_MONGOS" {
+		logName = "mongos"
+	}
+	var total int64
+	for from := start; from.Before(end); from = from.Add(ptr.window) {
+		to := from.Add(ptr.window)
+		if to.After(end) {
+			to = end
+		}
+		url := fmt.Sprintf("%v/groups/%v/clusters/%v/logs/%v.gz?startDate=%d&endDate=%d",
+			ptr.baseURL, ptr.groupID, process.Hostname, logName, from.Unix(), to.Unix())
+		resp, err := ptr.get(url, ATLAS_ACCEPT_GZIP)
+		if err != nil {
+			return total, err
+		}
+		n, err := io.Copy(writer, resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// get sends a digest authenticated GET request and retries on 429 and 5xx
+func (ptr *AtlasClient) get(url string, accept string) (*http.Response, error) {
+	headers := map[string]string{"Accept": accept}
+	delay := ptr.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := httpGet(context.Background(), ptr.client, url, ptr.publicKey, ptr.privateKey, true, headers)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500) || attempt >= ptr.retries {
+			return nil, fmt.Errorf("http failed: %v %v", resp.Status, url)
+		}
+		wait := delay
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+		log.Printf("%v, retry in %v", resp.Status, wait)
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// AnalyzeAtlas downloads logs of an Atlas cluster between from and to, and analyzes
+// each node as its own hatchet, e.g. Cluster0_cluster0_shard_00_00, or all in one
+// hatchet when merging
+func (ptr *Logv2) AnalyzeAtlas(client *AtlasClient) error {
+	end := ptr.to
+	if end.After(time.Now()) {
+		end = time.Now()
+	}
+	start := ptr.from
+	if start.Before(end.Add(-ATLAS_LOG_RETENTION)) {
+		start = end.Add(-ATLAS_LOG_RETENTION)
+	}
+	processes, err := client.GetProcesses()
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "hatchet_atlas")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	var segments []LogSegment
+	for _, process := range processes {
+		host := getAtlasNodeName(process)
+		filename := filepath.Join(tmpDir, host+".log.gz")
+		log.Printf("downloading logs of %v from %v to %v", process.Hostname, getDateTimeStr(start), getDateTimeStr(end))
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		n, err := client.DownloadLogs(process, start, end, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			log.Printf("no logs of %v", process.Hostname)
+			continue
+		}
+		segments = append(segments, LogSegment{Filename: filename, Path: filepath.Join(client.cluster, host)})
+	}
+	fileCount := 0
+	if ptr.merge {
+		for _, segment := range segments {
+			if !isMongoDBLog(segment.Filename) {
+				log.Printf("skipping %s (not a MongoDB log)", segment.Path)
+				continue
+			}
+			fileCount++
+			if err = ptr.Analyze(segment.Filename, 0); err != nil {
+				return err
+			}
+		}
+	} else {
+		fileCount = ptr.analyzeSegments(segments)
+		// Clear hatchetName so caller knows not to print summary again
+		ptr.hatchetName = ""
+	}
+	if fileCount == 0 {
+		log.Printf("no MongoDB logs found of cluster %v", client.cluster)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * atlas_test.go
+ */
+
+package hatchet
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newAtlasServer stands in for the Atlas Admin API with a log line every 30 minutes of
+// each host, rate limiting and failing the first authenticated log download of each host
+func newAtlasServer(t *testing.T, start time.Time) *httptest.Server {
+	processes := []AtlasProcess{
+		{Hostname: "cluster0-shard-00-00.abcde.mongodb.net", Port: 27017, TypeName: "REPLICA_PRIMARY"},
+		{Hostname: "other-shard-00-00.fghij.mongodb.net", Port: 27017, TypeName: "REPLICA_PRIMARY"},
+		{Hostname: "cluster0-shard-00-01.abcde.mongodb.net", Port: 27017, TypeName: "REPLICA_SECONDARY"},
+		{Hostname: "atlas-x1y2z3-shard-00-02.abcde.mongodb.net", Port: 27017, TypeName: "REPLICA_SECONDARY",
+			UserAlias: "cluster0-shard-00-02.abcde.mongodb.net"},
+	}
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), `Digest username="public"`) {
+			w.Header().Set("WWW-Authenticate", `Digest realm="MMS Public API", nonce="abc123", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/groups/grp/processes" {
+			pageNum, _ := strconv.Atoi(r.URL.Query().Get("pageNum"))
+			itemsPerPage, _ := strconv.Atoi(r.URL.Query().Get("itemsPerPage"))
+			from := min((pageNum-1)*itemsPerPage, len(processes))
+			to := min(from+itemsPerPage, len(processes))
+			json.NewEncoder(w).Encode(map[string]interface{}{"results": processes[from:to], "totalCount": len(processes)})
+			return
+		}
+		toks := strings.Split(r.URL.Path, "/") // /groups/grp/clusters/{host}/logs/mongodb.gz
+		if len(toks) != 7 || toks[6] != "mongodb.gz" || r.Header.Get("Accept") != ATLAS_ACCEPT_GZIP {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		host := toks[4]
+		mu.Lock()
+		attempts[host]++
+		n := attempts[host]
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		} else if n == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		startDate, _ := strconv.ParseInt(r.URL.Query().Get("startDate"), 10, 64)
+		endDate, _ := strconv.ParseInt(r.URL.Query().Get("endDate"), 10, 64)
+		zw := gzip.NewWriter(w)
+		for tm := start; tm.Before(start.Add(3 * time.Hour)); tm = tm.Add(30 * time.Minute) {
+			if tm.Unix() >= startDate && tm.Unix() < endDate {
+				fmt.Fprintf(zw, `{"t":{"$date":"%v"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"10.0.0.1:5000","connectionCount":1}}`+"\n",
+					tm.Format("2006-01-02T15:04:05.000Z07:00"))
+			}
+		}
+		zw.Close()
+	}))
+}
+
+func TestAtlasClient(t *testing.T) {
+	start := time.Date(2024, 3, 18, 10, 0, 0, 0, time.UTC)
+	server := newAtlasServer(t, start)
+	defer server.Close()
+	client, err := NewAtlasClient(server.URL, "grp/Cluster0", "public:private", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.backoff = time.Millisecond
+	client.pageSize = 2
+	processes, err := client.GetProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 3 || processes[1].Hostname != "cluster0-shard-00-01.abcde.mongodb.net" ||
+		getAtlasNodeName(processes[2]) != "cluster0-shard-00-02" {
+		t.Fatalf("expected 3 processes of Cluster0 from 2 pages, got %v", processes)
+	}
+	client.hosts = []string{"cluster0-shard-00-02"}
+	if processes, err = client.GetProcesses(); err != nil || len(processes) != 1 || !strings.HasPrefix(processes[0].Hostname, "atlas-") {
+		t.Fatalf("expected 1 process selected by its user alias, got %v %v", processes, err)
+	}
+	client.hosts = []string{"cluster0-shard-00-01"}
+	if processes, err = client.GetProcesses(); err != nil || len(processes) != 1 {
+		t.Fatalf("expected 1 selected process, got %v %v", processes, err)
+	}
+	client.retries = 1
+	if _, err = client.DownloadLogs(processes[0], start, start.Add(time.Hour), &strings.Builder{}); err == nil {
+		t.Fatal("expected failure after retries")
+	}
+	if _, err = NewAtlasClient(server.URL, "grp", "public:private", ""); err == nil {
+		t.Fatal("expected an error of missing cluster name")
+	}
+}
+
+func TestAtlasClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer server.Close()
+	client, err := NewAtlasClient(server.URL, "grp/Cluster0", "public:private", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTimeout(50 * time.Millisecond)
+	if _, err = client.GetProcesses(); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestAnalyzeAtlas(t *testing.T) {
+	useTestDatabase(t)
+	start := time.Date(2024, 3, 18, 10, 0, 0, 0, time.UTC)
+	server := newAtlasServer(t, start)
+	defer server.Close()
+	client, err := NewAtlasClient(server.URL, "grp/Cluster0", "public:private", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.backoff = time.Millisecond
+	logv2 := &Logv2{testing: true, url: instance.url, from: start, to: start.Add(3 * time.Hour)}
+	if err = logv2.AnalyzeAtlas(client); err != nil {
+		t.Fatal(err)
+	}
+	names, err := GetExistingHatchetNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("expected a hatchet of each node, got %v", names)
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, "Cluster0_cluster0_shard_00_0") {
+			t.Fatalf("unexpected hatchet name %v", name)
+		}
+		dbase, err := NewSQLite3DB(instance.url, name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count int
+		if err = dbase.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %v", name)).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		info := dbase.GetHatchetInfo()
+		dbase.Close()
+		if count != 6 || !strings.HasPrefix(info.Start, "2024-03-18T10:00:00") || !strings.HasPrefix(info.End, "2024-03-18T12:30:00") {
+			t.Fatalf("expected 6 logs of 3 windows of %v, got %d from %v to %v", name, count, info.Start, info.End)
+		}
+	}
+
+	merged := &Logv2{testing: true, url: instance.url, from: start, to: start.Add(3 * time.Hour), merge: true, hatchetName: "atlas_merged"}
+	if err = merged.AnalyzeAtlas(client); err != nil {
+		t.Fatal(err)
+	}
+	dbase, err := NewSQLite3DB(instance.url, "atlas_merged", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbase.Close()
+	var count int
+	if err = dbase.db.QueryRow("SELECT COUNT(*) FROM atlas_merged").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 18 {
+		t.Fatalf("expected 18 logs merged from 3 nodes, got %d", count)
+	}
+}
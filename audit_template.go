@@ -64,6 +64,12 @@ func GetAuditTablesTemplate(download string) (*template.Template, error) {
 		<div style='font-size: 1.8em; font-weight: bold; color: #c62828;'>{{getCollscanCount .Data}}</div>
 	</div>
 	{{end}}
+	{{if hasData .Data "authfail"}}
+	<div style='background: linear-gradient(135deg, #fce4ec 0%, #f8bbd0 100%); padding: 15px 25px; border-radius: 12px; flex: 1; min-width: 180px; box-shadow: 0 2px 4px rgba(0,0,0,0.1);'>
+		<div style='font-size: 0.85em; color: #666; text-transform: uppercase; letter-spacing: 0.5px;'>Auth Failures</div>
+		<div style='font-size: 1.8em; font-weight: bold; color: #ad1457;'>{{getAuthFailures .Data}}</div>
+	</div>
+	{{end}}
 </div>
 
 <div style='margin: 5px 5px; width=100%; clear: left;'>
@@ -77,7 +83,7 @@ func GetAuditTablesTemplate(download string) (*template.Template, error) {
 	  </table>
 	</div>
 <!-- Security & Warnings Section -->
-{{if or (hasData .Data "exception") (hasData .Data "driver") (hasData .Data "failed")}}
+{{if or (hasData .Data "exception") (hasData .Data "driver") (hasData .Data "failed") (hasData .Data "errors")}}
 <div style='clear: both; height: 30px;'></div>
 <h3 style='margin: 10px 10px 10px 10px; color: #555; border-bottom: 2px solid #ddd; padding-bottom: 8px;'>
 	<i class='fa fa-shield' style='color: #c62828;'></i> Security & Warnings
@@ -137,6 +143,105 @@ func GetAuditTablesTemplate(download string) (*template.Template, error) {
 	</table>
 {{end}}
 
+{{if hasData .Data "errors"}}
+	<table style='float: left; margin: 10px 10px; clear: left;'>
+		<caption><button class='btn'
+			onClick="javascript:loadData('/hatchets/{{.Hatchet}}/charts/errors?type=rate'); return false;">
+			<i class='fa fa-bar-chart'></i></button>Errors</caption>
+		<tr><th></th><th>Error</th><th>Code</th><th>Category</th><th>Total</th><th>Namespaces</th><th>Last Seen</th></tr>
+	{{range $n, $val := index .Data "errors"}}
+		<tr><td align=right>{{add $n 1}}</td>
+			<td>
+				<button class='btn' onClick="javascript:loadData('/hatchets/{{$name}}/logs/all?context={{$val.Name}}'); return false;"><i class='fa fa-search'></i></button>{{$val.Name}}
+			</td>
+			<td align=right>{{index $val.Values 0}}</td><td>{{index $val.Values 1}}</td>
+			<td align=right>{{getFormattedNumber $val.Values 2}}</td><td align=right>{{getFormattedNumber $val.Values 3}}</td>
+			<td>{{index $val.Values 4}}</td>
+		</tr>
+	{{end}}
+	</table>
+{{end}}
+
+{{if hasData .Data "error-sources"}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-crosshairs"></i></span>Errors by Namespaces, AppNames and IPs</caption>
+		<tr><th></th><th>Error</th><th>Namespace</th><th>AppName</th><th>IP</th><th>Total</th></tr>
+	{{range $n, $val := index .Data "error-sources"}}
+		<tr><td align=right>{{add $n 1}}</td>
+			<td>{{$val.Name}}</td><td class='break'>{{index $val.Values 0}}</td><td>{{index $val.Values 1}}</td>
+			<td>{{index $val.Values 2}}</td><td align=right>{{getFormattedNumber $val.Values 3}}</td>
+		</tr>
+	{{end}}
+	</table>
+{{end}}
+
+<!-- Audit Log Section -->
+{{if or (hasData .Data "authfail") (hasData .Data "privilege") (hasData .Data "ddl") (hasData .Data "user")}}
+<div style='clear: both; height: 30px;'></div>
+<h3 style='margin: 10px 10px 10px 10px; color: #555; border-bottom: 2px solid #ddd; padding-bottom: 8px;'>
+	<i class='fa fa-user-secret' style='color: #ad1457;'></i> Audit Log
+</h3>
+{{end}}
+
+{{if hasData .Data "authfail"}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-lock"></i></span>Authentication Failures</caption>
+		<tr><th></th><th>User</th><th>Action</th><th>IP</th><th>Total</th><th>Last Seen</th></tr>
+	{{range $n, $val := index .Data "authfail"}}
+		<tr><td align=right>{{add $n 1}}</td>
+			<td>{{$val.Name}}</td><td>{{index $val.Values 0}}</td><td>{{index $val.Values 1}}</td>
+			<td align=right>{{getFormattedNumber $val.Values 2}}</td><td>{{index $val.Values 3}}</td>
+		</tr>
+	{{end}}
+	</table>
+{{end}}
+
+{{if hasData .Data "privilege"}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-key"></i></span>Privilege Changes</caption>
+		<tr><th></th><th>Date</th><th>Action</th><th>By</th><th>Target</th><th>Result</th></tr>
+	{{range $n, $val := index .Data "privilege"}}
+		<tr><td align=right>{{add $n 1}}</td>
+			<td>{{index $val.Values 0}}</td><td>{{$val.Name}}</td><td>{{index $val.Values 1}}</td>
+			<td>{{index $val.Values 2}}</td>
+			{{if eq (index $val.Values 3) 0}}
+				<td align='center'><i class='fa fa-check'></i></td>
+			{{else}}
+				<td align='center'><mark>{{index $val.Values 3}}</mark></td>
+			{{end}}
+		</tr>
+	{{end}}
+	</table>
+{{end}}
+
+{{if hasData .Data "ddl"}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-database"></i></span>DDL Events</caption>
+		<tr><th></th><th>Action</th><th>Target</th><th>Total</th><th>Last Seen</th></tr>
+	{{range $n, $val := index .Data "ddl"}}
+		<tr><td align=right>{{add $n 1}}</td>
+			<td>{{$val.Name}}</td><td>{{index $val.Values 0}}</td>
+			<td align=right>{{getFormattedNumber $val.Values 1}}</td><td>{{index $val.Values 2}}</td>
+		</tr>
+	{{end}}
+	</table>
+{{end}}
+
+{{if hasData .Data "user"}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-users"></i></span>Activities by Users</caption>
+		<tr><th></th><th>User</th><th>Events</th><th>Failed</th><th>Actions</th><th>First Seen</th><th>Last Seen</th></tr>
+	{{range $n, $val := index .Data "user"}}
+		<tr><td align=right>{{add $n 1}}</td>
+			<td>{{$val.Name}}</td>
+			<td align=right>{{getFormattedNumber $val.Values 0}}</td><td align=right>{{getFormattedNumber $val.Values 1}}</td>
+			<td align=right>{{getFormattedNumber $val.Values 2}}</td>
+			<td>{{index $val.Values 3}}</td><td>{{index $val.Values 4}}</td>
+		</tr>
+	{{end}}
+	</table>
+{{end}}
+
 <!-- Connections & Clients Section -->
 {{if or (hasData .Data "ip") (hasData .Data "duration")}}
 <div style='clear: both; height: 30px;'></div>
@@ -233,6 +338,45 @@ func GetAuditTablesTemplate(download string) (*template.Template, error) {
 		<td align=right>{{getFormattedNumber $val.Values 0}}</td><td align=right>{{getFormattedSize $val.Values 1}}</td></tr>
 	{{end}}
 	</table>
+{{end}}
+
+<!-- Startup Configuration Section -->
+{{if .Startups}}
+<div style='clear: both; height: 30px;'></div>
+<h3 style='margin: 10px 10px 10px 10px; color: #555; border-bottom: 2px solid #ddd; padding-bottom: 8px;'>
+	<i class='fa fa-power-off' style='color: #1565c0;'></i> Startup Configuration
+</h3>
+{{range $i, $digest := .Startups}}
+	<p style='clear: left; margin: 10px 10px;'><b>{{if $digest.Process}}Restarted{{else}}Configuration logged{{end}} at {{$digest.Date}}</b>
+		{{range $digest.Process}} &bull; {{.Name}}: {{.Value}}{{end}}{{if $digest.FCV}} &bull; FCV: {{$digest.FCV}}{{end}}</p>
+	{{if $digest.Warnings}}
+	<table style='float: left; margin: 10px 10px; clear: left;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-exclamation-triangle"></i></span>Startup Warnings</caption>
+		<tr><th></th><th>Warning</th><th>Message</th></tr>
+	{{range $n, $val := $digest.Warnings}}
+		<tr><td align=right>{{add $n 1}}</td><td>{{$val.Name}}</td><td class='break'>{{$val.Value}}</td></tr>
+	{{end}}
+	</table>
+	{{end}}
+	{{if $digest.Options}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-terminal"></i></span>Options Set by Command Line</caption>
+		<tr><th></th><th>Option</th><th>Value</th></tr>
+	{{range $n, $val := $digest.Options}}
+		<tr><td align=right>{{add $n 1}}</td><td>{{$val.Name}}</td><td class='break'>{{$val.Value}}</td></tr>
+	{{end}}
+	</table>
+	{{end}}
+	{{if $digest.Storage}}
+	<table style='float: left; margin: 10px 10px;'>
+		<caption><span style="font-size: 16px; padding: 5px 5px;"><i class="fa fa-database"></i></span>Storage Engine</caption>
+		<tr><th></th><th>Setting</th><th>Value</th></tr>
+	{{range $n, $val := $digest.Storage}}
+		<tr><td align=right>{{add $n 1}}</td><td>{{$val.Name}}</td><td class='break'>{{$val.Value}}</td></tr>
+	{{end}}
+	</table>
+	{{end}}
+{{end}}
 {{end}}
 	<div style='clear: left;' align='center'><hr/><p/>{{.Version}}</div>
 `
@@ -290,6 +434,14 @@ func GetAuditTablesTemplate(download string) (*template.Template, error) {
 			seconds := etime.Unix() - stime.Unix()
 			return gox.GetDurationFromSeconds(float64(seconds))
 		},
+		"getAuthFailures": func(data map[string][]NameValues) string {
+			printer := message.NewPrinter(language.English)
+			total := 0
+			for _, item := range data["authfail"] {
+				total += item.Values[2].(int)
+			}
+			return printer.Sprintf("%d", total)
+		},
 		"getUniqueClients": func(data map[string][]NameValues) string {
 			return fmt.Sprintf("%d", len(data["ip"]))
 		},
@@ -490,6 +642,14 @@ func GetAuditTablesTemplate(download string) (*template.Template, error) {
 							}
 						}
 					}
+				} else if key == "authfail" && len(docs) > 0 {
+					failures := 0
+					for _, doc := range docs {
+						failures += doc.Values[2].(int)
+					}
+					html += printer.Sprintf("From the audit log, <mark>there were <span style='color: orange;'>%d</span> failed authentication or authorization attempts</mark>, see Authentication Failures table below. ", failures)
+				} else if key == "privilege" && len(docs) > 0 {
+					html += printer.Sprintf("Users and roles were changed <span style='color: orange;'>%d</span> times, please make sure these privilege changes were expected. ", len(docs))
 				} else if key == "collscan" && len(docs) > 0 {
 					html += "Let's move to the performance evaluation. "
 					for _, doc := range docs {
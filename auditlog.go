@@ -0,0 +1,165 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * auditlog.go
+ */
+
+package hatchet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	AUDIT_AUTH      = "auth"
+	AUDIT_DDL       = "ddl"
+	AUDIT_OTHER     = "other"
+	AUDIT_PRIVILEGE = "privilege"
+)
+
+// categories of audit event action types
+var auditCategories = map[string]string{
+	"authenticate": AUDIT_AUTH,
+	"authCheck":    AUDIT_AUTH,
+	"logout":       AUDIT_AUTH,
+
+	"createUser":               AUDIT_PRIVILEGE,
+	"dropUser":                 AUDIT_PRIVILEGE,
+	"dropAllUsersFromDatabase": AUDIT_PRIVILEGE,
+	"updateUser":               AUDIT_PRIVILEGE,
+	"grantRolesToUser":         AUDIT_PRIVILEGE,
+	"revokeRolesFromUser":      AUDIT_PRIVILEGE,
+	"createRole":               AUDIT_PRIVILEGE,
+	"updateRole":               AUDIT_PRIVILEGE,
+	"dropRole":                 AUDIT_PRIVILEGE,
+	"dropAllRolesFromDatabase": AUDIT_PRIVILEGE,
+	"grantRolesToRole":         AUDIT_PRIVILEGE,
+	"revokeRolesFromRole":      AUDIT_PRIVILEGE,
+	"grantPrivilegesToRole":    AUDIT_PRIVILEGE,
+	"revokePrivilegesFromRole": AUDIT_PRIVILEGE,
+	"createCollection":         AUDIT_DDL,
+	"createDatabase":           AUDIT_DDL,
+	"createIndex":              AUDIT_DDL,
+	"renameCollection":         AUDIT_DDL,
+	"dropCollection":           AUDIT_DDL,
+	"dropDatabase":             AUDIT_DDL,
+	"dropIndex":                AUDIT_DDL,
+	"enableSharding":           AUDIT_DDL,
+	"shardCollection":          AUDIT_DDL,
+	"refineCollectionShardKey": AUDIT_DDL,
+	"reshardCollection":        AUDIT_DDL,
+	"createView":               AUDIT_DDL,
+	"dropView":                 AUDIT_DDL,
+	"collMod":                  AUDIT_DDL,
+	"importCollection":         AUDIT_DDL,
+}
+
+// AuditEvent stores an event of MongoDB auditLog
+type AuditEvent struct {
+	AType     string    `bson:"atype"`
+	Category  string    `bson:"category"`
+	Param     string    `bson:"param"`  // param in relaxed extended JSON
+	Remote    string    `bson:"remote"` // client IP
+	Result    int       `bson:"result"`
+	Roles     string    `bson:"roles"`  // comma-separated role@db
+	Target    string    `bson:"target"` // namespace, user or role acted upon
+	Timestamp time.Time `bson:"ts"`
+	User      string    `bson:"user"` // comma-separated user@db
+
+	Marker int
+}
+
+// auditRecord is an auditLog line in JSON format
+type auditRecord struct {
+	AType     string    `bson:"atype"`
+	Param     bson.M    `bson:"param"`
+	Remote    bson.M    `bson:"remote"`
+	Result    int       `bson:"result"`
+	Roles     []bson.M  `bson:"roles"`
+	Timestamp time.Time `bson:"ts"`
+	Users     []bson.M  `bson:"users"`
+
+	// top-level fields of logv2, absent in auditLog
+	Component interface{} `bson:"c"`
+	Message   interface{} `bson:"msg"`
+	Severity  interface{} `bson:"s"`
+	Time      interface{} `bson:"t"`
+}
+
+// isAuditLog checks if a line may be an event of MongoDB auditLog in JSON format, not a logv2
+// line beginning with its timestamp.  ParseAuditLog checks the top-level fields of the event.
+func isAuditLog(line string) bool {
+	return strings.HasPrefix(line, "{") && !strings.HasPrefix(line, `{"t":`) && strings.Contains(line, `"atype"`)
+}
+
+// GetAuditCategory returns the category of an audit action type
+func GetAuditCategory(atype string) string {
+	if category, ok := auditCategories[atype]; ok {
+		return category
+	}
+	return AUDIT_OTHER
+}
+
+// ParseAuditLog parses an auditLog line of atype and ts at the top level without t, s, c and msg
+// of logv2, e.g.
+// {"atype":"authenticate","ts":{"$date":"2023-05-01T10:00:00.000+00:00"},"remote":{"ip":"10.0.0.1","port":52010},
+// "users":[],"roles":[],"param":{"user":"app","db":"admin","mechanism":"SCRAM-SHA-256"},"result":18}
+func ParseAuditLog(line string, event *AuditEvent) error {
+	var record auditRecord
+	if err := bson.UnmarshalExtJSON([]byte(line), false, &record); err != nil {
+		return err
+	}
+	if record.AType == "" || record.Timestamp.IsZero() || record.Time != nil || record.Severity != nil ||
+		record.Component != nil || record.Message != nil {
+		return errors.New("not a MongoDB audit log")
+	}
+	event.AType = record.AType
+	event.Category = GetAuditCategory(record.AType)
+	event.Result = record.Result
+	event.Timestamp = record.Timestamp
+	event.Remote, _ = record.Remote["ip"].(string)
+	event.User = joinAuditNames(record.Users, "user")
+	event.Roles = joinAuditNames(record.Roles, "role")
+	event.Target = getAuditTarget(record.Param)
+	if event.User == "" && event.AType == "authenticate" {
+		event.User = event.Target // users are empty on failed authentication
+	}
+	if len(record.Param) > 0 {
+		if data, err := bson.MarshalExtJSON(record.Param, false, false); err == nil {
+			event.Param = string(data)
+		}
+	}
+	return nil
+}
+
+// joinAuditNames returns comma-separated names of users or roles, e.g. app@admin
+func joinAuditNames(docs []bson.M, key string) string {
+	var names []string
+	for _, doc := range docs {
+		name := fmt.Sprintf("%v", doc[key])
+		if db, ok := doc["db"].(string); ok && db != "" {
+			name += "@" + db
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+// getAuditTarget returns the namespace, user or role an action was performed on
+func getAuditTarget(param bson.M) string {
+	db, _ := param["db"].(string)
+	if ns, ok := param["ns"].(string); ok {
+		return ns
+	} else if old, ok := param["old"].(string); ok {
+		return fmt.Sprintf("%v -> %v", old, param["new"])
+	} else if user, ok := param["user"].(string); ok {
+		return user + "@" + db
+	} else if role, ok := param["role"].(string); ok {
+		return role + "@" + db
+	}
+	return db
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * auditlog_test.go
+ */
+
+package hatchet
+
+import (
+	"strings"
+	"testing"
+)
+
+var auditLogs = []string{
+	`{"atype":"authenticate","ts":{"$date":"2023-05-01T10:00:00.000+00:00"},"local":{"ip":"10.0.0.5","port":27017},"remote":{"ip":"10.0.0.1","port":52010},"users":[],"roles":[],"param":{"user":"app","db":"admin","mechanism":"SCRAM-SHA-256"},"result":18}`,
+	`{"atype":"authenticate","ts":{"$date":"2023-05-01T10:00:01.000+00:00"},"local":{"ip":"10.0.0.5","port":27017},"remote":{"ip":"10.0.0.1","port":52011},"users":[],"roles":[],"param":{"user":"app","db":"admin","mechanism":"SCRAM-SHA-256"},"result":18}`,
+	`{"atype":"authenticate","ts":{"$date":"2023-05-01T10:00:02.000+00:00"},"local":{"ip":"10.0.0.5","port":27017},"remote":{"ip":"10.0.0.2","port":52012},"users":[{"user":"admin","db":"admin"}],"roles":[{"role":"root","db":"admin"}],"param":{"user":"admin","db":"admin","mechanism":"SCRAM-SHA-256"},"result":0}`,
+	`{"atype":"createUser","ts":{"$date":"2023-05-01T10:01:00.000+00:00"},"local":{"ip":"10.0.0.5","port":27017},"remote":{"ip":"10.0.0.2","port":52012},"users":[{"user":"admin","db":"admin"}],"roles":[{"role":"root","db":"admin"}],"param":{"user":"reporter","db":"test","roles":[{"role":"read","db":"test"}]},"result":0}`,
+	`{"atype":"renameCollection","ts":{"$date":"2023-05-01T10:02:00.000+00:00"},"local":{"ip":"10.0.0.5","port":27017},"remote":{"ip":"10.0.0.2","port":52012},"users":[{"user":"admin","db":"admin"}],"roles":[{"role":"root","db":"admin"}],"param":{"old":"test.orders","new":"test.orders_2023"},"result":0}`,
+	`{"atype":"dropCollection","ts":{"$date":"2023-05-01T10:03:00.000+00:00"},"local":{"ip":"10.0.0.5","port":27017},"remote":{"ip":"10.0.0.2","port":52012},"users":[{"user":"admin","db":"admin"}],"roles":[{"role":"root","db":"admin"}],"param":{"ns":"test.tmp"},"result":0}`,
+}
+
+// a slow query filtering on atype, of fields not in the order of mongod
+var auditSlowOp = `{"s":"I","t":{"$date":"2023-05-01T10:00:00.000+00:00"},"c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"admin.audit","command":{"find":"audit","filter":{"atype":"authenticate"},"$db":"admin"},"planSummary":"COLLSCAN","durationMillis":100}}`
+
+func TestParseAuditLog(t *testing.T) {
+	tests := []struct {
+		category string
+		user     string
+		target   string
+		result   int
+	}{
+		{AUDIT_AUTH, "app@admin", "app@admin", 18},
+		{AUDIT_AUTH, "app@admin", "app@admin", 18},
+		{AUDIT_AUTH, "admin@admin", "admin@admin", 0},
+		{AUDIT_PRIVILEGE, "admin@admin", "reporter@test", 0},
+		{AUDIT_DDL, "admin@admin", "test.orders -> test.orders_2023", 0},
+		{AUDIT_DDL, "admin@admin", "test.tmp", 0},
+	}
+	for i, test := range tests {
+		if !isAuditLog(auditLogs[i]) {
+			t.Fatalf("expected an audit log: %v", auditLogs[i])
+		}
+		event := AuditEvent{}
+		if err := ParseAuditLog(auditLogs[i], &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Category != test.category || event.User != test.user || event.Target != test.target ||
+			event.Result != test.result || event.Timestamp.IsZero() {
+			t.Fatalf("unexpected %+v", event)
+		}
+	}
+	if isAuditLog(`{"t":{"$date":"2023-05-01T10:00:00.000+00:00"},"s":"I","c":"NETWORK","msg":"Connection accepted"}`) {
+		t.Fatal("expected a logv2 log")
+	}
+	if err := ParseAuditLog(auditSlowOp, &AuditEvent{}); err == nil {
+		t.Fatal("expected a slow query of logv2")
+	}
+}
+
+func TestAnalyzeAuditSlowOp(t *testing.T) {
+	dbase := analyzeTestLogs(t, "audit_slowop", []string{auditSlowOp})
+	ops, err := dbase.GetSlowOps("avg_ms", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].QueryPattern != `{ atype:1 }` {
+		t.Fatalf("expected a slow query filtering on atype, got %+v", ops)
+	}
+}
+
+func TestAnalyzeAuditLog(t *testing.T) {
+	dbase := analyzeTestLogs(t, "auditlog", auditLogs)
+	data, err := dbase.GetAuditData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authfail := data["authfail"]
+	if len(authfail) != 1 || authfail[0].Name != "app@admin" || authfail[0].Values[2] != 2 {
+		t.Fatalf("unexpected authentication failures %v", authfail)
+	}
+	if len(data["privilege"]) != 1 || data["privilege"][0].Name != "createUser" {
+		t.Fatalf("unexpected privilege changes %v", data["privilege"])
+	}
+	if len(data["ddl"]) != 2 {
+		t.Fatalf("unexpected DDL events %v", data["ddl"])
+	}
+	users := data["user"]
+	if len(users) != 2 || users[0].Name != "admin@admin" || users[0].Values[0] != 4 || users[1].Values[1] != 2 {
+		t.Fatalf("unexpected user activities %v", users)
+	}
+	info := dbase.GetHatchetInfo()
+	if !strings.HasPrefix(info.Start, "2023-05-01T10:00:00") || !strings.HasPrefix(info.End, "2023-05-01T10:03:00") {
+		t.Fatalf("unexpected start %v and end %v", info.Start, info.End)
+	}
+	templ, err := GetAuditTablesTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := executeTestTemplate(t, templ, map[string]interface{}{"Hatchet": "auditlog", "Info": info, "Data": data})
+	if !strings.Contains(html, "Authentication Failures") || !strings.Contains(html, "reporter@test") {
+		t.Fatal("expected audit log tables")
+	}
+}
@@ -17,6 +17,8 @@ import (
 const (
 	BAR_CHART    = "bar_chart"
 	BUBBLE_CHART = "bubble_chart"
+	COLUMN_CHART = "column_chart"
+	LINE_CHART   = "line_chart"
 	PIE_CHART    = "pie_chart"
 
 	T_OPS            = "ops"
@@ -27,6 +29,10 @@ const (
 	T_CONNS_TOTAL    = "connections-total"
 	T_RESLEN_NS      = "reslen-ns"
 	T_RESLEN_APPNAME = "reslen-appname"
+	T_METRICS        = "metrics"
+	T_OPS_BREAKDOWN  = "ops-breakdown"
+	T_ERRORS         = "errors"
+	T_STORAGE        = "storage"
 )
 
 type Chart struct {
@@ -54,6 +60,65 @@ var charts = map[string]Chart{
 		"Display total response length by namespaces", "/reslen-ns?ns="},
 	T_RESLEN_APPNAME: {8, "Response Length by AppName ",
 		"Display total response length by application names", "/reslen-appname?appname="},
+	"metrics-opcounters": {9, "Opcounters (FTDC)",
+		"Display operations per second from diagnostic.data", "/metrics?type=opcounters"},
+	"metrics-connections": {10, "Connections (FTDC)",
+		"Display current and active connections from diagnostic.data", "/metrics?type=connections"},
+	"metrics-cache": {11, "WiredTiger Cache (FTDC)",
+		"Display WiredTiger cache usage from diagnostic.data", "/metrics?type=cache"},
+	"metrics-tickets": {12, "Tickets (FTDC)",
+		"Display read and write tickets from diagnostic.data", "/metrics?type=tickets"},
+	"metrics-replication": {13, "Replication Lag (FTDC)",
+		"Display max replication lag of secondaries from diagnostic.data", "/metrics?type=replication"},
+	T_OPS_BREAKDOWN: {14, "Slow Op Time Breakdown",
+		"Display time spent on CPU, disk reads, lock and flow control waits by slow ops", "/ops?type=breakdown"},
+	T_ERRORS: {15, "Error Rate",
+		"Display counts of errors by error names over a period of time", "/errors?type=rate"},
+	T_STORAGE: {16, "Checkpoints & Cache Pressure",
+		"Display average operation time with checkpoint running time and eviction warnings", "/storage?type=checkpoints"},
+}
+
+// vertical axis labels of metrics charts
+var metricsLabels = map[string]string{
+	"opcounters":  "ops/sec",
+	"connections": "connections",
+	"cache":       "MB",
+	"tickets":     "tickets",
+	"replication": "seconds",
+}
+
+// MetricsTable pivots metrics into rows of values by series names
+type MetricsTable struct {
+	Names []string
+	Rows  []MetricsRow
+}
+
+type MetricsRow struct {
+	Date   string
+	Values []interface{}
+}
+
+// GetMetricsTable returns a table of metrics, a value is nil if a series has no data at a time
+func GetMetricsTable(metrics []Metric) MetricsTable {
+	table := MetricsTable{}
+	columns := map[string]int{}
+	for _, metric := range metrics {
+		if _, ok := columns[metric.Name]; !ok {
+			columns[metric.Name] = len(table.Names)
+			table.Names = append(table.Names, metric.Name)
+		}
+	}
+	rows := map[string]int{}
+	for _, metric := range metrics {
+		i, ok := rows[metric.Date]
+		if !ok {
+			i = len(table.Rows)
+			rows[metric.Date] = i
+			table.Rows = append(table.Rows, MetricsRow{Date: metric.Date, Values: make([]interface{}, len(table.Names))})
+		}
+		table.Rows[i].Values[columns[metric.Name]] = metric.Value
+	}
+	return table
 }
 
 // ChartsHandler responds to charts API calls
@@ -79,13 +144,14 @@ func ChartsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Par
 	if duration != "" {
 		start, end = getStartEndDates(duration)
 	}
+	marker := r.URL.Query().Get("marker") // of a member of merged logs, of ops stats and error rates
 
 	if attr == T_OPS {
 		chartType := r.URL.Query().Get("type")
 		op := r.URL.Query().Get("op")
 		if chartType == "stats" {
 			chartType := T_OPS
-			docs, err := dbase.GetAverageOpTime(op, duration)
+			docs, err := dbase.GetAverageOpTime(op, duration, marker)
 			if len(docs) > 0 {
 				start = docs[0].Date
 				end = docs[len(docs)-1].Date
@@ -100,7 +166,7 @@ func ChartsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Par
 				return
 			}
 			doc := map[string]interface{}{"Hatchet": hatchetName, "OpCounts": docs, "Chart": charts[chartType],
-				"Type": chartType, "Summary": summary, "Start": start, "End": end, "VAxisLabel": "seconds"}
+				"Type": chartType, "Summary": summary, "Start": start, "End": end, "Marker": marker, "VAxisLabel": "seconds"}
 			if err = templ.Execute(w, doc); err != nil {
 				renderErrorPage(w, r, hatchetName, err.Error())
 				return
@@ -124,6 +190,29 @@ func ChartsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Par
 				return
 			}
 			return
+		} else if chartType == "breakdown" {
+			chartType = T_OPS_BREAKDOWN
+			docs, err := dbase.GetTimeBreakdown(op, duration)
+			if err != nil {
+				renderErrorPage(w, r, hatchetName, err.Error())
+				return
+			}
+			if len(docs) > 0 {
+				start = docs[0].Date
+				end = docs[len(docs)-1].Date
+			}
+			templ, err := GetChartTemplate(COLUMN_CHART)
+			if err != nil {
+				renderErrorPage(w, r, hatchetName, err.Error())
+				return
+			}
+			doc := map[string]interface{}{"Hatchet": hatchetName, "Metrics": GetMetricsTable(docs), "Chart": charts[chartType],
+				"Type": chartType, "Summary": summary, "Start": start, "End": end, "VAxisLabel": "seconds"}
+			if err = templ.Execute(w, doc); err != nil {
+				renderErrorPage(w, r, hatchetName, err.Error())
+				return
+			}
+			return
 		}
 		return
 	} else if attr == "connections" {
@@ -170,6 +259,80 @@ func ChartsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Par
 			}
 			return
 		}
+	} else if attr == T_ERRORS {
+		chartType := attr
+		docs, err := dbase.GetErrorRates(duration, marker)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		if len(docs) > 0 {
+			start = docs[0].Date
+			end = docs[len(docs)-1].Date
+		}
+		templ, err := GetChartTemplate(COLUMN_CHART)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Metrics": GetMetricsTable(docs), "Chart": charts[chartType],
+			"Type": chartType, "Summary": summary, "Start": start, "End": end, "Marker": marker, "VAxisLabel": "errors"}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
+	} else if attr == T_STORAGE {
+		chartType := attr
+		docs, err := dbase.GetStorageMetrics(duration)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		if len(docs) > 0 {
+			start = docs[0].Date
+			end = docs[len(docs)-1].Date
+		}
+		templ, err := GetChartTemplate(LINE_CHART)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Metrics": GetMetricsTable(docs), "Chart": charts[chartType],
+			"Type": chartType, "Summary": summary, "Start": start, "End": end, "VAxisLabel": "seconds, warnings"}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
+	} else if attr == T_METRICS {
+		metricsType := r.URL.Query().Get("type")
+		chartType := attr + "-" + metricsType
+		if _, ok := charts[chartType]; !ok {
+			renderErrorPage(w, r, hatchetName, fmt.Sprintf("unknown metrics type %v", metricsType))
+			return
+		}
+		docs, err := dbase.GetMetrics(metricsType, duration)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		if len(docs) > 0 {
+			start = docs[0].Date
+			end = docs[len(docs)-1].Date
+		}
+		templ, err := GetChartTemplate(LINE_CHART)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Metrics": GetMetricsTable(docs), "Chart": charts[chartType],
+			"Type": chartType, "Summary": summary, "Start": start, "End": end, "VAxisLabel": metricsLabels[metricsType]}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
 	} else if attr == T_RESLEN_UP {
 		ip := r.URL.Query().Get("ip")
 		chartType := attr
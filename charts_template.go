@@ -25,6 +25,12 @@ func GetChartTemplate(chartType string) (*template.Template, error) {
 	} else if chartType == PIE_CHART {
 		icon = "pie-chart"
 		color = "#ef6c00"
+	} else if chartType == LINE_CHART {
+		icon = "line-chart"
+		color = "#6a1b9a"
+	} else if chartType == COLUMN_CHART {
+		icon = "area-chart"
+		color = "#c62828"
 	}
 	html += fmt.Sprintf(`
 <!-- Header Bar -->
@@ -37,6 +43,10 @@ func GetChartTemplate(chartType string) (*template.Template, error) {
 		html += getPieChart()
 	} else if chartType == BAR_CHART {
 		html += getConnectionsChart()
+	} else if chartType == LINE_CHART {
+		html += getMetricsChart(false)
+	} else if chartType == COLUMN_CHART {
+		html += getMetricsChart(true)
 	}
 	html += `
 	<div style="float: left; width: 100%; clear: left;">
@@ -207,3 +217,46 @@ func getConnectionsChart() string {
 <div align='center' class='btn'><span style='color: red'>no data found</span></div>
 {{end}}`
 }
+
+// getMetricsChart returns a line chart of metrics, or a stacked column chart of parts of a total
+func getMetricsChart(stacked bool) string {
+	chart := "LineChart"
+	if stacked {
+		chart = "ColumnChart"
+	}
+	return fmt.Sprintf(`
+{{ if .Metrics.Rows }}
+<script>
+	setChartType();
+	google.charts.load('current', {'packages':['corechart']});
+	google.charts.setOnLoadCallback(drawChart);
+
+	function drawChart() {
+		var data = google.visualization.arrayToDataTable([
+			['Date/Time'{{range $i, $n := .Metrics.Names}}, {{$n}}{{end}}],
+	{{range $i, $v := .Metrics.Rows}}
+			[new Date("{{$v.Date}}"){{range $j, $n := $v.Values}}, {{$n}}{{end}}],
+	{{end}}
+		]);
+		// Set chart options
+		var options = {
+			'backgroundColor': { 'fill': 'transparent' },
+			'title': '{{.Chart.Title}}',
+			'hAxis': { slantedText: true, slantedTextAngle: 30 },
+			'vAxis': {title: '{{.VAxisLabel}}', minValue: 0},
+			'width': '100%%',
+			'height': 480,
+			'titleTextStyle': {'fontSize': 20},
+			'explorer': { actions: ['dragToZoom', 'rightClickToReset'] },
+			'interpolateNulls': true,
+			'isStacked': %v,
+			'legend': { 'position': 'right' } };
+		// Instantiate and draw our chart, passing in some options.
+		var chart = new google.visualization.%v(document.getElementById('hatchetChart'));
+		chart.draw(data, options);
+	}
+</script>
+{{else}}
+<div align='center' class='btn'><span style='color: red'>no data found</span></div>
+{{end}}`, stacked, chart)
+}
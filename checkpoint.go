@@ -0,0 +1,131 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * checkpoint.go
+ */
+
+package hatchet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+
+	"github.com/simagix/gox"
+)
+
+const (
+	IDENTITY_SIZE = 4096 // leading bytes hashed to identify a log file
+)
+
+// Checkpoint stores how far a log file has been ingested into a hatchet
+type Checkpoint struct {
+	Identity string `bson:"_id"`      // hash of the leading decompressed bytes
+	Name     string `bson:"name"`     // hatchet name
+	Filename string `bson:"filename"` // last ingested path
+	Offset   int64  `bson:"offset"`   // decompressed bytes ingested, always at a line boundary
+	Line     int    `bson:"line"`     // last line index (id) ingested
+	Date     string `bson:"date"`     // last timestamp ingested
+	Marker   int    `bson:"marker"`
+}
+
+// countingReader counts bytes read and remembers the position after the last newline.
+// A last line cut off in the middle of a JSON record is withheld, so that it is not read
+// until it is completed by the writer, and the next resume begins at a line boundary.
+// The last line of a finished file without a newline is terminated and read.
+type countingReader struct {
+	reader      io.Reader
+	count       int64
+	lastNewline int64
+
+	err     error  // of the underlying reader, returned once pending lines are read
+	pending []byte // bytes read but not returned yet
+}
+
+func (ptr *countingReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if i := bytes.LastIndexByte(ptr.pending, '\n'); i >= 0 {
+			n := copy(p, ptr.pending[:i+1])
+			ptr.pending = ptr.pending[n:]
+			return n, nil
+		}
+		if ptr.err != nil {
+			return 0, ptr.err
+		}
+		buf := make([]byte, len(p))
+		n, err := ptr.reader.Read(buf)
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			ptr.lastNewline = ptr.count + int64(i) + 1
+		}
+		ptr.count += int64(n)
+		ptr.pending = append(ptr.pending, buf[:n]...)
+		ptr.err = err
+		if errors.Is(err, io.EOF) && len(ptr.pending) > 0 && !isCutOffRecord(ptr.pending) {
+			ptr.pending = append(ptr.pending, '\n')
+			ptr.lastNewline = ptr.count
+		}
+	}
+}
+
+// isCutOffRecord checks if a line is a JSON record being written
+func isCutOffRecord(line []byte) bool {
+	return len(line) > 0 && line[0] == '{' && !json.Valid(line)
+}
+
+// GetFileIdentity returns a hash of the first IDENTITY_SIZE decompressed bytes
+// of a log file.  The identity remains the same when a log grows or is re-pulled
+// compressed.  An empty string is returned for files too small to identify.
+func GetFileIdentity(filename string) string {
+	file, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	reader, err := gox.NewReader(file)
+	if err != nil {
+		return ""
+	}
+	buf := make([]byte, IDENTITY_SIZE)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
+
+// GetCheckpoint returns the checkpoint of a file identity and nil if the file
+// was not ingested before or its hatchet no longer exists
+func GetCheckpoint(identity string) *Checkpoint {
+	var err error
+	var dbase Database
+	logv2 := GetLogv2()
+	if GetLogv2().GetDBType() == Mongo {
+		if dbase, err = NewMongoDB(logv2.url, "_temp"); err != nil {
+			return nil
+		}
+	} else {
+		if dbase, err = NewSQLite3DB(logv2.url, "_temp", logv2.cacheSize); err != nil {
+			return nil
+		}
+	}
+	defer dbase.Close()
+	checkpoint, err := dbase.GetCheckpoint(identity)
+	if err != nil || checkpoint == nil {
+		return nil
+	}
+	names, _ := dbase.GetHatchetNames()
+	for _, name := range names {
+		if name == checkpoint.Name {
+			return checkpoint
+		}
+	}
+	log.Println("hatchet", checkpoint.Name, "of checkpoint no longer exists")
+	return nil
+}
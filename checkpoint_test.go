@@ -0,0 +1,114 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * checkpoint_test.go
+ */
+
+package hatchet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountingReader(t *testing.T) {
+	tests := []struct {
+		data        string
+		expected    string
+		lastNewline int64
+	}{
+		{"line1\nline2\n{\"t\":{\"$date\"", "line1\nline2\n", 12}, // being written
+		{"line1\nline2\n{\"t\":{}}", "line1\nline2\n{\"t\":{}}\n", 20},
+		{"line1\nline2\nlast", "line1\nline2\nlast\n", 16},
+	}
+	for _, test := range tests {
+		counter := &countingReader{reader: strings.NewReader(test.data)}
+		data, err := io.ReadAll(bufio.NewReader(counter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.expected {
+			t.Fatalf("expected %q, got %q", test.expected, data)
+		}
+		if counter.count != int64(len(test.data)) || counter.lastNewline != test.lastNewline {
+			t.Fatalf("expected %v bytes and last newline at %v, got %v and %v", len(test.data), test.lastNewline,
+				counter.count, counter.lastNewline)
+		}
+	}
+}
+
+func TestAnalyzeResume(t *testing.T) {
+	tmpDir := useTestDatabase(t)
+	logname := filepath.Join(tmpDir, "mongod.log")
+	logLine := func(i int) string {
+		return fmt.Sprintf(`{"t":{"$date":"2024-03-18T10:%02d:%02d.000-04:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"127.0.0.1:%d","connectionCount":%d}}`+"\n",
+			i/60, i%60, 50000+i, i)
+	}
+	writeString := func(str string) {
+		file, err := os.OpenFile(logname, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err = file.WriteString(str); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeLogs := func(from int, to int) {
+		for i := from; i <= to; i++ {
+			writeString(logLine(i))
+		}
+	}
+	analyze := func() *Logv2 {
+		logv2 := &Logv2{testing: true, url: instance.url, resume: true, to: time.Now()}
+		if err := logv2.Analyze(logname, 1); err != nil {
+			t.Fatal(err)
+		}
+		return logv2
+	}
+	writeLogs(1, 100)
+	first := analyze()
+	partial := logLine(101)
+	writeString(partial[:len(partial)/2]) // being written
+	analyze()
+	writeString(partial[len(partial)/2:])
+	writeLogs(102, 149)
+	last := logLine(150)
+	writeString(last[:len(last)-1]) // of a finished file without a newline
+	analyze()
+	writeString("\n")
+	second := analyze()
+	if first.hatchetName != second.hatchetName {
+		t.Fatalf("expected the same hatchet, got %v and %v", first.hatchetName, second.hatchetName)
+	}
+
+	dbase, err := NewSQLite3DB(instance.url, second.hatchetName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbase.Close()
+	var total, count, maxID int
+	query := fmt.Sprintf("SELECT COUNT(*), COUNT(DISTINCT id), MAX(id) FROM %v", second.hatchetName)
+	if err = dbase.db.QueryRow(query).Scan(&total, &count, &maxID); err != nil {
+		t.Fatal(err)
+	}
+	if total != 150 || count != 150 || maxID != 150 {
+		t.Fatalf("expected 150 logs, got %v of %v ids with max id %v", total, count, maxID)
+	}
+	info := dbase.GetHatchetInfo()
+	if !strings.HasPrefix(info.Start, "2024-03-18T14:00:01") || !strings.HasPrefix(info.End, "2024-03-18T14:02:30") {
+		t.Fatalf("unexpected start %v and end %v", info.Start, info.End)
+	}
+	checkpoint, err := dbase.GetCheckpoint(GetFileIdentity(logname))
+	if err != nil || checkpoint == nil {
+		t.Fatal("checkpoint not found", err)
+	}
+	if checkpoint.Line != 150 {
+		t.Fatalf("expected checkpoint at line 150, got %v", checkpoint.Line)
+	}
+}
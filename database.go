@@ -33,6 +33,7 @@ type HatchetEntry struct {
 }
 
 type Database interface {
+	Append() error
 	Begin() error
 	Close() error
 	Commit() error
@@ -42,23 +43,42 @@ type Database interface {
 	Rename(newName string) error
 	GetAcceptedConnsCounts(duration string) ([]NameValue, error)
 	GetAuditData() (map[string][]NameValues, error)
-	GetAverageOpTime(op string, duration string) ([]OpCount, error)
+	GetAverageOpTime(op string, duration string, marker string) ([]OpCount, error)
+	GetTimeBreakdown(op string, duration string) ([]Metric, error)
+	GetCheckpoint(identity string) (*Checkpoint, error)
 	GetConnectionStats(chartType string, duration string) ([]RemoteClient, error)
+	GetErrorRates(duration string, marker string) ([]Metric, error)
 	GetHatchetInfo() HatchetInfo
 	GetHatchetNames() ([]string, error)
 	GetHatchetsWithTime() ([]HatchetEntry, error)
 	GetLogs(opts ...string) ([]LegacyLog, error)
+	GetMetrics(chartType string, duration string) ([]Metric, error)
 	GetOpsCounts(duration string) ([]NameValue, error)
+	GetProcessLifetimes() ([]ProcessLifetime, error)
+	GetReplEvents(eventType string, duration string, marker string) ([]ReplEvent, error)
 	GetReslenByAppName(appname string, duration string) ([]NameValue, error)
 	GetReslenByNamespace(ip string, duration string) ([]NameValue, error)
 	GetReslenByIP(ip string, duration string) ([]NameValue, error)
-	GetSlowOps(orderBy string, order string, collscan bool) ([]OpStat, error)
+	GetShardingStats() (*ShardingStats, error)
+	GetSlowOps(orderBy string, order string, collscan bool, groupBy string, opts ...string) ([]OpStat, error)
 	GetSlowestLogs(topN int) ([]LegacyLog, error)
+	GetStartupDigests() ([]StartupDigest, error)
+	GetStorageMetrics(duration string) ([]Metric, error)
+	GetTransactionStats() (*TransactionStats, error)
 	GetVerbose() bool
+	InsertAuditLog(index int, end string, event *AuditEvent) error
 	InsertClientConn(index int, doc *Logv2Info) error
 	InsertDriver(index int, doc *Logv2Info) error
+	InsertError(index int, end string, logError *LogError) error
 	InsertFailedMessages(m *FailedMessages) error
 	InsertLog(index int, end string, doc *Logv2Info, stat *OpStat) error
+	InsertMetric(metric Metric) error
+	InsertReplEvent(index int, end string, event *ReplEvent) error
+	InsertShardEvent(index int, end string, event *ShardEvent) error
+	InsertStartupEvent(index int, end string, event *StartupEvent) error
+	InsertStorageEvent(index int, end string, event *StorageEvent) error
+	InsertTransaction(index int, end string, txn *Transaction) error
+	SaveCheckpoint(checkpoint Checkpoint) error
 	SearchLogs(opts ...string) ([]LegacyLog, error)
 	SetVerbose(v bool)
 	UpdateHatchetInfo(info HatchetInfo) error
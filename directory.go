@@ -0,0 +1,282 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * directory.go
+ */
+
+package hatchet
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simagix/gox"
+)
+
+// rotated suffixes, e.g. mongod.log.1 and mongod.log.2024-03-18T14-49-06
+var rotatedLogRegex = regexp.MustCompile(`^(.+)\.(\d+|\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}(\.\d+)?)$`)
+
+// LogSegment is a log file of a node, either active or rotated
+type LogSegment struct {
+	Filename string    // file to analyze
+	Path     string    // path used for naming
+	Base     string    // active log name of the node, e.g. shard0/rs0-0/mongod.log
+	Suffix   string    // rotated suffix, empty for the active log
+	Start    time.Time // timestamp of the first log
+}
+
+// matchGlobs returns true if a relative path, any of its trailing subpaths,
+// or any of its file and directory names matches comma-separated glob patterns
+func matchGlobs(patterns string, name string) bool {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		for i := range parts {
+			if ok, _ := path.Match(pattern, strings.Join(parts[i:], "/")); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, parts[i]); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIncluded checks a relative path against -include and -exclude patterns
+func (ptr *Logv2) isIncluded(name string) bool {
+	if ptr.include != "" && !matchGlobs(ptr.include, name) {
+		return false
+	}
+	return ptr.exclude == "" || !matchGlobs(ptr.exclude, name)
+}
+
+// findLogFiles walks a directory recursively and returns relative paths of
+// included files.  Hidden files and directories are skipped.
+func (ptr *Logv2) findLogFiles(dirname string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dirname, func(fullPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if fullPath == dirname {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dirname, fullPath)
+		if err != nil {
+			return err
+		}
+		if ptr.isIncluded(rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// getRotatedLogBase returns the active log name and the rotated suffix of a log file
+func getRotatedLogBase(logname string) (string, string) {
+	dir, name := filepath.Split(logname)
+	for _, ext := range []string{".gz", ".zst"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if matches := rotatedLogRegex.FindStringSubmatch(name); matches != nil {
+		return dir + matches[1], matches[2]
+	}
+	return dir + name, ""
+}
+
+// getLogStartTime returns the timestamp of the first log of a file
+func getLogStartTime(filename string) time.Time {
+	file, err := os.Open(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	defer file.Close()
+	reader, err := gox.NewReader(file)
+	if err != nil {
+		return time.Time{}
+	}
+	for i := 0; i < 10; i++ { // first few lines only
+		line, _, err := reader.ReadLine()
+		if err != nil {
+			break
+		}
+		event := AuditEvent{}
+		if isAuditLog(string(line)) && ParseAuditLog(string(line), &event) == nil {
+			return event.Timestamp
+		}
+		doc := Logv2Info{}
+		if ParseLogLine(string(line), &doc) == nil && !doc.Timestamp.IsZero() {
+			return doc.Timestamp
+		}
+	}
+	return time.Time{}
+}
+
+// rotationBefore orders rotated segments when timestamps are not available,
+// mongod.log.2 before mongod.log.1 and dated segments before the active log
+func rotationBefore(a LogSegment, b LogSegment) bool {
+	if a.Suffix == "" || b.Suffix == "" {
+		return b.Suffix == "" && a.Suffix != ""
+	}
+	x, xerr := strconv.Atoi(a.Suffix)
+	y, yerr := strconv.Atoi(b.Suffix)
+	if xerr == nil && yerr == nil {
+		return x > y
+	}
+	return a.Suffix < b.Suffix
+}
+
+// GroupRotatedLogs groups log segments by node and sorts each group chronologically
+func GroupRotatedLogs(segments []LogSegment) [][]LogSegment {
+	var bases []string
+	groups := map[string][]LogSegment{}
+	for _, segment := range segments {
+		segment.Base, segment.Suffix = getRotatedLogBase(segment.Path)
+		if _, ok := groups[segment.Base]; !ok {
+			bases = append(bases, segment.Base)
+		}
+		groups[segment.Base] = append(groups[segment.Base], segment)
+	}
+	var results [][]LogSegment
+	for _, base := range bases {
+		group := groups[base]
+		sort.SliceStable(group, func(i, j int) bool {
+			if !group[i].Start.IsZero() && !group[j].Start.IsZero() && !group[i].Start.Equal(group[j].Start) {
+				return group[i].Start.Before(group[j].Start)
+			}
+			return rotationBefore(group[i], group[j])
+		})
+		results = append(results, group)
+	}
+	return results
+}
+
+// analyzeDirectory analyzes MongoDB logs in a directory and its subdirectories
+func (ptr *Logv2) analyzeDirectory(dirname string) error {
+	files, err := ptr.findLogFiles(dirname)
+	if err != nil {
+		return err
+	}
+	var segments []LogSegment
+	for _, file := range files {
+		fullPath := filepath.Join(dirname, file)
+		segments = append(segments, LogSegment{Filename: fullPath, Path: fullPath})
+	}
+	if ptr.analyzeSegments(segments) == 0 {
+		log.Printf("no MongoDB log files found in directory %s", dirname)
+	}
+	// Clear hatchetName so caller knows not to print summary again
+	ptr.hatchetName = ""
+	return nil
+}
+
+// analyzeSegments analyzes log segments in sequence, each as its own hatchet,
+// or rotated segments of a node into one hatchet with -merge-rotated.  FTDC
+// files of diagnostic.data are added as metrics to the hatchet of the node of
+// logs in the parent directory, or analyzed as their own hatchet when there is
+// none or more than one.  It returns the number of MongoDB logs and
+// diagnostic.data directories found.
+func (ptr *Logv2) analyzeSegments(segments []LogSegment) int {
+	var logs []LogSegment
+	var ftdcDirs []string
+	ftdcFiles := map[string][]string{}
+	for _, segment := range segments {
+		if isFTDCFile(segment.Filename) {
+			dir := filepath.Dir(segment.Path)
+			if _, ok := ftdcFiles[dir]; !ok {
+				ftdcDirs = append(ftdcDirs, dir)
+			}
+			ftdcFiles[dir] = append(ftdcFiles[dir], segment.Filename)
+			continue
+		}
+		if !isMongoDBLog(segment.Filename) {
+			log.Printf("skipping %s (not a MongoDB log)", segment.Path)
+			continue
+		}
+		segment.Start = getLogStartTime(segment.Filename)
+		logs = append(logs, segment)
+	}
+	// Get existing names once, then track new names locally to avoid DB query timing issues
+	existingNames, _ := GetExistingHatchetNames()
+	processedNames := make([]string, 0)
+	uniqueName := func(logname string) string {
+		allNames := append(existingNames, processedNames...)
+		hatchetName := getUniqueHatchetName(logname, allNames)
+		processedNames = append(processedNames, hatchetName)
+		return hatchetName
+	}
+	nodes := map[string]map[string]string{} // hatchet names of the latest logs of nodes by directories
+	addNode := func(segment LogSegment, hatchetName string) {
+		dir := filepath.Dir(segment.Path)
+		if nodes[dir] == nil {
+			nodes[dir] = map[string]string{}
+		}
+		nodes[dir][segment.Base] = hatchetName
+	}
+	for _, group := range GroupRotatedLogs(logs) {
+		if !ptr.mergeRotated || len(group) == 1 {
+			for _, segment := range group {
+				hatchetName := uniqueName(segment.Path)
+				addNode(segment, hatchetName)
+				ptr.analyzeFile(segment.Filename, hatchetName)
+			}
+			continue
+		}
+		hatchetName := uniqueName(group[0].Base)
+		addNode(group[0], hatchetName)
+		// Create a new Logv2 instance for each node to merge its rotated logs
+		nodeLogv2 := ptr.cloneFor(hatchetName)
+		nodeLogv2.merge = true
+		log.Printf("merging %d rotated logs of %s", len(group), group[0].Base)
+		for _, segment := range group {
+			if err := nodeLogv2.Analyze(segment.Filename, 0); err != nil {
+				log.Printf("error processing %s: %v", segment.Filename, err)
+			}
+		}
+		if !nodeLogv2.legacy {
+			nodeLogv2.PrintSummary()
+		}
+	}
+	for _, dir := range ftdcDirs {
+		if ptr.legacy {
+			break
+		}
+		var hatchetName string
+		node := nodes[filepath.Dir(dir)]
+		for _, name := range node {
+			hatchetName = name
+		}
+		if len(node) > 1 {
+			log.Printf("%d nodes of logs in %s, adding %s as its own hatchet", len(node), filepath.Dir(dir), dir)
+		}
+		standalone := len(node) != 1
+		if standalone {
+			hatchetName = uniqueName(dir)
+		}
+		if err := ptr.analyzeFTDC(ftdcFiles[dir], hatchetName, standalone); err != nil {
+			log.Printf("error processing %s: %v", dir, err)
+		}
+	}
+	return len(logs) + len(ftdcDirs)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * directory_test.go
+ */
+
+package hatchet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetRotatedLogBase(t *testing.T) {
+	tests := []struct {
+		path   string
+		base   string
+		suffix string
+	}{
+		{"rs0-0/mongod.log", "rs0-0/mongod.log", ""},
+		{"rs0-0/mongod.log.gz", "rs0-0/mongod.log", ""},
+		{"rs0-0/mongod.log.1", "rs0-0/mongod.log", "1"},
+		{"rs0-0/mongod.log.12.gz", "rs0-0/mongod.log", "12"},
+		{"mongodb.log.2024-03-18T14-49-06", "mongodb.log", "2024-03-18T14-49-06"},
+		{"mongodb.log.2024-03-18T14-49-06.zst", "mongodb.log", "2024-03-18T14-49-06"},
+		{"mongodb-2.log", "mongodb-2.log", ""},
+	}
+	for _, test := range tests {
+		base, suffix := getRotatedLogBase(test.path)
+		if base != test.base || suffix != test.suffix {
+			t.Errorf("%v: expected (%v, %v), got (%v, %v)", test.path, test.base, test.suffix, base, suffix)
+		}
+	}
+}
+
+func TestGroupRotatedLogs(t *testing.T) {
+	start := time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)
+	segments := []LogSegment{
+		{Path: "rs0-0/mongod.log"},
+		{Path: "rs0-0/mongod.log.1.gz"},
+		{Path: "rs0-0/mongod.log.2"},
+		{Path: "rs0-1/mongod.log.2024-03-19T00-00-00", Start: start.Add(24 * time.Hour)},
+		{Path: "rs0-1/mongod.log.2024-03-18T00-00-00", Start: start},
+		{Path: "rs0-1/mongod.log", Start: start.Add(48 * time.Hour)},
+	}
+	var paths [][]string
+	for _, group := range GroupRotatedLogs(segments) {
+		var names []string
+		for _, segment := range group {
+			names = append(names, segment.Path)
+		}
+		paths = append(paths, names)
+	}
+	expected := [][]string{
+		{"rs0-0/mongod.log.2", "rs0-0/mongod.log.1.gz", "rs0-0/mongod.log"},
+		{"rs0-1/mongod.log.2024-03-18T00-00-00", "rs0-1/mongod.log.2024-03-19T00-00-00", "rs0-1/mongod.log"},
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+}
+
+func TestFindLogFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"shard0/rs0-0/mongod.log", "shard0/rs0-0/mongod.log.1.gz",
+		"shard0/rs0-0/diagnostic.data/metrics.interim", "shard0/notes.txt", ".hidden/mongod.log"} {
+		filename := filepath.Join(tmpDir, name)
+		os.MkdirAll(filepath.Dir(filename), 0755)
+		if err := os.WriteFile(filename, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logv2 := &Logv2{include: "mongod.log*", exclude: "*.gz"}
+	files, err := logv2.findLogFiles(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join("shard0", "rs0-0", "mongod.log")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+	logv2 = &Logv2{exclude: "diagnostic.data"}
+	if files, err = logv2.findLogFiles(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %v", files)
+	}
+	if !matchGlobs("rs0-0/*.gz", files[2]) || matchGlobs("shard1/*", files[2]) {
+		t.Fatalf("unexpected match of %v", files[2])
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * error_codes.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	ERROR_AUTH      = "auth"
+	ERROR_CONFLICT  = "conflict"
+	ERROR_DUPLICATE = "duplicate"
+	ERROR_ELECTION  = "election"
+	ERROR_NETWORK   = "network"
+	ERROR_OTHER     = "other"
+	ERROR_RESOURCE  = "resource"
+	ERROR_SHARDING  = "sharding"
+	ERROR_SHUTDOWN  = "shutdown"
+	ERROR_TIMEOUT   = "timeout"
+)
+
+// attributes of errors of log lines
+var errorKeys = []string{"errCode", "errName", "codeName", "error"}
+
+// names of common error codes, for logs with errCode only
+var errorCodeNames = map[int]string{
+	6:     "HostUnreachable",
+	13:    "Unauthorized",
+	18:    "AuthenticationFailed",
+	50:    "MaxTimeMSExpired",
+	63:    "StaleShardVersion",
+	89:    "NetworkTimeout",
+	91:    "ShutdownInProgress",
+	112:   "WriteConflict",
+	150:   "StaleEpoch",
+	189:   "PrimarySteppedDown",
+	249:   "StaleDbVersion",
+	251:   "NoSuchTransaction",
+	262:   "ExceededTimeLimit",
+	292:   "QueryExceededMemoryLimitNoDiskUseAllowed",
+	10107: "NotWritablePrimary",
+	11000: "DuplicateKey",
+	11600: "InterruptedAtShutdown",
+	11601: "Interrupted",
+	11602: "InterruptedDueToReplStateChange",
+	13388: "StaleConfig",
+	13435: "NotPrimaryNoSecondaryOk",
+}
+
+// categories of error names
+var errorCategories = map[string]string{
+	"AuthenticationFailed": ERROR_AUTH,
+	"Unauthorized":         ERROR_AUTH,
+
+	"NoSuchTransaction": ERROR_CONFLICT,
+	"TransactionTooOld": ERROR_CONFLICT,
+	"WriteConflict":     ERROR_CONFLICT,
+
+	"DuplicateKey": ERROR_DUPLICATE,
+
+	"InterruptedDueToReplStateChange": ERROR_ELECTION,
+	"NotPrimaryNoSecondaryOk":         ERROR_ELECTION,
+	"NotPrimaryOrSecondary":           ERROR_ELECTION,
+	"NotWritablePrimary":              ERROR_ELECTION,
+	"PrimarySteppedDown":              ERROR_ELECTION,
+
+	"HostNotFound":    ERROR_NETWORK,
+	"HostUnreachable": ERROR_NETWORK,
+	"NetworkTimeout":  ERROR_NETWORK,
+	"SocketException": ERROR_NETWORK,
+
+	"ExceededMemoryLimit":                      ERROR_RESOURCE,
+	"QueryExceededMemoryLimitNoDiskUseAllowed": ERROR_RESOURCE,
+	"TooManyFilesOpen":                         ERROR_RESOURCE,
+
+	"StaleConfig":       ERROR_SHARDING,
+	"StaleDbVersion":    ERROR_SHARDING,
+	"StaleEpoch":        ERROR_SHARDING,
+	"StaleShardVersion": ERROR_SHARDING,
+
+	"InterruptedAtShutdown": ERROR_SHUTDOWN,
+	"ShutdownInProgress":    ERROR_SHUTDOWN,
+
+	"ExceededTimeLimit": ERROR_TIMEOUT,
+	"MaxTimeMSExpired":  ERROR_TIMEOUT,
+}
+
+// LogError stores an error of a log line classified by its code name
+type LogError struct {
+	AppName   string `json:"appname" bson:"appname"`
+	Category  string `json:"category" bson:"category"` // e.g. timeout, election or conflict
+	Code      int    `json:"code" bson:"code"`
+	Component string `json:"component" bson:"component"`
+	IP        string `json:"ip" bson:"ip"`
+	Name      string `json:"name" bson:"name"` // errName or codeName, e.g. MaxTimeMSExpired
+	Namespace string `json:"ns" bson:"ns"`
+	Op        string `json:"op" bson:"op"`
+
+	Marker int `json:"-" bson:"marker"`
+}
+
+// GetErrorCategory returns the category of an error name
+func GetErrorCategory(name string) string {
+	if category, ok := errorCategories[name]; ok {
+		return category
+	}
+	return ERROR_OTHER
+}
+
+// GetLogError returns the error of a log line from errCode, errName and codeName of its
+// attributes or of its error document, or nil if none
+func GetLogError(doc *Logv2Info, stat *OpStat) *LogError {
+	if !hasAnyKey(doc.Attr, errorKeys) && doc.Msg != "Authentication failed" {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	code := ToInt(attrMap["errCode"])
+	name, _ := attrMap["errName"].(string)
+	if name == "" {
+		name, _ = attrMap["codeName"].(string)
+	}
+	if errMap, ok := attrMap["error"].(bson.M); ok && code == 0 && name == "" {
+		code = ToInt(errMap["code"])
+		name, _ = errMap["codeName"].(string)
+	}
+	if result, ok := attrMap["result"].(string); ok && code == 0 && name == "" && doc.Msg == "Authentication failed" {
+		name = strings.SplitN(result, ":", 2)[0] // e.g. AuthenticationFailed: SCRAM authentication failed
+	}
+	if code == 0 && name == "" {
+		return nil
+	}
+	if name == "" {
+		if name = errorCodeNames[code]; name == "" {
+			name = fmt.Sprintf("code %d", code)
+		}
+	}
+	logError := &LogError{Category: GetErrorCategory(name), Code: code, Component: doc.Component, Name: name,
+		Marker: doc.Marker}
+	if stat != nil && stat.Namespace != "" {
+		logError.Namespace = stat.Namespace
+		logError.Op = stat.Op
+	} else {
+		logError.Namespace, _ = attrMap["ns"].(string)
+	}
+	if logError.AppName = doc.Attributes.AppName; logError.AppName == "" {
+		logError.AppName, _ = attrMap["appName"].(string)
+	}
+	if doc.Client != nil && doc.Client.IP != "" {
+		logError.IP = doc.Client.IP
+	} else if remote, ok := attrMap["remote"].(string); ok {
+		logError.IP = strings.Split(remote, ":")[0]
+	}
+	return logError
+}
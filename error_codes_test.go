@@ -0,0 +1,93 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * error_codes_test.go
+ */
+
+package hatchet
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var errorLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:01.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","appName":"reporting","command":{"find":"users","filter":{"status":"A"},"maxTimeMS":100,"$db":"test"},"planSummary":"COLLSCAN","docsExamined":5000,"nreturned":0,"ok":0,"errMsg":"operation exceeded time limit","errName":"MaxTimeMSExpired","errCode":50,"remote":"10.0.0.1:52010","durationMillis":101}}`,
+	`{"t":{"$date":"2024-03-18T10:00:02.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","appName":"reporting","command":{"find":"users","filter":{"status":"B"},"maxTimeMS":100,"$db":"test"},"planSummary":"COLLSCAN","docsExamined":5000,"nreturned":0,"ok":0,"errMsg":"operation exceeded time limit","errCode":50,"remote":"10.0.0.1:52010","durationMillis":102}}`,
+	`{"t":{"$date":"2024-03-18T10:01:03.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn2","msg":"Slow query","attr":{"type":"update","ns":"test.orders","command":{"q":{"_id":1},"u":{"$set":{"qty":1}}},"planSummary":"IDHACK","keysExamined":1,"docsExamined":1,"writeConflicts":3,"errName":"WriteConflict","errCode":112,"remote":"10.0.0.2:52011","durationMillis":150}}`,
+	`{"t":{"$date":"2024-03-18T10:01:04.000+00:00"},"s":"W","c":"REPL","id":21405,"ctx":"conn3","msg":"Stepping down","attr":{"error":{"code":10107,"codeName":"NotWritablePrimary","errmsg":"not primary"}}}`,
+	`{"t":{"$date":"2024-03-18T10:01:05.000+00:00"},"s":"I","c":"ACCESS","id":20249,"ctx":"conn4","msg":"Authentication failed","attr":{"mechanism":"SCRAM-SHA-256","principalName":"app","authenticationDatabase":"admin","remote":"10.0.0.3:52012","result":"AuthenticationFailed: SCRAM authentication failed, storedKey mismatch"}}`,
+	`{"t":{"$date":"2024-03-18T10:01:06.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"A"},"$db":"test"},"planSummary":"COLLSCAN","docsExamined":5000,"nreturned":1,"durationMillis":100}}`,
+}
+
+func TestGetLogError(t *testing.T) {
+	expected := []LogError{
+		{AppName: "reporting", Category: ERROR_TIMEOUT, Code: 50, Component: "COMMAND", IP: "10.0.0.1", Name: "MaxTimeMSExpired", Namespace: "test.users", Op: "find"},
+		{AppName: "reporting", Category: ERROR_TIMEOUT, Code: 50, Component: "COMMAND", IP: "10.0.0.1", Name: "MaxTimeMSExpired", Namespace: "test.users", Op: "find"},
+		{Category: ERROR_CONFLICT, Code: 112, Component: "WRITE", IP: "10.0.0.2", Name: "WriteConflict", Namespace: "test.orders", Op: "update"},
+		{Category: ERROR_ELECTION, Code: 10107, Component: "REPL", Name: "NotWritablePrimary"},
+		{Category: ERROR_AUTH, Component: "ACCESS", IP: "10.0.0.3", Name: "AuthenticationFailed"},
+	}
+	for i, line := range errorLogs {
+		doc := Logv2Info{}
+		if err := bson.UnmarshalExtJSON([]byte(line), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		stat, _ := AnalyzeSlowOp(&doc)
+		logError := GetLogError(&doc, stat)
+		if i >= len(expected) {
+			if logError != nil {
+				t.Fatalf("expected no error, got %+v", logError)
+			}
+			continue
+		}
+		if logError == nil || *logError != expected[i] {
+			t.Fatalf("expected %+v, got %+v", expected[i], logError)
+		}
+	}
+	if GetErrorCategory("BadValue") != ERROR_OTHER {
+		t.Fatal("expected an unknown error name to be of other")
+	}
+}
+
+func TestGetErrorRates(t *testing.T) {
+	dbase := analyzeTestLogs(t, "mongod_errors", errorLogs)
+	data, err := dbase.GetAuditData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := data["errors"]
+	if len(errs) != 4 || errs[0].Name != "MaxTimeMSExpired" || errs[0].Values[0] != 50 || errs[0].Values[1] != ERROR_TIMEOUT ||
+		errs[0].Values[2] != 2 || errs[0].Values[3] != 1 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	sources := data["error-sources"]
+	if len(sources) != 4 || sources[0].Name != "MaxTimeMSExpired" || sources[0].Values[0] != "test.users" ||
+		sources[0].Values[1] != "reporting" || sources[0].Values[2] != "10.0.0.1" || sources[0].Values[3] != 2 {
+		t.Fatalf("unexpected error sources %v", sources)
+	}
+	metrics, err := dbase.GetErrorRates("2024-03-18T10:00:00,2024-03-18T10:02:00", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := GetMetricsTable(metrics)
+	if len(table.Rows) != 2 || len(table.Names) != 4 || table.Rows[0].Values[0] != 2.0 {
+		t.Fatalf("unexpected error rates %+v", table)
+	}
+	templ, err := GetAuditTablesTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "mongod_errors", "Info": dbase.GetHatchetInfo(), "Data": data}
+	html := executeTestTemplate(t, templ, doc)
+	if !strings.Contains(html, "Errors by Namespaces") || !strings.Contains(html, "NotWritablePrimary") {
+		t.Fatal("expected error tables")
+	}
+	if templ, err = GetChartTemplate(COLUMN_CHART); err != nil {
+		t.Fatal(err)
+	}
+	doc = map[string]interface{}{"Hatchet": "mongod_errors", "Metrics": table, "Chart": charts[T_ERRORS], "Summary": "",
+		"Start": "", "End": "", "VAxisLabel": "errors"}
+	executeTestTemplate(t, templ, doc)
+}
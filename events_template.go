@@ -0,0 +1,169 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * events_template.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"html/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// colors of replica set event types
+var replEventColors = map[string]string{EVENT_ELECTION: "#1565c0", EVENT_OPLOG: "#ef6c00", EVENT_ROLLBACK: "#c62828",
+	EVENT_STATE: "#2e7d32", EVENT_STEPDOWN: "#6a1b9a", EVENT_SYNC_SOURCE: "#00838f",
+
+	EVENT_FATAL: "#b71c1c", EVENT_RESTART: "#37474f", EVENT_SHUTDOWN: "#5d4037", EVENT_UNCLEAN: "#d84315"}
+
+// colors of statuses of process lifetimes
+var lifetimeColors = map[string]string{LIFETIME_CRASHED: "#b71c1c", LIFETIME_RUNNING: "#2e7d32",
+	LIFETIME_SHUTDOWN: "#37474f", LIFETIME_UNCLEAN: "#d84315"}
+
+// GetEventsTemplate returns HTML of process lifetimes and the replica set and process event
+// timeline, of which events of members of merged logs are in columns of their markers
+func GetEventsTemplate(download string) (*template.Template, error) {
+	html := headers
+	if download == "" {
+		html = getContentHTML()
+	}
+	html += `
+<script>
+	function downloadEvents() {
+		anchor = document.createElement('a');
+		anchor.download = '{{.Hatchet}}_events.html';
+		anchor.href = '/hatchets/{{.Hatchet}}/logs/events?type={{.Type}}&duration={{.Duration}}&marker={{.Marker}}&download=true';
+		anchor.dataset.downloadurl = ['text/html', anchor.download, anchor.href].join(':');
+		anchor.click();
+	}
+
+	function getEvents() {
+		var sel = document.getElementById('eventType');
+		loadData('/hatchets/{{.Hatchet}}/logs/events?type=' + sel.options[sel.selectedIndex].value + '&duration={{.Duration}}&marker={{.Marker}}');
+	}
+</script>
+<div align='left'>`
+	if download == "" {
+		html += `
+<!-- Header Bar -->
+<div style='display: flex; justify-content: space-between; align-items: center; margin-bottom: 15px;'>
+	<h2 style='margin: 0; color: #444; font-size: 1.4em;'><i class='fa fa-heartbeat' style='color: #1565c0;'></i> Events</h2>
+	<span>type
+		<select id='eventType' onchange='getEvents(); return false;'>
+			<option value=''>all</option>
+		{{ range $t := .Types }}
+			<option value='{{ $t }}' {{ if eq $t $.Type }}selected{{ end }}>{{ $t }}</option>
+		{{ end }}
+		</select>
+	<button id="download" onClick="downloadEvents(); return false;"
+		class="download-btn"><i class="fa fa-download"></i> Download</button>
+	</span>
+</div>`
+	} else {
+		html += "<div align='center'>{{.Summary}}</div>"
+	}
+	html += `
+{{ if .Lifetimes }}
+<h3>Process Lifetimes</h3>
+	<table width='100%'>
+		<tr><th>#</th>{{ if .Merge }}<th>member</th>{{ end }}<th>start</th><th>end</th><th>status</th><th>fatal</th>
+			<th>process</th>{{ if not .Download }}<th>filter</th>{{ end }}</tr>
+	{{ range $n, $value := .Lifetimes }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			{{ if $.Merge }}<td>{{ getMarkerHTML $value.Marker }}</td>{{ end }}
+			<td style='white-space: nowrap;'>{{ $value.Start }}</td>
+			<td style='white-space: nowrap;'>{{ $value.End }}</td>
+			<td>{{ getLifetimeHTML $value.Status }}</td>
+			<td align='right'>{{ if $value.Fatals }}<span style='color:red;'>{{ numPrinter $value.Fatals }}</span>{{ else }}0{{ end }}</td>
+			<td class='break'>{{ $value.Detail }}</td>
+		{{ if not $.Download }}
+			<td style='white-space: nowrap;'>
+				<button class='btn' title='events' onClick="javascript:loadData('/hatchets/{{$.Hatchet}}/logs/events?duration={{$value.Duration}}&marker={{$value.Marker}}'); return false;"><i class='fa fa-heartbeat'></i></button>
+				<button class='btn' title='logs' onClick="javascript:loadData('/hatchets/{{$.Hatchet}}/logs/all?duration={{$value.Duration}}&marker={{$value.Marker}}'); return false;"><i class='fa fa-search'></i></button>
+				<button class='btn' title='average operation time' onClick="javascript:loadData('/hatchets/{{$.Hatchet}}/charts/ops?type=stats&duration={{$value.Duration}}&marker={{$value.Marker}}'); return false;"><i class='fa fa-area-chart'></i></button>
+				<button class='btn' title='slow ops stats' onClick="javascript:loadData('/hatchets/{{$.Hatchet}}/stats/slowops?duration={{$value.Duration}}&marker={{$value.Marker}}'); return false;"><i class='fa fa-info'></i></button>
+				<button class='btn' title='error rate' onClick="javascript:loadData('/hatchets/{{$.Hatchet}}/charts/errors?type=rate&duration={{$value.Duration}}&marker={{$value.Marker}}'); return false;"><i class='fa fa-bar-chart'></i></button>
+			</td>
+		{{ end }}
+		</tr>
+	{{ end }}
+	</table>
+<h3>Timeline</h3>
+{{ end }}
+{{ if .Counts }}
+<p>{{ range $n, $value := .Counts }}{{ if $n }}, {{ end }}{{ numPrinter $value.Value }} {{ $value.Name }}{{ end }} events.</p>
+{{ end }}
+{{ if .Events }}
+	<table width='100%'>
+		<tr><th>#</th><th>date</th>
+		{{ if .Merge }}
+			{{ range $m := .Markers }}<th>{{ getMarkerHTML $m }}</th>{{ end }}
+		{{ else }}
+			<th>event</th>
+		{{ end }}
+		</tr>
+	{{ range $n, $value := .Events }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td style='white-space: nowrap;'>{{ $value.Date }}</td>
+		{{ if $.Merge }}
+			{{ range $m := $.Markers }}
+				<td class='break'>{{ if eq $m $value.Marker }}{{ getEventHTML $value }}{{ end }}</td>
+			{{ end }}
+		{{ else }}
+			<td class='break'>{{ getEventHTML $value }}</td>
+		{{ end }}
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No event is found.</p>
+{{ end }}
+	<div align='center'><hr/><p/>{{.Version}}</div>
+</div>`
+	if download == "" {
+		html += "</div><!-- end content-container -->"
+	}
+	html += "</body></html>"
+	return template.New("hatchet").Funcs(template.FuncMap{
+		"add": func(a int, b int) int {
+			return a + b
+		},
+		"getEventHTML": func(event ReplEvent) template.HTML {
+			return template.HTML(getEventHTML(event))
+		},
+		"getLifetimeHTML": func(status string) template.HTML {
+			return template.HTML(fmt.Sprintf("<span style='padding: 1px 4px; background-color: %v; color: white; font-size: 0.85em;'>%v</span>",
+				lifetimeColors[status], status))
+		},
+		"getMarkerHTML": func(marker int) template.HTML {
+			return template.HTML(GetMarkerHTML(marker))
+		},
+		"numPrinter": func(n interface{}) string {
+			printer := message.NewPrinter(language.English)
+			return printer.Sprintf("%v", ToInt(n))
+		}}).Parse(html)
+}
+
+// getEventHTML returns HTML of an event with its type, state transition and details
+func getEventHTML(event ReplEvent) string {
+	color := replEventColors[event.Type]
+	if color == "" {
+		color = "#888"
+	}
+	html := fmt.Sprintf("<span style='padding: 1px 4px; background-color: %v; color: white; font-size: 0.85em;'>%v</span> %v",
+		color, event.Type, template.HTMLEscapeString(event.Name))
+	if event.FromState != "" || event.ToState != "" {
+		html += fmt.Sprintf(" <b>%v &rarr; %v</b>", template.HTMLEscapeString(event.FromState),
+			template.HTMLEscapeString(event.ToState))
+	}
+	if event.Detail != "" {
+		html += fmt.Sprintf("<br/><span style='color: #888; font-size: 0.85em;'>%v</span>",
+			template.HTMLEscapeString(event.Detail))
+	}
+	return html
+}
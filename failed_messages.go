@@ -18,3 +18,9 @@ func (c *FailedMessages) inc(name string) {
 	n := c.counters[name]
 	c.counters[name] = n + 1
 }
+
+func (c *FailedMessages) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counters = map[string]int{}
+}
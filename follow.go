@@ -0,0 +1,131 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * follow.go
+ */
+
+package hatchet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	FOLLOW_POLL_INTERVAL = time.Second
+	FOLLOW_REFRESH       = 30 * time.Second
+)
+
+// TailReader reads a growing log file like tail -F.  At EOF, it waits for
+// new data instead of returning io.EOF and reopens the file after it is
+// rotated (renamed) or truncated.  It only returns io.EOF after Stop is called.
+type TailReader struct {
+	file     *os.File
+	filename string
+	interval time.Duration
+	offset   int64
+	once     sync.Once
+	stop     chan struct{}
+
+	onIdle func() // called each time the reader has caught up with the writer
+}
+
+// NewTailReader opens a file to follow
+func NewTailReader(filename string, interval time.Duration) (*TailReader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkPlainText(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &TailReader{file: file, filename: filename, interval: interval, stop: make(chan struct{})}, nil
+}
+
+// checkPlainText returns an error if a file is compressed because a
+// compressed stream cannot be followed
+func checkPlainText(file *os.File) error {
+	buf := make([]byte, 4)
+	n, _ := file.Read(buf)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if n >= 2 && buf[0] == 0x1f && buf[1] == 0x8b {
+		return fmt.Errorf("cannot follow compressed file %v", file.Name())
+	}
+	return nil
+}
+
+// Read reads available data and blocks at EOF until more data is written
+func (ptr *TailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := ptr.file.Read(p)
+		if n > 0 {
+			ptr.offset += int64(n)
+			return n, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if ptr.onIdle != nil {
+			ptr.onIdle()
+		}
+		select {
+		case <-ptr.stop:
+			return 0, io.EOF
+		case <-time.After(ptr.interval):
+		}
+		if err = ptr.checkRotation(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// checkRotation reopens the file if it was renamed and recreated, or rewinds
+// if it was truncated in place (copytruncate)
+func (ptr *TailReader) checkRotation() error {
+	info, err := os.Stat(ptr.filename)
+	if err != nil {
+		return nil // being rotated, check again later
+	}
+	current, err := ptr.file.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, current) {
+		if current.Size() > ptr.offset {
+			return nil // drain the rotated file first
+		}
+		file, err := os.Open(ptr.filename)
+		if err != nil {
+			return nil // recreated file may not be readable yet
+		}
+		log.Println("log rotated, reopening", ptr.filename)
+		ptr.file.Close()
+		ptr.file = file
+		ptr.offset = 0
+	} else if info.Size() < ptr.offset {
+		log.Println("log truncated, rewinding", ptr.filename)
+		if _, err = ptr.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		ptr.offset = 0
+	}
+	return nil
+}
+
+// Stop makes pending and future reads return io.EOF
+func (ptr *TailReader) Stop() {
+	ptr.once.Do(func() { close(ptr.stop) })
+}
+
+// Close closes the underlying file
+func (ptr *TailReader) Close() error {
+	ptr.Stop()
+	return ptr.file.Close()
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * follow_test.go
+ */
+
+package hatchet
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTailReader(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "mongod.log")
+	if err := os.WriteFile(filename, []byte("line1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tail, err := NewTailReader(filename, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tail.Close()
+	reader := bufio.NewReader(tail)
+	readLine := func(expected string) {
+		line, _, err := reader.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != expected {
+			t.Fatalf("expected %v, got %v", expected, string(line))
+		}
+	}
+	readLine("line1")
+
+	// appended lines
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("line2\n")
+	file.Close()
+	readLine("line2")
+
+	// rotated by renaming
+	if err = os.Rename(filename, filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filename, []byte("line3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	readLine("line3")
+
+	// truncated in place
+	if err = os.WriteFile(filename, []byte("4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	readLine("4")
+
+	tail.Stop()
+	if _, _, err = reader.ReadLine(); err != io.EOF {
+		t.Fatalf("expected EOF after stop, got %v", err)
+	}
+}
+
+func TestTailReaderCompressed(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "mongod.log.gz")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(file)
+	zw.Write([]byte("line1\n"))
+	zw.Close()
+	file.Close()
+	if _, err = NewTailReader(filename, time.Millisecond); err == nil {
+		t.Fatal("expected error following a compressed file")
+	}
+}
@@ -0,0 +1,428 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * ftdc.go
+ */
+
+package hatchet
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+const (
+	FTDC_METADATA          = 0
+	FTDC_METRIC_CHUNK      = 1
+	FTDC_PERIODIC_METADATA = 2
+	FTDC_BUCKET            = time.Minute // metrics are averaged by minute
+)
+
+// Metric stores an averaged value of a series of a chart
+type Metric struct {
+	Date  string  `json:"date" bson:"date"`
+	Type  string  `json:"type" bson:"type"` // opcounters, connections, cache, tickets, or replication
+	Name  string  `json:"name" bson:"name"`
+	Value float64 `json:"value" bson:"value"`
+}
+
+// FTDCChunk is a decoded metric chunk, values are indexed by [metric][sample]
+type FTDCChunk struct {
+	Keys   []string
+	Values [][]int64
+}
+
+// ftdcSeries defines a series to keep from serverStatus
+type ftdcSeries struct {
+	Type    string
+	Name    string
+	Paths   []string // candidates, the first found is used
+	Counter bool     // cumulative counter, stored as a rate per second
+	Scale   float64
+}
+
+var ftdcSeriesList = []ftdcSeries{
+	{"opcounters", "insert", []string{"serverStatus.opcounters.insert"}, true, 1},
+	{"opcounters", "query", []string{"serverStatus.opcounters.query"}, true, 1},
+	{"opcounters", "update", []string{"serverStatus.opcounters.update"}, true, 1},
+	{"opcounters", "delete", []string{"serverStatus.opcounters.delete"}, true, 1},
+	{"opcounters", "getmore", []string{"serverStatus.opcounters.getmore"}, true, 1},
+	{"opcounters", "command", []string{"serverStatus.opcounters.command"}, true, 1},
+	{"connections", "current", []string{"serverStatus.connections.current"}, false, 1},
+	{"connections", "active", []string{"serverStatus.connections.active"}, false, 1},
+	{"cache", "max MB", []string{"serverStatus.wiredTiger.cache.maximum bytes configured"}, false, 1.0 / (1024 * 1024)},
+	{"cache", "used MB", []string{"serverStatus.wiredTiger.cache.bytes currently in the cache"}, false, 1.0 / (1024 * 1024)},
+	{"cache", "dirty MB", []string{"serverStatus.wiredTiger.cache.tracked dirty bytes in the cache"}, false, 1.0 / (1024 * 1024)},
+	{"tickets", "read out", []string{"serverStatus.queues.execution.read.out",
+		"serverStatus.wiredTiger.concurrentTransactions.read.out"}, false, 1},
+	{"tickets", "write out", []string{"serverStatus.queues.execution.write.out",
+		"serverStatus.wiredTiger.concurrentTransactions.write.out"}, false, 1},
+	{"tickets", "read available", []string{"serverStatus.queues.execution.read.available",
+		"serverStatus.wiredTiger.concurrentTransactions.read.available"}, false, 1},
+	{"tickets", "write available", []string{"serverStatus.queues.execution.write.available",
+		"serverStatus.wiredTiger.concurrentTransactions.write.available"}, false, 1},
+}
+
+// ReadFTDCFile reads BSON documents of a FTDC file, metadata documents and
+// decoded metric chunks are passed to callbacks
+func ReadFTDCFile(filename string, onMetadata func(doc bson.Raw), onChunk func(chunk *FTDCChunk)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for {
+		doc, err := readBSONDocument(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		ftdcType, ok := doc.Lookup("type").AsInt64OK()
+		if !ok {
+			continue
+		}
+		if ftdcType == FTDC_METADATA {
+			if metadata, ok := doc.Lookup("doc").DocumentOK(); ok && onMetadata != nil {
+				onMetadata(metadata)
+			}
+		} else if ftdcType == FTDC_METRIC_CHUNK {
+			_, data, ok := doc.Lookup("data").BinaryOK()
+			if !ok {
+				continue
+			}
+			chunk, err := DecodeFTDCChunk(data)
+			if err != nil {
+				log.Printf("skipping a metric chunk of %v: %v", filename, err)
+				continue
+			}
+			onChunk(chunk)
+		}
+	}
+}
+
+// readBSONDocument reads a BSON document
+func readBSONDocument(reader io.Reader) (bson.Raw, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(header)
+	if size < 5 || size > MAX_DOC_SIZE {
+		return nil, fmt.Errorf("invalid BSON document size %v", size)
+	}
+	doc := make([]byte, size)
+	copy(doc, header)
+	if _, err := io.ReadFull(reader, doc[4:]); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// isFTDCFile checks if a file is a FTDC file of diagnostic.data
+func isFTDCFile(filename string) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	doc, err := readBSONDocument(file)
+	if err != nil || bson.Raw(doc).Validate() != nil {
+		return false
+	}
+	_, hasID := doc.Lookup("_id").TimeOK()
+	_, hasType := doc.Lookup("type").AsInt64OK()
+	return hasID && hasType
+}
+
+// DecodeFTDCChunk decodes a metric chunk, which is a uint32 length followed by
+// zlib compressed data of a reference document, the numbers of metrics and
+// deltas, and varint encoded deltas of each metric with zeros run-length encoded
+func DecodeFTDCChunk(data []byte) (*FTDCChunk, error) {
+	if len(data) < 4 {
+		return nil, errors.New("metric chunk too short")
+	}
+	zreader, err := zlib.NewReader(bytes.NewReader(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	defer zreader.Close()
+	buf, err := io.ReadAll(io.LimitReader(zreader, int64(binary.LittleEndian.Uint32(data[:4]))))
+	if err != nil {
+		return nil, err
+	}
+	reader := bytes.NewReader(buf)
+	ref, err := readBSONDocument(reader)
+	if err != nil {
+		return nil, err
+	}
+	chunk := &FTDCChunk{}
+	var refs []int64
+	extractFTDCMetrics(ref, "", &chunk.Keys, &refs)
+	var counts [2]uint32 // numbers of metrics and deltas
+	if err = binary.Read(reader, binary.LittleEndian, &counts); err != nil {
+		return nil, err
+	}
+	if int(counts[0]) != len(refs) {
+		return nil, fmt.Errorf("expected %v metrics, found %v in reference document", counts[0], len(refs))
+	}
+	chunk.Values = make([][]int64, len(refs))
+	var zeros uint64
+	for i := range refs {
+		chunk.Values[i] = make([]int64, counts[1]+1)
+		chunk.Values[i][0] = refs[i]
+		for j := 1; j <= int(counts[1]); j++ {
+			var delta uint64
+			if zeros > 0 {
+				zeros--
+			} else {
+				if delta, err = binary.ReadUvarint(reader); err != nil {
+					return nil, err
+				}
+				if delta == 0 {
+					if zeros, err = binary.ReadUvarint(reader); err != nil {
+						return nil, err
+					}
+				}
+			}
+			chunk.Values[i][j] = chunk.Values[i][j-1] + int64(delta)
+		}
+	}
+	return chunk, nil
+}
+
+// extractFTDCMetrics flattens numeric values of a document in order, the
+// same way mongod does when it writes metric chunks
+func extractFTDCMetrics(doc bson.Raw, prefix string, keys *[]string, values *[]int64) {
+	elements, _ := doc.Elements()
+	for _, element := range elements {
+		key := prefix + element.Key()
+		value := element.Value()
+		switch value.Type {
+		case bsontype.Double:
+			*keys = append(*keys, key)
+			*values = append(*values, int64(value.Double()))
+		case bsontype.Int32:
+			*keys = append(*keys, key)
+			*values = append(*values, int64(value.Int32()))
+		case bsontype.Int64:
+			*keys = append(*keys, key)
+			*values = append(*values, value.Int64())
+		case bsontype.Decimal128:
+			f, _ := strconv.ParseFloat(value.Decimal128().String(), 64)
+			*keys = append(*keys, key)
+			*values = append(*values, int64(f))
+		case bsontype.Boolean:
+			var b int64
+			if value.Boolean() {
+				b = 1
+			}
+			*keys = append(*keys, key)
+			*values = append(*values, b)
+		case bsontype.DateTime:
+			*keys = append(*keys, key)
+			*values = append(*values, value.DateTime())
+		case bsontype.Timestamp:
+			t, i := value.Timestamp()
+			*keys = append(*keys, key+".t", key+".i")
+			*values = append(*values, int64(t), int64(i))
+		case bsontype.EmbeddedDocument:
+			extractFTDCMetrics(value.Document(), key+".", keys, values)
+		case bsontype.Array:
+			extractFTDCMetrics(bson.Raw(value.Array()), key+".", keys, values)
+		}
+	}
+}
+
+// ftdcAggregator averages series of samples by minute
+type ftdcAggregator struct {
+	buckets  map[Metric]*[2]float64 // sum and count keyed by date, type and name
+	counters map[string][2]int64    // last value and time of counters
+	end      time.Time
+	start    time.Time
+}
+
+func newFTDCAggregator() *ftdcAggregator {
+	return &ftdcAggregator{buckets: map[Metric]*[2]float64{}, counters: map[string][2]int64{}}
+}
+
+func (ptr *ftdcAggregator) add(date time.Time, ctype string, name string, value float64) {
+	key := Metric{Date: getDateTimeStr(date.Truncate(FTDC_BUCKET)), Type: ctype, Name: name}
+	bucket, ok := ptr.buckets[key]
+	if !ok {
+		bucket = &[2]float64{}
+		ptr.buckets[key] = bucket
+	}
+	bucket[0] += value
+	bucket[1]++
+}
+
+// addChunk adds selected series of samples of a chunk
+func (ptr *ftdcAggregator) addChunk(chunk *FTDCChunk) {
+	indexes := map[string]int{}
+	for i, key := range chunk.Keys {
+		indexes[key] = i
+	}
+	timeIndex, ok := indexes["start"]
+	if !ok {
+		if timeIndex, ok = indexes["serverStatus.localTime"]; !ok {
+			return
+		}
+	}
+	series := map[int]ftdcSeries{}
+	for _, s := range ftdcSeriesList {
+		for _, path := range s.Paths {
+			if i, ok := indexes[path]; ok {
+				series[i] = s
+				break
+			}
+		}
+	}
+	members := getFTDCMembers(indexes)
+	for j, millis := range chunk.Values[timeIndex] {
+		date := time.UnixMilli(millis).UTC()
+		if ptr.start.IsZero() || date.Before(ptr.start) {
+			ptr.start = date
+		}
+		if date.After(ptr.end) {
+			ptr.end = date
+		}
+		for i, s := range series {
+			value := chunk.Values[i][j]
+			if !s.Counter {
+				ptr.add(date, s.Type, s.Name, float64(value)*s.Scale)
+				continue
+			}
+			last, ok := ptr.counters[s.Name]
+			ptr.counters[s.Name] = [2]int64{value, millis}
+			if !ok || value < last[0] || millis <= last[1] { // counters reset on restarts
+				continue
+			}
+			seconds := float64(millis-last[1]) / 1000
+			ptr.add(date, s.Type, s.Name, float64(value-last[0])*s.Scale/seconds)
+		}
+		if lag, ok := getReplicationLag(chunk, members, j); ok {
+			ptr.add(date, "replication", "max lag seconds", lag)
+		}
+	}
+}
+
+// getFTDCMembers returns indexes of optimeDate and state of replica set members
+func getFTDCMembers(indexes map[string]int) [][2]int {
+	var members [][2]int
+	for n := 0; ; n++ {
+		prefix := fmt.Sprintf("replSetGetStatus.members.%d.", n)
+		optime, ok := indexes[prefix+"optimeDate"]
+		if !ok {
+			return members
+		}
+		if state, ok := indexes[prefix+"state"]; ok {
+			members = append(members, [2]int{optime, state})
+		}
+	}
+}
+
+// getReplicationLag returns the max lag in seconds of secondaries behind the primary
+func getReplicationLag(chunk *FTDCChunk, members [][2]int, j int) (float64, bool) {
+	var primary int64
+	secondary := int64(math.MaxInt64)
+	for _, member := range members {
+		optime := chunk.Values[member[0]][j]
+		switch chunk.Values[member[1]][j] {
+		case 1: // PRIMARY
+			primary = optime
+		case 2: // SECONDARY
+			if optime < secondary {
+				secondary = optime
+			}
+		}
+	}
+	if primary == 0 || secondary == math.MaxInt64 {
+		return 0, false
+	}
+	return math.Max(0, float64(primary-secondary)/1000), true
+}
+
+// metrics returns averaged metrics in chronological order
+func (ptr *ftdcAggregator) metrics() []Metric {
+	var metrics []Metric
+	for key, bucket := range ptr.buckets {
+		key.Value = math.Round(100*bucket[0]/bucket[1]) / 100
+		metrics = append(metrics, key)
+	}
+	sort.Slice(metrics, func(i int, j int) bool {
+		if metrics[i].Date != metrics[j].Date {
+			return metrics[i].Date < metrics[j].Date
+		}
+		if metrics[i].Type != metrics[j].Type {
+			return metrics[i].Type < metrics[j].Type
+		}
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics
+}
+
+// analyzeFTDC decodes FTDC files of a diagnostic.data directory into metrics
+// of a hatchet.  Metrics are added to the hatchet of the node's logs if it
+// exists, otherwise a hatchet is created for the metrics.
+func (ptr *Logv2) analyzeFTDC(filenames []string, hatchetName string, standalone bool) error {
+	sort.Strings(filenames) // metrics.interim is after dated files
+	var version string
+	aggregator := newFTDCAggregator()
+	for _, filename := range filenames {
+		log.Println("decoding FTDC", filename)
+		err := ReadFTDCFile(filename, func(doc bson.Raw) {
+			if v, ok := doc.Lookup("buildInfo", "version").StringValueOK(); ok {
+				version = v
+			}
+		}, aggregator.addChunk)
+		if err != nil {
+			log.Printf("error decoding %v: %v", filename, err)
+		}
+	}
+	metrics := aggregator.metrics()
+	if len(metrics) == 0 {
+		return fmt.Errorf("no metrics found in %v", filenames)
+	}
+	log.Printf("adding %d metrics to hatchet %v", len(metrics), hatchetName)
+	dbase, err := GetDatabase(hatchetName)
+	if err != nil {
+		return err
+	}
+	defer dbase.Close()
+	if standalone {
+		err = dbase.Begin()
+	} else {
+		err = dbase.Append()
+	}
+	if err != nil {
+		return err
+	}
+	for _, metric := range metrics {
+		if err = dbase.InsertMetric(metric); err != nil {
+			return err
+		}
+	}
+	if err = dbase.Commit(); err != nil {
+		return err
+	}
+	if !standalone {
+		return nil
+	}
+	info := HatchetInfo{Start: getDateTimeStr(aggregator.start), End: getDateTimeStr(aggregator.end), Version: version}
+	return dbase.UpdateHatchetInfo(info)
+}
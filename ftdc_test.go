@@ -0,0 +1,262 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * ftdc_test.go
+ */
+
+package hatchet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// encodeFTDCChunk encodes samples of the same shape into a metric chunk
+func encodeFTDCChunk(t *testing.T, samples []bson.D) []byte {
+	var values [][]int64
+	var ref bson.Raw
+	for j, sample := range samples {
+		data, err := bson.Marshal(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		var metrics []int64
+		extractFTDCMetrics(data, "", &keys, &metrics)
+		if j == 0 {
+			ref = data
+			values = make([][]int64, len(metrics))
+		}
+		for i, value := range metrics {
+			values[i] = append(values[i], value)
+		}
+	}
+	var buf bytes.Buffer
+	buf.Write(ref)
+	binary.Write(&buf, binary.LittleEndian, [2]uint32{uint32(len(values)), uint32(len(samples) - 1)})
+	varint := make([]byte, binary.MaxVarintLen64)
+	zeros := uint64(0)
+	flush := func() {
+		if zeros > 0 {
+			buf.Write(varint[:binary.PutUvarint(varint, 0)])
+			buf.Write(varint[:binary.PutUvarint(varint, zeros-1)])
+			zeros = 0
+		}
+	}
+	for i := range values {
+		for j := 1; j < len(samples); j++ {
+			delta := uint64(values[i][j] - values[i][j-1])
+			if delta == 0 {
+				zeros++
+				continue
+			}
+			flush()
+			buf.Write(varint[:binary.PutUvarint(varint, delta)])
+		}
+	}
+	flush()
+	var compressed bytes.Buffer
+	compressed.Write(binary.LittleEndian.AppendUint32(nil, uint32(buf.Len())))
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(buf.Bytes())
+	writer.Close()
+	return compressed.Bytes()
+}
+
+// writeFTDCFile writes a metadata document and a metric chunk of samples every second
+func writeFTDCFile(t *testing.T, filename string, start time.Time, n int) {
+	var samples []bson.D
+	for j := 0; j < n; j++ {
+		date := start.Add(time.Duration(j) * time.Second)
+		samples = append(samples, bson.D{
+			{Key: "start", Value: primitive.NewDateTimeFromTime(date)},
+			{Key: "serverStatus", Value: bson.D{
+				{Key: "host", Value: "localhost"},
+				{Key: "opcounters", Value: bson.D{{Key: "insert", Value: int64(10 * j)}, {Key: "query", Value: int64(100)}}},
+				{Key: "connections", Value: bson.D{{Key: "current", Value: int32(5 + j%2)}, {Key: "active", Value: int32(2)}}},
+				{Key: "wiredTiger", Value: bson.D{{Key: "cache", Value: bson.D{
+					{Key: "bytes currently in the cache", Value: int64(512 * 1024 * 1024)},
+					{Key: "tracked dirty bytes in the cache", Value: 1.5 * 1024 * 1024},
+				}}}},
+			}},
+			{Key: "replSetGetStatus", Value: bson.D{{Key: "members", Value: bson.A{
+				bson.D{{Key: "optimeDate", Value: primitive.NewDateTimeFromTime(date)}, {Key: "state", Value: int32(1)}, {Key: "self", Value: true}},
+				bson.D{{Key: "optimeDate", Value: primitive.NewDateTimeFromTime(date.Add(-3 * time.Second))}, {Key: "state", Value: int32(2)}},
+				bson.D{{Key: "optimeDate", Value: primitive.Timestamp{T: uint32(date.Unix())}}, {Key: "state", Value: int32(7)}},
+			}}}},
+			{Key: "end", Value: primitive.NewDateTimeFromTime(date)},
+		})
+	}
+	metadata, _ := bson.Marshal(bson.D{{Key: "_id", Value: primitive.NewDateTimeFromTime(start)}, {Key: "type", Value: int32(FTDC_METADATA)},
+		{Key: "doc", Value: bson.D{{Key: "buildInfo", Value: bson.D{{Key: "version", Value: "7.0.2"}}}}}})
+	chunk, _ := bson.Marshal(bson.D{{Key: "_id", Value: primitive.NewDateTimeFromTime(start)}, {Key: "type", Value: int32(FTDC_METRIC_CHUNK)},
+		{Key: "data", Value: primitive.Binary{Data: encodeFTDCChunk(t, samples)}}})
+	os.MkdirAll(filepath.Dir(filename), 0755)
+	if err := os.WriteFile(filename, append(metadata, chunk...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeFTDCChunk(t *testing.T) {
+	start := time.Date(2024, 3, 18, 10, 0, 0, 0, time.UTC)
+	samples := []bson.D{}
+	for j := 0; j < 5; j++ {
+		samples = append(samples, bson.D{{Key: "start", Value: primitive.NewDateTimeFromTime(start.Add(time.Duration(j) * time.Second))},
+			{Key: "a", Value: int64(7)}, {Key: "b", Value: bson.D{{Key: "c", Value: int32(j * j)}, {Key: "ok", Value: j%2 == 0}}},
+			{Key: "ts", Value: primitive.Timestamp{T: 100, I: uint32(j)}}})
+	}
+	chunk, err := DecodeFTDCChunk(encodeFTDCChunk(t, samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"start", "a", "b.c", "b.ok", "ts.t", "ts.i"}
+	if len(chunk.Keys) != len(keys) {
+		t.Fatalf("expected keys %v, got %v", keys, chunk.Keys)
+	}
+	for i, key := range keys {
+		if chunk.Keys[i] != key || len(chunk.Values[i]) != 5 {
+			t.Fatalf("unexpected key %v with %d values", chunk.Keys[i], len(chunk.Values[i]))
+		}
+	}
+	if chunk.Values[0][4] != start.Add(4*time.Second).UnixMilli() || chunk.Values[1][4] != 7 ||
+		chunk.Values[2][3] != 9 || chunk.Values[3][3] != 0 || chunk.Values[5][4] != 4 {
+		t.Fatalf("unexpected values %v", chunk.Values)
+	}
+}
+
+// testdata/metrics.2024-03-18T10-00-00Z-00000 was written by the FTDC collector of github.com/mongodb/ftdc,
+// of 3 chunks of 60 samples every second of serverStatus and replSetGetStatus
+func TestReadFTDCFile(t *testing.T) {
+	var version string
+	var chunks []*FTDCChunk
+	err := ReadFTDCFile("testdata/metrics.2024-03-18T10-00-00Z-00000", func(doc bson.Raw) {
+		version, _ = doc.Lookup("buildInfo", "version").StringValueOK()
+	}, func(chunk *FTDCChunk) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "6.0.5" || len(chunks) != 3 {
+		t.Fatalf("expected metadata of 6.0.5 and 3 chunks, got %v and %d chunks", version, len(chunks))
+	}
+	indexes := map[string]int{}
+	for i, key := range chunks[2].Keys {
+		indexes[key] = i
+	}
+	start := time.Date(2024, 3, 18, 10, 0, 0, 0, time.UTC)
+	values := chunks[2].Values
+	if len(values[indexes["start"]]) != 60 || values[indexes["start"]][59] != start.Add(179*time.Second).UnixMilli() ||
+		values[indexes["serverStatus.opcounters.insert"]][59] != 1790 ||
+		values[indexes["serverStatus.repl.lastWrite.opTime.ts.t"]][0] != start.Add(120*time.Second).Unix() ||
+		values[indexes["replSetGetStatus.members.1.state"]][0] != 2 {
+		t.Fatalf("unexpected values of keys %v", chunks[2].Keys)
+	}
+
+	aggregator := newFTDCAggregator()
+	for _, chunk := range chunks {
+		aggregator.addChunk(chunk)
+	}
+	metrics := map[string]float64{}
+	for _, metric := range aggregator.metrics() {
+		if metric.Date == "2024-03-18T10:01:00.000-0000" {
+			metrics[metric.Type+"."+metric.Name] = metric.Value
+		}
+	}
+	if metrics["opcounters.insert"] != 10 || metrics["opcounters.query"] != 100 || metrics["connections.active"] != 5 ||
+		metrics["cache.max MB"] != 8192 || metrics["tickets.read out"] != 2 || metrics["replication.max lag seconds"] != 2 {
+		t.Fatalf("unexpected metrics %v", metrics)
+	}
+}
+
+func TestAnalyzeFTDC(t *testing.T) {
+	tmpDir := useTestDatabase(t)
+	start := time.Date(2024, 3, 18, 10, 0, 0, 0, time.UTC)
+	bundle := filepath.Join(tmpDir, "bundle")
+	writeFTDCFile(t, filepath.Join(bundle, "rs0-0", "diagnostic.data", "metrics.2024-03-18T10-00-00Z-00000"), start, 120)
+	writeFTDCFile(t, filepath.Join(bundle, "rs0-1", "diagnostic.data", "metrics.interim"), start, 60)
+	logline := `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"127.0.0.1:50000","connectionCount":1}}`
+	// logs of two nodes sharing a directory, metrics of rs0-2 are not attached to either of them
+	writeFTDCFile(t, filepath.Join(bundle, "rs0-2", "diagnostic.data", "metrics.interim"), start, 60)
+	for _, name := range []string{"rs0-0/mongod.log", "rs0-2/mongod.log", "rs0-2/mongos.log"} {
+		if err := os.WriteFile(filepath.Join(bundle, name), []byte(logline+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logv2 := &Logv2{testing: true, url: instance.url, to: time.Now()}
+	if err := logv2.Analyze(bundle, 0); err != nil {
+		t.Fatal(err)
+	}
+	names, err := GetExistingHatchetNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 5 {
+		t.Fatalf("expected hatchets of rs0-0 and rs0-2 logs and rs0-1 and rs0-2 metrics, got %v", names)
+	}
+
+	dbase, err := NewSQLite3DB(instance.url, "rs0_0_mongod", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbase.Close()
+	docs, err := dbase.GetMetrics("opcounters", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := GetMetricsTable(docs)
+	if len(table.Names) != 2 || len(table.Rows) != 2 {
+		t.Fatalf("expected 2 series by minutes, got %v", table)
+	}
+	for _, row := range table.Rows {
+		if row.Values[0] != 10.0 || row.Values[1] != 0.0 { // insert and query per second
+			t.Fatalf("unexpected opcounters %v", row)
+		}
+	}
+	if docs, err = dbase.GetMetrics("replication", "2024-03-18T10:00,2024-03-18T10:00:59"); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Value != 3 {
+		t.Fatalf("expected 3 seconds of lag, got %v", docs)
+	}
+	if docs, err = dbase.GetMetrics("cache", ""); err != nil {
+		t.Fatal(err)
+	}
+	if table = GetMetricsTable(docs); len(table.Names) != 2 || table.Rows[0].Values[0] != 1.5 {
+		t.Fatalf("unexpected cache metrics %v", table)
+	}
+
+	metrics, err := NewSQLite3DB(instance.url, "rs0_1_diagnostic_data", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metrics.Close()
+	if info := metrics.GetHatchetInfo(); info.Version != "7.0.2" {
+		t.Fatalf("unexpected hatchet info %+v", info)
+	}
+	for _, name := range []string{"rs0_2_mongod", "rs0_2_mongos"} {
+		node, err := NewSQLite3DB(instance.url, name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer node.Close()
+		if docs, err = node.GetMetrics("opcounters", ""); err != nil || len(docs) != 0 {
+			t.Fatalf("expected no metrics of %v, got %v, %v", name, docs, err)
+		}
+	}
+	node, err := NewSQLite3DB(instance.url, "rs0_2_diagnostic_data", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	if docs, err = node.GetMetrics("opcounters", ""); err != nil || len(docs) == 0 {
+		t.Fatalf("expected metrics of rs0-2, got %v, %v", docs, err)
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/aws/aws-sdk-go v1.44.219
 	github.com/brianvoe/gofakeit/v6 v6.24.0
 	github.com/julienschmidt/httprouter v1.3.0
+	github.com/klauspost/compress v1.16.7
 	github.com/simagix/gox v0.3.0
 	go.mongodb.org/mongo-driver v1.17.6
 	golang.org/x/text v0.31.0
@@ -17,7 +18,6 @@ require (
 	github.com/golang/snappy v1.0.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	github.com/klauspost/compress v1.16.7 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
 	github.com/ncruces/go-strftime v0.1.9 // indirect
@@ -27,24 +27,34 @@ import (
 const SQLITE3_FILE = "./data/hatchet.db"
 
 func Run(fullVersion string) {
+	atlas := flag.String("atlas", "", "download logs of an Atlas cluster, <project ID>/<cluster name>")
 	bios := flag.Bool("bios", false, "populate bios documents")
 	cache := flag.Int("cache_size", 2000, "number of cache pages")
 	connstr := flag.String("url", SQLITE3_FILE, "database file name or connection string")
 	digest := flag.Bool("digest", false, "HTTP digest")
 	endpoint := flag.String("endpoint-url", "", "AWS endpoint")
+	exclude := flag.String("exclude", "", "comma-separated glob patterns of files to skip in directories and archives")
+	follow := flag.Bool("follow", false, "follow a growing log file and ingest new lines")
 	from := flag.String("from", "1970-01-01T00:00:00Z", "from date/time")
+	include := flag.String("include", "", "comma-separated glob patterns of files to analyze in directories and archives")
 	merge := flag.Bool("merge", false, "merge files")
+	mergeRotated := flag.Bool("merge-rotated", false, "merge rotated logs of a node into one hatchet")
 	legacy := flag.Bool("legacy", false, "view logs in legacy format")
+	hosts := flag.String("hosts", "", "comma-separated hosts of -atlas, defaults to all nodes")
 	infile := flag.String("obfuscate", "", "obfuscate logs")
 	port := flag.Int("port", 3721, "web server port number")
 	profile := flag.String("aws-profile", "default", "AWS profile name")
-	s3 := flag.Bool("s3", false, "files from AWS S3")
+	rebuild := flag.Bool("rebuild", false, "ignore checkpoints and rebuild hatchets from the beginning")
+	refresh := flag.Duration("refresh", FOLLOW_REFRESH, "stats refresh interval of -follow")
+	s3 := flag.Bool("s3", false, "files from AWS S3, bucket/key or a prefix, e.g. s3://bucket/case-123/")
 	sim := flag.String("sim", "", "simulate read/write load tests")
+	timeout := flag.Duration("timeout", HTTP_TIMEOUT, "HTTP connect, response and idle read timeout")
 	to := flag.String("to", "", "from date/time")
 	user := flag.String("user", "", "HTTP Auth (username:password)")
 	verbose := flag.Bool("v", false, "turn on verbose")
 	ver := flag.Bool("version", false, "print version number")
 	web := flag.Bool("web", false, "starts a web server")
+	window := flag.Duration("window", ATLAS_WINDOW, "time span of each log download of -atlas")
 	server := flag.Bool("server", false, "starts a web server (alias for -web)")
 	report := flag.Bool("report", false, "generate HTML reports to ./html directory")
 	flag.Parse()
@@ -126,12 +136,33 @@ func Run(fullVersion string) {
 		fromTime, err = time.Parse(layout, "2000-01-01T00:00:00")
 	}
 	toTime, err := time.Parse(layout, *to)
-	if err != nil {
+	if err != nil && *follow {
+		toTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC) // keep new logs
+	} else if err != nil {
 		toTime = time.Now()
 	}
 	logv2 := Logv2{version: fullVersion, url: *connstr, verbose: *verbose,
 		legacy: *legacy, user: *user, isDigest: *digest, cacheSize: *cache,
-		from: fromTime, to: toTime, merge: *merge}
+		from: fromTime, to: toTime, merge: *merge, follow: *follow, refresh: *refresh,
+		rebuild: *rebuild, resume: !*merge && !*follow && !*legacy && *from == "" && *to == "", timeout: *timeout,
+		include: *include, exclude: *exclude, mergeRotated: *mergeRotated}
+	if *follow && (len(flag.Args()) != 1 || *merge || *legacy) {
+		log.Fatalln("-follow requires exactly one log file and cannot be used with -merge or -legacy")
+	}
+	var atlasClient *AtlasClient
+	if *atlas != "" {
+		if *follow || len(flag.Args()) > 0 {
+			log.Fatalln("-atlas cannot be used with -follow or log files")
+		}
+		if atlasClient, err = NewAtlasClient(ATLAS_API_URL, *atlas, *user, *hosts); err != nil {
+			log.Fatal(err)
+		}
+		atlasClient.SetWindow(*window)
+		atlasClient.SetTimeout(*timeout)
+		if !flagset["from"] {
+			logv2.from = toTime.Add(-24 * time.Hour) // last 24 hours by default
+		}
+	}
 	if *merge {
 		logv2.hatchetName = getHatchetName("merge")
 	}
@@ -162,12 +193,25 @@ func Run(fullVersion string) {
 		existingSet[name] = true
 	}
 
-	for i, logname := range flag.Args() {
-		if err := logv2.Analyze(logname, i+1); err != nil {
+	if *follow && *web {
+		// keep following in the background while serving the web UI
+		go func() {
+			if err := logv2.Analyze(flag.Args()[0], 1); err != nil {
+				log.Println(err)
+			}
+		}()
+	} else if atlasClient != nil {
+		if err := logv2.AnalyzeAtlas(atlasClient); err != nil {
 			log.Fatal(err)
 		}
-		if !*merge && !*legacy {
-			logv2.PrintSummary()
+	} else {
+		for i, logname := range flag.Args() {
+			if err := logv2.Analyze(logname, i+1); err != nil {
+				log.Fatal(err)
+			}
+			if !*merge && !*legacy {
+				logv2.PrintSummary()
+			}
 		}
 	}
 	if *merge && !*legacy {
@@ -175,7 +219,7 @@ func Run(fullVersion string) {
 	}
 
 	// Generate HTML reports if -report flag is set
-	if *report && len(flag.Args()) > 0 {
+	if *report && (len(flag.Args()) > 0 || atlasClient != nil) {
 		existingAfter, _ := GetExistingHatchetNames()
 		for _, name := range existingAfter {
 			if !existingSet[name] {
@@ -189,7 +233,7 @@ func Run(fullVersion string) {
 	}
 
 	if *legacy || !*web {
-		if len(flag.Args()) == 0 {
+		if len(flag.Args()) == 0 && atlasClient == nil {
 			flag.PrintDefaults()
 		}
 		return
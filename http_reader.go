@@ -7,69 +7,297 @@ package hatchet
 
 import (
 	"bufio"
-	"encoding/base64"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"strings"
+	"time"
+)
 
-	"github.com/simagix/gox"
+const (
+	HTTP_MAX_RESUMES = 5
+	HTTP_TIMEOUT     = 30 * time.Second
 )
 
-func GetHTTPContent(url, username, password string) (*bufio.Reader, error) {
-	var err error
-	var req *http.Request
+// HTTPReader streams the body of a URL and resumes with Range requests after a dropped connection
+type HTTPReader struct {
+	body     io.ReadCloser
+	cancel   context.CancelFunc
+	client   *http.Client
+	digest   bool
+	offset   int64 // bytes read
+	password string
+	percent  int64
+	progress bool // print progress to stderr
+	resumes  int
+	timeout  time.Duration // connect, response header and idle read timeout
+	timer    *time.Timer
+	total    int64 // Content-Length, -1 if unknown
+	url      string
+	username string
+}
 
-	client := &http.Client{}
+// NewHTTPReader sends a GET request with basic or digest authentication and returns a reader of the body
+func NewHTTPReader(url, username, password string, digest bool, timeout time.Duration) (*HTTPReader, error) {
+	if timeout <= 0 {
+		timeout = HTTP_TIMEOUT
+	}
+	ptr := &HTTPReader{client: newHTTPClient(timeout), digest: digest, password: password,
+		percent: -1, timeout: timeout, total: -1, url: url, username: username}
+	if err := ptr.open(); err != nil {
+		return nil, err
+	}
+	return ptr, nil
+}
 
-	if username != "" && password != "" {
-		auth := username + ":" + password
-		base64Auth := base64.StdEncoding.EncodeToString([]byte(auth))
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
+// newHTTPClient returns a client of connect, TLS handshake and response header timeouts
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+	transport.DisableCompression = true // keep offsets of Range requests in bytes of the body
+	return &http.Client{Transport: transport}
+}
+
+// SetProgress turns on the progress indicator based on Content-Length
+func (ptr *HTTPReader) SetProgress(progress bool) {
+	ptr.progress = progress
+}
+
+// Read reads the body, reconnecting from the last offset when the connection drops or stalls
+func (ptr *HTTPReader) Read(p []byte) (int, error) {
+	for {
+		if ptr.body == nil {
+			return 0, io.EOF
 		}
-		req.Header.Set("Authorization", "Basic "+base64Auth)
-	} else {
-		req, err = http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, err
+		ptr.timer.Reset(ptr.timeout)
+		n, err := ptr.body.Read(p)
+		ptr.timer.Stop()
+		ptr.offset += int64(n)
+		ptr.printProgress()
+		if err == nil || (n > 0 && err != io.EOF) {
+			return n, nil
+		} else if err == io.EOF && (ptr.total < 0 || ptr.offset >= ptr.total) {
+			ptr.Close()
+			return n, io.EOF
+		}
+		if ptr.total < 0 || ptr.resumes >= HTTP_MAX_RESUMES {
+			ptr.Close()
+			return n, fmt.Errorf("error reading %v at byte %d: %v", ptr.url, ptr.offset, err)
+		}
+		ptr.resumes++
+		log.Printf("connection dropped at byte %d of %d (%v), resuming", ptr.offset, ptr.total, err)
+		if err = ptr.open(); err != nil {
+			ptr.Close()
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
 		}
 	}
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// Close closes the body
+func (ptr *HTTPReader) Close() error {
+	if ptr.body == nil {
+		return nil
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("http failed: %v", resp.Status)
+	ptr.timer.Stop()
+	ptr.cancel()
+	err := ptr.body.Close()
+	ptr.body = nil
+	if ptr.progress && ptr.total > 0 {
+		fmt.Fprint(os.Stderr, "\r     \r")
 	}
-	defer resp.Body.Close()
+	return err
+}
 
-	buf, err := io.ReadAll(resp.Body)
+// open sends a request from the current offset
+func (ptr *HTTPReader) open() error {
+	if ptr.body != nil {
+		ptr.timer.Stop()
+		ptr.cancel()
+		ptr.body.Close()
+		ptr.body = nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	headers := map[string]string{}
+	if ptr.offset > 0 {
+		headers["Range"] = fmt.Sprintf("bytes=%d-", ptr.offset)
+	}
+	resp, err := httpGet(ctx, ptr.client, ptr.url, ptr.username, ptr.password, ptr.digest, headers)
 	if err != nil {
-		return nil, err
+		cancel()
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("http failed: %v", resp.Status)
 	}
-	return GetBufioReader(buf)
+	if ptr.offset > 0 && resp.StatusCode == http.StatusOK { // Range not supported, skip bytes read
+		if _, err = io.CopyN(io.Discard, resp.Body, ptr.offset); err != nil {
+			resp.Body.Close()
+			cancel()
+			return err
+		}
+	} else if ptr.offset == 0 {
+		ptr.total = resp.ContentLength
+	}
+	ptr.body = resp.Body
+	ptr.cancel = cancel
+	ptr.timer = time.AfterFunc(ptr.timeout, cancel) // reset on every read, fired when stalled
+	return nil
 }
 
-func GetHTTPDigestContent(url, user, secret string) (*bufio.Reader, error) {
-	var err error
-	var resp *http.Response
+// printProgress prints percentage of bytes read
+func (ptr *HTTPReader) printProgress() {
+	if !ptr.progress || ptr.total <= 0 {
+		return
+	}
+	if percent := (100 * ptr.offset) / ptr.total; percent != ptr.percent {
+		ptr.percent = percent
+		fmt.Fprintf(os.Stderr, "\r%3d%% \r", percent)
+	}
+}
 
-	headers := map[string]string{}
-	var body []byte
-	if resp, err = gox.HTTPDigest("GET", url, user, secret, headers, body); err != nil {
+// httpGet sends a GET request with basic or digest authentication
+func httpGet(ctx context.Context, client *http.Client, url, username, password string, digest bool, headers map[string]string) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		if username != "" && password != "" && !digest {
+			req.SetBasicAuth(username, password)
+		}
+		return req, nil
+	}
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil || !digest || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if req, err = newRequest(); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("http failed: %v", resp.Status)
+	if err = setDigestAuth(req, challenge, username, password); err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// setDigestAuth sets the Authorization header answering a digest challenge, e.g.
+// Digest realm="MMS Public API", nonce="...", qop="auth,auth-int", algorithm=MD5
+func setDigestAuth(req *http.Request, challenge string, username string, password string) error {
+	if !strings.HasPrefix(challenge, "Digest ") {
+		return errors.New("digest authentication is not supported")
+	}
+	params := parseDigestParams(challenge[len("Digest "):])
+	if algorithm := params["algorithm"]; algorithm != "" && !strings.EqualFold(algorithm, "MD5") {
+		return fmt.Errorf("digest algorithm %v is not supported", algorithm)
+	}
+	qop := ""
+	for _, value := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(value) == "auth" {
+			qop = "auth"
+		}
+	}
+	if params["qop"] != "" && qop == "" {
+		return fmt.Errorf("digest qop %v is not supported", params["qop"])
+	}
+	hash := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	uri := req.URL.RequestURI()
+	ha1 := hash(username + ":" + params["realm"] + ":" + password)
+	ha2 := hash(req.Method + ":" + uri)
+	auth := fmt.Sprintf(`Digest username="%v", realm="%v", nonce="%v", uri="%v"`, username, params["realm"],
+		params["nonce"], uri)
+	if qop == "" { // RFC 2069
+		auth += fmt.Sprintf(`, response="%v"`, hash(ha1+":"+params["nonce"]+":"+ha2))
+	} else {
+		b := make([]byte, 8)
+		rand.Read(b)
+		cnonce := hex.EncodeToString(b)
+		response := hash(fmt.Sprintf("%v:%v:00000001:%v:%v:%v", ha1, params["nonce"], cnonce, qop, ha2))
+		auth += fmt.Sprintf(`, cnonce="%v", nc=00000001, qop=%v, response="%v"`, cnonce, qop, response)
+	}
+	if opaque, ok := params["opaque"]; ok {
+		auth += fmt.Sprintf(`, opaque="%v"`, opaque)
+	}
+	if algorithm, ok := params["algorithm"]; ok {
+		auth += ", algorithm=" + algorithm
+	}
+	req.Header.Set("Authorization", auth)
+	return nil
+}
+
+// parseDigestParams returns comma-separated parameters of a digest header of tokens or quoted
+// strings, which may have commas and escaped quotes
+func parseDigestParams(str string) map[string]string {
+	params := map[string]string{}
+	for {
+		str = strings.TrimLeft(str, ", \t")
+		i := strings.IndexByte(str, '=')
+		if i < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(str[:i]))
+		str = strings.TrimLeft(str[i+1:], " \t")
+		var value strings.Builder
+		if strings.HasPrefix(str, `"`) {
+			i = 1
+			for ; i < len(str) && str[i] != '"'; i++ {
+				if str[i] == '\\' && i+1 < len(str) {
+					i++
+				}
+				value.WriteByte(str[i])
+			}
+			str = str[min(i+1, len(str)):]
+		} else {
+			if i = strings.IndexByte(str, ','); i < 0 {
+				i = len(str)
+			}
+			value.WriteString(strings.TrimSpace(str[:i]))
+			str = str[i:]
+		}
+		params[key] = value.String()
 	}
-	defer resp.Body.Close()
+}
 
-	buf, err := io.ReadAll(resp.Body)
+// GetHTTPContent returns a streaming reader of a URL with optional basic authentication
+func GetHTTPContent(url, username, password string) (*bufio.Reader, error) {
+	reader, err := NewHTTPReader(url, username, password, false, HTTP_TIMEOUT)
 	if err != nil {
 		return nil, err
 	}
+	return NewStreamReader(reader)
+}
 
-	return GetBufioReader(buf)
+// GetHTTPDigestContent returns a streaming reader of a URL with digest authentication
+func GetHTTPDigestContent(url, user, secret string) (*bufio.Reader, error) {
+	reader, err := NewHTTPReader(url, user, secret, true, HTTP_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamReader(reader)
 }
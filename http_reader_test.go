@@ -6,8 +6,21 @@
 package hatchet
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 func TestGetHTTPContent(t *testing.T) {
@@ -22,3 +35,130 @@ func TestGetHTTPContent(t *testing.T) {
 	}
 	t.Log(string(content))
 }
+
+// newRangeServer serves data with Range support, dropping the connection of the first
+// request or stalling it after half of data
+func newRangeServer(t *testing.T, data []byte, stall time.Duration, ranges *[]string) *httptest.Server {
+	var mu sync.Mutex
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		if !first {
+			http.ServeContent(w, r, "mongod.log", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data[:len(data)/2])
+		w.(http.Flusher).Flush()
+		if stall > 0 {
+			time.Sleep(stall)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+}
+
+func TestHTTPReaderResume(t *testing.T) {
+	var logs strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&logs, `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"NETWORK","id":22943,"msg":"Connection accepted","attr":{"connectionCount":%d}}`+"\n", i)
+	}
+	encoder, _ := zstd.NewWriter(nil)
+	compressed := encoder.EncodeAll([]byte(logs.String()), nil)
+	for _, stall := range []time.Duration{0, time.Second} {
+		var ranges []string
+		server := newRangeServer(t, compressed, stall, &ranges)
+		httpReader, err := NewHTTPReader(server.URL+"/mongod.log.zst", "", "", false, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := NewStreamReader(httpReader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		httpReader.Close()
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != logs.String() {
+			t.Fatalf("expected %d bytes of logs, got %d", logs.Len(), len(content))
+		}
+		if len(ranges) != 2 || ranges[1] != fmt.Sprintf("bytes=%d-", len(compressed)/2) {
+			t.Fatalf("expected a Range request resuming from byte %d, got %v", len(compressed)/2, ranges)
+		}
+	}
+}
+
+func TestHTTPDigest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := parseDigestParams(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "))
+		hash := func(s string) string {
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}
+		ha1 := hash(`public:MMS "Public", API:private`)
+		ha2 := hash("GET:" + r.URL.RequestURI())
+		expected := hash(strings.Join([]string{ha1, "abc123", params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		if params["uri"] != r.URL.RequestURI() || params["response"] != expected || params["qop"] != "auth" ||
+			params["opaque"] != "xyz" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="MMS \"Public\", API", domain="", nonce="abc123", algorithm=MD5, qop="auth-int,auth", opaque="xyz", stale=false`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintln(w, "authenticated")
+	}))
+	defer server.Close()
+	reader, err := GetHTTPDigestContent(server.URL+"/logs/mongodb.gz?startDate=1", "public", "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line, _, _ := reader.ReadLine(); string(line) != "authenticated" {
+		t.Fatalf("unexpected response %v", string(line))
+	}
+	if _, err = GetHTTPDigestContent(server.URL+"/logs/mongodb.gz", "public", "wrong"); err == nil {
+		t.Fatal("expected 401 Unauthorized")
+	}
+}
+
+func TestAnalyzeHTTP(t *testing.T) {
+	useTestDatabase(t)
+	data, err := os.ReadFile("logs/sample-mongod.log.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "mongod.log.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+	logv2 := &Logv2{testing: true, url: instance.url, to: time.Now(), user: "user:secret"}
+	if err = logv2.Analyze(server.URL+"/sample-mongod.log.gz", 1); err != nil {
+		t.Fatal(err)
+	}
+	dbase, err := NewSQLite3DB(instance.url, logv2.hatchetName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbase.Close()
+	var count int
+	if err = dbase.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %v", logv2.hatchetName)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Fatal("expected logs streamed over HTTP")
+	}
+}
@@ -0,0 +1,433 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * index_advisor.go
+ */
+
+package hatchet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// equalityOps match a value or values, ranked first by the ESR rule
+var equalityOps = map[string]bool{"$all": true, "$elemMatch": true, "$eq": true, "$in": true}
+
+// unindexableOps need indexes other than a compound index, e.g. text or 2dsphere
+var unindexableOps = map[string]bool{"$geoIntersects": true, "$geoWithin": true, "$near": true, "$nearSphere": true,
+	"$text": true, "$where": true}
+
+// IndexAdvice is a suggested compound index of a namespace
+type IndexAdvice struct {
+	Count      int      `json:"count"`    // number of ops
+	Indexes    []string `json:"indexes"`  // plans used, e.g. COLLSCAN
+	Key        string   `json:"key"`      // index key, e.g. { a:1, b:-1, c:1 }
+	Name       string   `json:"name"`     // index name, e.g. a_1_b_-1_c_1
+	Namespace  string   `json:"ns"`       // database.collection
+	Patterns   []string `json:"patterns"` // query patterns supported
+	TotalMilli int      `json:"total_ms"` // total milliseconds of ops to be saved
+
+	fields []indexField
+}
+
+// indexField is a field of an index key
+type indexField struct {
+	Equality bool // equality fields are interchangeable in order
+	Name     string
+	Value    int
+}
+
+// shapeField is a field of a query pattern, of which the value is a token, a []shapeField or a []interface{}
+type shapeField struct {
+	Key   string
+	Value interface{}
+}
+
+// GetIndexAdvice proposes compound indexes of COLLSCAN and poorly targeted patterns of slow ops.
+// Fields are ordered by the ESR rule, equality first, then sort and range. An index of which
+// the key is a prefix of another of the same namespace is merged into the longer one. Indexes
+// are sorted by total milliseconds of ops they support.
+func GetIndexAdvice(ops []OpStat) []IndexAdvice {
+	suggestions := map[string]*IndexAdvice{}
+	for _, op := range ops {
+		if op.Index != "COLLSCAN" && !op.PoorlyTargeted {
+			continue
+		} else if op.Op == cmdInsert || op.QueryPattern == "" || strings.HasPrefix(op.Index, "ErrMsg:") {
+			continue
+		}
+		fields, err := getIndexFields(op.QueryPattern, op.SortShape)
+		if err != nil || len(fields) == 0 || isIndexUsed(fields, op.Index) {
+			continue
+		}
+		key := getIndexKey(fields)
+		id := op.Namespace + " " + key
+		advice := suggestions[id]
+		if advice == nil {
+			advice = &IndexAdvice{Key: key, Name: getIndexName(fields), Namespace: op.Namespace, fields: fields}
+			suggestions[id] = advice
+		}
+		advice.add(IndexAdvice{Count: op.Count, Indexes: []string{op.Index}, Patterns: []string{op.QueryPattern}, TotalMilli: op.TotalMilli})
+	}
+
+	list := []*IndexAdvice{}
+	for _, advice := range suggestions {
+		list = append(list, advice)
+	}
+	sort.Slice(list, func(i, j int) bool { // longer keys first to merge prefixes into
+		if len(list[i].fields) == len(list[j].fields) {
+			return list[i].TotalMilli > list[j].TotalMilli || (list[i].TotalMilli == list[j].TotalMilli && list[i].Key < list[j].Key)
+		}
+		return len(list[i].fields) > len(list[j].fields)
+	})
+	merged := []*IndexAdvice{}
+	for _, advice := range list {
+		var longer *IndexAdvice
+		for _, other := range merged {
+			if other.Namespace == advice.Namespace && isKeyPrefix(advice.fields, other.fields) {
+				longer = other
+				break
+			}
+		}
+		if longer != nil {
+			longer.add(*advice)
+		} else {
+			merged = append(merged, advice)
+		}
+	}
+	advices := []IndexAdvice{}
+	for _, advice := range merged {
+		advices = append(advices, *advice)
+	}
+	sort.Slice(advices, func(i, j int) bool {
+		if advices[i].TotalMilli == advices[j].TotalMilli {
+			return advices[i].Namespace+advices[i].Key < advices[j].Namespace+advices[j].Key
+		}
+		return advices[i].TotalMilli > advices[j].TotalMilli
+	})
+	return advices
+}
+
+// getIndexFields returns fields of an index of a query pattern and a sort by the ESR rule,
+// equality fields in alphabetical order, sort fields in order and then range fields
+func getIndexFields(pattern string, sortShape string) ([]indexField, error) {
+	filter, err := parseShape(pattern)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := filter.([]shapeField)
+	if !ok {
+		return nil, errors.New("query pattern is not a document")
+	}
+	equalities := []string{}
+	ranges := []string{}
+	for _, field := range flattenAnd(doc) {
+		if strings.HasPrefix(field.Key, "$") { // $or, $expr etc.
+			continue
+		}
+		switch getFieldType(field.Value) {
+		case "equality":
+			equalities = append(equalities, field.Key)
+		case "range":
+			ranges = append(ranges, field.Key)
+		}
+	}
+	sort.Strings(equalities)
+	sort.Strings(ranges)
+	fields := []indexField{}
+	names := map[string]bool{}
+	for _, name := range equalities {
+		if !names[name] {
+			fields = append(fields, indexField{Equality: true, Name: name, Value: 1})
+			names[name] = true
+		}
+	}
+	if sortShape != "" {
+		if sortDoc, err := parseShape(sortShape); err == nil {
+			keys, _ := sortDoc.([]shapeField)
+			for _, key := range keys {
+				if names[key.Key] {
+					continue
+				}
+				value := 1
+				if token, ok := key.Value.(string); ok && strings.HasPrefix(token, "-") {
+					value = -1
+				}
+				fields = append(fields, indexField{Name: key.Key, Value: value})
+				names[key.Key] = true
+			}
+		}
+	}
+	for _, name := range ranges {
+		if !names[name] {
+			fields = append(fields, indexField{Name: name, Value: 1})
+			names[name] = true
+		}
+	}
+	return fields, nil
+}
+
+// GetCreateIndexesScript returns a mongo shell script creating suggested indexes by collections
+func GetCreateIndexesScript(advices []IndexAdvice) string {
+	var buf strings.Builder
+	namespaces := []string{}
+	indexes := map[string][]IndexAdvice{}
+	for _, advice := range advices {
+		if indexes[advice.Namespace] == nil {
+			namespaces = append(namespaces, advice.Namespace)
+		}
+		indexes[advice.Namespace] = append(indexes[advice.Namespace], advice)
+	}
+	for _, ns := range namespaces {
+		dbName, collName := ns, ns
+		if i := strings.Index(ns, "."); i > 0 {
+			dbName, collName = ns[:i], ns[i+1:]
+		}
+		specs := []string{}
+		for _, advice := range indexes[ns] {
+			buf.WriteString(fmt.Sprintf("// %v %v: %d ops, %d ms\n", ns, advice.Key, advice.Count, advice.TotalMilli))
+			specs = append(specs, fmt.Sprintf("\t\t{ key: %v, name: %q }", getIndexKeyJSON(advice.fields), advice.Name))
+		}
+		buf.WriteString(fmt.Sprintf("db.getSiblingDB(%q).runCommand({\n\tcreateIndexes: %q,\n\tindexes: [\n%v\n\t]\n});\n\n",
+			dbName, collName, strings.Join(specs, ",\n")))
+	}
+	return buf.String()
+}
+
+// add adds ops, plans and patterns of another suggestion
+func (ptr *IndexAdvice) add(advice IndexAdvice) {
+	ptr.Count += advice.Count
+	ptr.TotalMilli += advice.TotalMilli
+	ptr.Indexes = appendDistinct(ptr.Indexes, advice.Indexes...)
+	ptr.Patterns = appendDistinct(ptr.Patterns, advice.Patterns...)
+}
+
+// appendDistinct appends values not in a list
+func appendDistinct(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, v := range list {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// flattenAnd returns fields of a filter with those of $and merged
+func flattenAnd(doc []shapeField) []shapeField {
+	fields := []shapeField{}
+	for _, field := range doc {
+		if field.Key != "$and" {
+			fields = append(fields, field)
+			continue
+		}
+		list, _ := field.Value.([]interface{})
+		for _, v := range list {
+			if sub, ok := v.([]shapeField); ok {
+				fields = append(fields, flattenAnd(sub)...)
+			}
+		}
+	}
+	return fields
+}
+
+// getFieldType returns equality, range or empty of a field not supported by a compound index
+func getFieldType(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "/") { // regular expression
+			return "range"
+		}
+		return "equality"
+	case []shapeField:
+		if len(v) == 0 || !strings.HasPrefix(v[0].Key, "$") { // embedded document
+			return "equality"
+		}
+		fieldType := "equality"
+		for _, op := range v {
+			if unindexableOps[op.Key] {
+				return ""
+			} else if !equalityOps[op.Key] {
+				fieldType = "range"
+			}
+		}
+		return fieldType
+	}
+	return "equality" // array
+}
+
+// getIndexKey returns an index key as a query pattern, e.g. { a:1, b:-1 }
+func getIndexKey(fields []indexField) string {
+	keys := []string{}
+	for _, field := range fields {
+		keys = append(keys, fmt.Sprintf("%v:%d", field.Name, field.Value))
+	}
+	return "{ " + strings.Join(keys, ", ") + " }"
+}
+
+// getIndexKeyJSON returns an index key in JSON, e.g. { "a": 1, "b": -1 }
+func getIndexKeyJSON(fields []indexField) string {
+	keys := []string{}
+	for _, field := range fields {
+		keys = append(keys, fmt.Sprintf("%q: %d", field.Name, field.Value))
+	}
+	return "{ " + strings.Join(keys, ", ") + " }"
+}
+
+// getIndexName returns the default name of an index, e.g. a_1_b_-1
+func getIndexName(fields []indexField) string {
+	names := []string{}
+	for _, field := range fields {
+		names = append(names, fmt.Sprintf("%v_%d", field.Name, field.Value))
+	}
+	return strings.Join(names, "_")
+}
+
+// isIndexUsed returns true if fields are a prefix of the key of the index used, e.g. { a:1, b:1 }
+func isIndexUsed(fields []indexField, index string) bool {
+	shape, err := parseShape(index)
+	if err != nil {
+		return false
+	}
+	doc, _ := shape.([]shapeField)
+	used := []indexField{}
+	for _, field := range doc {
+		used = append(used, indexField{Name: field.Key, Value: ToInt(field.Value)})
+	}
+	return len(used) > 0 && isKeyPrefix(fields, used)
+}
+
+// isKeyPrefix returns true if fields are a prefix of fields of another index, of which
+// equality fields match leading fields of the other in any order
+func isKeyPrefix(fields []indexField, other []indexField) bool {
+	if len(fields) > len(other) {
+		return false
+	}
+	equalities := map[string]bool{}
+	for i, field := range fields {
+		if field.Equality {
+			equalities[field.Name] = true
+		} else if field.Name != other[i].Name || field.Value != other[i].Value {
+			return false
+		}
+	}
+	for _, field := range other[:len(equalities)] {
+		if !equalities[field.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseShape parses a query pattern, e.g. { a:1, b:{ $in:[...] }, "c.d":/^.../i }, into
+// []shapeField of documents, []interface{} of arrays and strings of other values
+func parseShape(shape string) (interface{}, error) {
+	parser := &shapeParser{str: shape}
+	value, err := parser.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if parser.skipSpaces(); parser.pos < len(parser.str) {
+		return nil, fmt.Errorf("unexpected %q at %d of %v", parser.str[parser.pos], parser.pos, shape)
+	}
+	return value, nil
+}
+
+type shapeParser struct {
+	pos int
+	str string
+}
+
+func (ptr *shapeParser) skipSpaces() {
+	for ptr.pos < len(ptr.str) && ptr.str[ptr.pos] == ' ' {
+		ptr.pos++
+	}
+}
+
+func (ptr *shapeParser) parseValue() (interface{}, error) {
+	ptr.skipSpaces()
+	if ptr.pos >= len(ptr.str) {
+		return nil, errors.New("unexpected end of " + ptr.str)
+	}
+	switch ptr.str[ptr.pos] {
+	case '{':
+		ptr.pos++
+		doc := []shapeField{}
+		for {
+			if ptr.skipSpaces(); ptr.pos < len(ptr.str) && ptr.str[ptr.pos] == '}' {
+				ptr.pos++
+				return doc, nil
+			}
+			key := ptr.parseToken(":")
+			if ptr.pos >= len(ptr.str) || key == "" {
+				return nil, errors.New("missing key of " + ptr.str)
+			}
+			ptr.pos++ // :
+			value, err := ptr.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			doc = append(doc, shapeField{Key: key, Value: value})
+			if err = ptr.parseSeparator('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '[':
+		ptr.pos++
+		list := []interface{}{}
+		for {
+			if ptr.skipSpaces(); ptr.pos < len(ptr.str) && ptr.str[ptr.pos] == ']' {
+				ptr.pos++
+				return list, nil
+			}
+			value, err := ptr.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+			if err = ptr.parseSeparator(']'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ptr.parseToken(",}]"), nil
+}
+
+// parseSeparator skips a comma, or leaves the closing bracket to be consumed
+func (ptr *shapeParser) parseSeparator(closing byte) error {
+	if ptr.skipSpaces(); ptr.pos >= len(ptr.str) {
+		return errors.New("unexpected end of " + ptr.str)
+	} else if ptr.str[ptr.pos] == ',' {
+		ptr.pos++
+		return nil
+	} else if ptr.str[ptr.pos] != closing {
+		return fmt.Errorf("unexpected %q at %d of %v", ptr.str[ptr.pos], ptr.pos, ptr.str)
+	}
+	return nil
+}
+
+// parseToken returns a quoted string, or characters before any of delimiters
+func (ptr *shapeParser) parseToken(delimiters string) string {
+	ptr.skipSpaces()
+	if ptr.pos < len(ptr.str) && ptr.str[ptr.pos] == '"' {
+		end := strings.IndexByte(ptr.str[ptr.pos+1:], '"')
+		if end >= 0 {
+			token := ptr.str[ptr.pos+1 : ptr.pos+1+end]
+			ptr.pos += end + 2
+			ptr.skipSpaces()
+			return token
+		}
+	}
+	start := ptr.pos
+	for ptr.pos < len(ptr.str) && !strings.ContainsRune(delimiters, rune(ptr.str[ptr.pos])) {
+		ptr.pos++
+	}
+	return strings.TrimSpace(ptr.str[start:ptr.pos])
+}
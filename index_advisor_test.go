@@ -0,0 +1,108 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * index_advisor_test.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetIndexFields(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		sort     string
+		expected string
+	}{
+		{`{ age:{ $gt:1 }, status:1 }`, `{ name:1, age:-1 }`, `{ status:1, name:1, age:-1 }`},
+		{`{ date:{ $gte:1, $lt:1 }, cust_id:{ $in:[...] }, status:1 }`, ``, `{ cust_id:1, status:1, date:1 }`},
+		{`{ $and:[{ "items.sku":1 }, { qty:{ $lte:1 } }], name:/^.../i }`, `{ qty:-1 }`, `{ items.sku:1, qty:-1, name:1 }`},
+		{`{ $or:[{ a:1 }, { b:1 }], c:{ $ne:1 } }`, ``, `{ c:1 }`},
+		{`{ loc:{ $near:1 }, type:1 }`, ``, `{ type:1 }`},
+	}
+	for _, test := range tests {
+		fields, err := getIndexFields(test.pattern, test.sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key := getIndexKey(fields); key != test.expected {
+			t.Fatalf("expected %v of %v, got %v", test.expected, test.pattern, key)
+		}
+	}
+	if _, err := getIndexFields(`{ a:{ $in:[...] }`, ""); err == nil {
+		t.Fatal("expected an error of an unclosed query pattern")
+	}
+}
+
+func TestGetIndexAdvice(t *testing.T) {
+	ops := []OpStat{
+		{Op: "find", Namespace: "test.users", Index: "COLLSCAN", QueryPattern: `{ status:1 }`, Count: 10, TotalMilli: 1000},
+		{Op: "find", Namespace: "test.users", Index: "COLLSCAN", QueryPattern: `{ age:{ $gt:1 }, status:1 }`, SortShape: `{ name:1 }`, Count: 5, TotalMilli: 2000},
+		{Op: "update", Namespace: "test.users", Index: "{ status:1 }", QueryPattern: `{ status:1, name:1 }`, PoorlyTargeted: true, Count: 2, TotalMilli: 500},
+		{Op: "find", Namespace: "test.orders", Index: "{ cust_id:1 }", QueryPattern: `{ cust_id:1 }`, PoorlyTargeted: true, Count: 100, TotalMilli: 9000},
+		{Op: "find", Namespace: "test.orders", Index: "{ date:1 }", QueryPattern: `{ date:{ $gte:1 } }`, Count: 100, TotalMilli: 9000},
+		{Op: "find", Namespace: "test.orders", Index: "COLLSCAN", QueryPattern: `{ status:1 }`, SortShape: `{ date:-1 }`, Count: 50, TotalMilli: 5000},
+		{Op: "insert", Namespace: "test.logs", Index: "COLLSCAN", QueryPattern: ``, Count: 1, TotalMilli: 100},
+	}
+	advices := GetIndexAdvice(ops)
+	if len(advices) != 2 {
+		t.Fatalf("expected 2 indexes, got %+v", advices)
+	}
+	advice := advices[0]
+	if advice.Namespace != "test.orders" || advice.Key != "{ status:1, date:-1 }" || advice.Count != 50 || advice.TotalMilli != 5000 {
+		t.Fatalf("unexpected index %+v", advice)
+	}
+	// { status:1 } and { name:1, status:1 } are prefixes of { status:1, name:1, age:1 }
+	if advice = advices[1]; advice.Namespace != "test.users" || advice.Key != "{ status:1, name:1, age:1 }" || advice.Name != "status_1_name_1_age_1" ||
+		advice.Count != 17 || advice.TotalMilli != 3500 || len(advice.Patterns) != 3 || len(advice.Indexes) != 2 {
+		t.Fatalf("unexpected index %+v", advice)
+	}
+}
+
+func TestGetCreateIndexesScript(t *testing.T) {
+	ops := []OpStat{
+		{Op: "find", Namespace: "test.users", Index: "COLLSCAN", QueryPattern: `{ age:{ $gt:1 }, status:1 }`, SortShape: `{ name:1 }`, Count: 5, TotalMilli: 2000},
+		{Op: "find", Namespace: "test.users", Index: "COLLSCAN", QueryPattern: `{ email:1 }`, Count: 1, TotalMilli: 100},
+		{Op: "find", Namespace: "sales.orders", Index: "COLLSCAN", QueryPattern: `{ "customer.id":1 }`, Count: 1, TotalMilli: 1000},
+	}
+	script := GetCreateIndexesScript(GetIndexAdvice(ops))
+	expected := `db.getSiblingDB("test").runCommand({
+	createIndexes: "users",
+	indexes: [
+		{ key: { "status": 1, "name": 1, "age": 1 }, name: "status_1_name_1_age_1" },
+		{ key: { "email": 1 }, name: "email_1" }
+	]
+});`
+	if !strings.Contains(script, expected) || !strings.Contains(script, `{ key: { "customer.id": 1 }, name: "customer.id_1" }`) {
+		t.Fatalf("unexpected script %v", script)
+	}
+	if strings.Index(script, `createIndexes: "users"`) > strings.Index(script, `createIndexes: "orders"`) {
+		t.Fatalf("expected collections ranked by total milliseconds, got %v", script)
+	}
+}
+
+func TestGetIndexAdviceOfSlowOps(t *testing.T) {
+	str := `{"t":{"$date":"2024-03-18T10:00:0%d.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"A","age":{"$gt":21}},"sort":{"name":1,"age":-1},"$db":"test"},"planSummary":"COLLSCAN","docsExamined":10000,"nreturned":20,"durationMillis":%d}}`
+	var logs []string
+	for i := 0; i < 3; i++ {
+		logs = append(logs, fmt.Sprintf(str, i, 100*(i+1)))
+	}
+	dbase := analyzeTestLogs(t, "indexes", logs)
+	ops, err := dbase.GetSlowOps("total_ms", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	advices := GetIndexAdvice(ops)
+	if len(advices) != 1 || advices[0].Key != "{ status:1, name:1, age:-1 }" || advices[0].Count != 3 || advices[0].TotalMilli != 600 {
+		t.Fatalf("unexpected indexes %+v", advices)
+	}
+	templ, err := GetIndexesTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "indexes", "Advices": advices, "Summary": "", "Version": ""}
+	executeTestTemplate(t, templ, doc)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * indexes_template.go
+ */
+
+package hatchet
+
+import (
+	"html/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// GetIndexesTemplate returns HTML of index advice
+func GetIndexesTemplate(download string) (*template.Template, error) {
+	html := headers
+	if download == "" {
+		html = getContentHTML()
+	}
+	html += `
+<script>
+	function downloadIndexes() {
+		anchor = document.createElement('a');
+		anchor.download = '{{.Hatchet}}_indexes.html';
+		anchor.href = '/hatchets/{{.Hatchet}}/stats/indexes?download=true';
+		anchor.dataset.downloadurl = ['text/html', anchor.download, anchor.href].join(':');
+		anchor.click();
+	}
+	function downloadScript() {
+		anchor = document.createElement('a');
+		anchor.download = '{{.Hatchet}}_indexes.js';
+		anchor.href = '/hatchets/{{.Hatchet}}/stats/indexes?script=true';
+		anchor.dataset.downloadurl = ['text/javascript', anchor.download, anchor.href].join(':');
+		anchor.click();
+	}
+</script>
+<div align='left'>`
+	if download == "" {
+		html += `
+<!-- Header Bar -->
+<div style='display: flex; justify-content: space-between; align-items: center; margin-bottom: 15px;'>
+	<h2 style='margin: 0; color: #444; font-size: 1.4em;'><i class='fa fa-key' style='color: #1565c0;'></i> Index Advice</h2>
+	<span>
+	<button id="script" onClick="downloadScript(); return false;"
+		class="download-btn"><i class="fa fa-code"></i> createIndexes</button>
+	<button id="download" onClick="downloadIndexes(); return false;"
+		class="download-btn"><i class="fa fa-download"></i> Download</button>
+	</span>
+</div>`
+	} else {
+		html += "<div align='center'>{{.Summary}}</div>"
+	}
+	html += `
+<p>Compound indexes of COLLSCAN and poorly targeted query patterns, with fields ordered by the Equality-Sort-Range rule.
+Indexes of which keys are prefixes of others are merged, and are ranked by total milliseconds of slow ops they support.
+Review them with existing indexes before creating.</p>
+{{ if .Advices }}
+	<table width='100%'>
+		<tr><th>#</th><th>namespace</th><th>index</th><th>count</th><th>total ms</th><th>plans</th><th>query patterns</th></tr>
+	{{ range $n, $value := .Advices }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td class='break'>{{ $value.Namespace }}</td>
+			<td class='break' title='{{ $value.Name }}'>{{ $value.Key }}</td>
+			<td align='right'>{{ numPrinter $value.Count }}</td>
+			<td align='right'>{{ numPrinter $value.TotalMilli }}</td>
+			<td>{{ range $i, $index := $value.Indexes }}{{ if $i }}<br/>{{ end }}{{ if eq $index "COLLSCAN" }}<span style='color:red;'>{{ $index }}</span>{{ else }}{{ $index }}{{ end }}{{ end }}</td>
+			<td class='break'>{{ range $i, $pattern := $value.Patterns }}{{ if $i }}<br/>{{ end }}{{ $pattern }}{{ end }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No index is suggested.</p>
+{{ end }}
+	<div align='center'><hr/><p/>{{.Version}}</div>
+</div>`
+	if download == "" {
+		html += "</div><!-- end content-container -->"
+	}
+	html += "</body></html>"
+	return template.New("hatchet").Funcs(template.FuncMap{
+		"add": func(a int, b int) int {
+			return a + b
+		},
+		"numPrinter": func(n interface{}) string {
+			printer := message.NewPrinter(language.English)
+			return printer.Sprintf("%v", ToInt(n))
+		}}).Parse(html)
+}
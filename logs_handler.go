@@ -15,6 +15,7 @@ import (
 func LogsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	/** APIs
 	 * /hatchets/{hatchet}/logs/all
+	 * /hatchets/{hatchet}/logs/events
 	 * /hatchets/{hatchet}/logs/slowops
 	 */
 	hatchetName := params.ByName("hatchet")
@@ -31,6 +32,7 @@ func LogsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	info := dbase.GetHatchetInfo()
 	summary := GetHatchetSummary(info)
 	duration := r.URL.Query().Get("duration")
+	marker := r.URL.Query().Get("marker") // of a member of merged logs
 
 	if attr == "all" {
 		var hasMore bool
@@ -48,6 +50,7 @@ func LogsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Param
 			fmt.Sprintf("context=%v", context),
 			fmt.Sprintf("severity=%v", severity),
 			fmt.Sprintf("duration=%v", duration),
+			fmt.Sprintf("marker=%v", marker),
 		}
 		logs, err := dbase.GetLogs(searchOpts...)
 		if err != nil {
@@ -71,8 +74,8 @@ func LogsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Param
 			logs = logs[:len(logs)-1]
 		}
 		limit = fmt.Sprintf("%v,%v", offset+nlimit, nlimit)
-		url := fmt.Sprintf("%v?component=%v&context=%v&severity=%v&duration=%v&limit=%v", r.URL.Path,
-			component, context, severity, duration, limit)
+		url := fmt.Sprintf("%v?component=%v&context=%v&severity=%v&duration=%v&marker=%v&limit=%v", r.URL.Path,
+			component, context, severity, duration, marker, limit)
 		doc := map[string]interface{}{"Hatchet": hatchetName, "Merge": info.Merge, "Logs": logs, "Seq": seq,
 			"Summary": summary, "Context": context, "Component": component, "Severity": severity,
 			"HasMore": hasMore, "URL": url, "TotalCount": totalCount, "Version": GetLogv2().version}
@@ -103,5 +106,34 @@ func LogsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Param
 			return
 		}
 		return
+	} else if attr == "events" {
+		eventType := r.URL.Query().Get("type")
+		download := r.URL.Query().Get("download")
+		events, err := dbase.GetReplEvents(eventType, duration, marker)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		templ, err := GetEventsTemplate(download)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		var lifetimes []ProcessLifetime
+		if duration == "" && marker == "" { // of the whole hatchet
+			if lifetimes, err = dbase.GetProcessLifetimes(); err != nil {
+				renderErrorPage(w, r, hatchetName, err.Error())
+				return
+			}
+		}
+		counts, markers := GetReplEventSummary(events)
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Merge": info.Merge, "Events": events, "Counts": counts,
+			"Markers": markers, "Type": eventType, "Types": EVENT_TYPES, "Duration": duration, "Marker": marker, "Summary": summary,
+			"Lifetimes": lifetimes, "Download": download, "Version": GetLogv2().version}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
 	}
 }
@@ -13,10 +13,11 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/simagix/gox"
@@ -28,8 +29,27 @@ const (
 	DOLLAR_CMD = "$cmd"
 	LIMIT      = 100
 	TOP_N      = 23
+
+	QUERY_TARGETING_RATIO = 1000  // examined per returned, alerted by Atlas by default
+	DISTINCT_SEPARATOR    = " | " // separator of distinct values of grouped slow ops
+
+	KEYS_PER_DOC_RATIO = 10   // index keys written per document, of write amplification by indexes
+	MULTI_WRITE_DOCS   = 1000 // documents written per multi update or delete op
+
+	EXACT_LATENCY_MS = 1000 // latencies of histograms are exact below, and of 3 significant digits above
+	MAX_LATENCY_MS   = 1e9  // latencies of histograms are exact again above
 )
 
+// PERCENTILE_COLUMNS are latency percentiles of slow ops, merged from histograms of the grouping shown
+var PERCENTILE_COLUMNS = []string{"p50_ms", "p95_ms", "p99_ms"}
+
+// SLOWOP_GROUPS maps MongoDB hashes of a query to columns of grouping slow ops other than by Hatchet query patterns
+var SLOWOP_GROUPS = map[string]string{"queryHash": "query_hash", "planCacheKey": "plan_cache_key", "queryShapeHash": "query_shape_hash"}
+
+// TIME_BREAKDOWN are categories of time spent by slow ops, from cpuNanos, storage.data.timeReadingMicros,
+// locks.*.timeAcquiringMicros, flowControl.timeAcquiringMicros, remoteOpWaitMillis and the remaining duration
+var TIME_BREAKDOWN = []string{"CPU", "disk read", "lock wait", "flow control", "remote wait", "other"}
+
 var instance *Logv2
 
 // GetLogv2 returns Logv2 instance
@@ -42,22 +62,31 @@ func GetLogv2() *Logv2 {
 
 // Logv2 keeps Logv2 object
 type Logv2 struct {
-	buildInfo   map[string]interface{}
-	cacheSize   int
-	from        time.Time
-	logname     string
-	legacy      bool
-	hatchetName string
-	isDigest    bool
-	merge       bool
-	s3client    *S3Client
-	testing     bool //test mode
-	to          time.Time
-	totalLines  int
-	url         string // connection string
-	user        string
-	verbose     bool
-	version     string
+	buildInfo    map[string]interface{}
+	cacheSize    int
+	exclude      string // glob patterns of files to skip in directories and archives
+	follow       bool
+	from         time.Time
+	include      string // glob patterns of files to analyze in directories and archives
+	logname      string
+	legacy       bool
+	hatchetName  string
+	isDigest     bool
+	merge        bool
+	merged       int           // number of files merged
+	mergeRotated bool          // merge rotated logs of a node
+	rebuild      bool          // rebuild hatchets of checkpoints
+	refresh      time.Duration // metadata refresh interval in follow mode
+	resume       bool          // append new logs from checkpoints
+	s3client     *S3Client
+	testing      bool          //test mode
+	timeout      time.Duration // HTTP connect, response header and idle read timeout
+	to           time.Time
+	totalLines   int
+	url          string // connection string
+	user         string
+	verbose      bool
+	version      string
 }
 
 // Logv2Info stores logv2 struct
@@ -73,19 +102,57 @@ type Logv2Info struct {
 	Attributes Attributes
 	Message    string // remaining legacy message
 	Client     *RemoteClient
+	Error      *LogError
+	Event      *ReplEvent
 	Marker     int
+	Shard      *ShardEvent
+	Startup    *StartupEvent
+	Storage    *StorageEvent
+	Txn        *Transaction
+
+	attrMap bson.M // Attr converted by getAttrMap, reset by ParseLogLine
+}
+
+// getAttrMap returns attributes of a log line as a map, converted once per line
+func (ptr *Logv2Info) getAttrMap() bson.M {
+	if ptr.attrMap == nil {
+		ptr.attrMap = BsonD2M(ptr.Attr)
+	}
+	return ptr.attrMap
 }
 
 type Attributes struct {
 	AppName            string                 `json:"appName" bson:"appName"`
+	BytesRead          int                    `json:"bytesRead" bson:"bytesRead"` // storage.data.bytesRead
 	Command            map[string]interface{} `json:"command" bson:"command"`
+	CPUNanos           int                    `json:"cpuNanos" bson:"cpuNanos"`
+	DocsExamined       int                    `json:"docsExamined" bson:"docsExamined"`
 	ErrMsg             string                 `json:"errMsg" bson:"errMsg"`
+	FlowControlMicros  int                    `json:"flowControlMicros" bson:"flowControlMicros"` // flowControl.timeAcquiringMicros
+	HasSortStage       bool                   `json:"hasSortStage" bson:"hasSortStage"`
+	KeysDeleted        int                    `json:"keysDeleted" bson:"keysDeleted"`
+	KeysExamined       int                    `json:"keysExamined" bson:"keysExamined"`
+	KeysInserted       int                    `json:"keysInserted" bson:"keysInserted"`
+	LockWaitMicros     int                    `json:"lockWaitMicros" bson:"lockWaitMicros"` // sum of locks.*.timeAcquiringMicros
 	Milli              int                    `json:"durationMillis" bson:"durationMillis"`
+	NDeleted           int                    `json:"ndeleted" bson:"ndeleted"`
+	NInserted          int                    `json:"ninserted" bson:"ninserted"`
+	NMatched           int                    `json:"nMatched" bson:"nMatched"`
+	NModified          int                    `json:"nModified" bson:"nModified"`
+	NReturned          int                    `json:"nreturned" bson:"nreturned"`
+	NUpserted          int                    `json:"nUpserted" bson:"nUpserted"`
 	NS                 string                 `json:"ns" bson:"ns"`
+	NumYields          int                    `json:"numYields" bson:"numYields"`
 	OriginatingCommand map[string]interface{} `json:"originatingCommand" bson:"originatingCommand"`
+	PlanCacheKey       string                 `json:"planCacheKey" bson:"planCacheKey"`
 	PlanSummary        string                 `json:"planSummary" bson:"planSummary"`
+	QueryHash          string                 `json:"queryHash" bson:"queryHash"`
+	QueryShapeHash     string                 `json:"queryShapeHash" bson:"queryShapeHash"`
+	RemoteOpWaitMillis int                    `json:"remoteOpWaitMillis" bson:"remoteOpWaitMillis"`
 	Reslen             int                    `json:"reslen" bson:"reslen"`
+	TimeReadingMicros  int                    `json:"timeReadingMicros" bson:"timeReadingMicros"` // storage.data.timeReadingMicros
 	Type               string                 `json:"type" bson:"type"`
+	UsedDisk           bool                   `json:"usedDisk" bson:"usedDisk"`
 }
 
 type RemoteClient struct {
@@ -99,18 +166,75 @@ type RemoteClient struct {
 	Version string `bsno:"version"` // driver version
 }
 
+// LatencyBucket counts slow ops of a latency, the lower bound of the bucket in milliseconds
+type LatencyBucket struct {
+	Count int `json:"n" bson:"n"`
+	Milli int `json:"ms" bson:"ms"`
+}
+
 // OpStat stores performance data
 type OpStat struct {
 	AvgMilli     float64 `json:"avg_ms" bson:"avg_ms"`               // avg millisecond
 	Count        int     `json:"count" bson:"count"`                 // number of ops
+	DocsExamined int     `json:"docs_examined" bson:"docs_examined"` // total docsExamined
 	Index        string  `json:"index" bson:"index"`                 // index used
+	KeysExamined int     `json:"keys_examined" bson:"keys_examined"` // total keysExamined
 	MaxMilli     int     `json:"max_ms" bson:"max_ms"`               // max millisecond
 	Namespace    string  `json:"ns" bson:"ns"`                       // database.collectin
+	NMatched     int     `json:"nmatched" bson:"nmatched"`           // total nMatched
+	NModified    int     `json:"nmodified" bson:"nmodified"`         // total nModified
+	NReturned    int     `json:"nreturned" bson:"nreturned"`         // total nreturned
+	NumYields    int     `json:"num_yields" bson:"num_yields"`       // total numYields
 	Op           string  `json:"op" bson:"op"`                       // count, delete, find, remove, and update
+	P50Milli     int     `json:"p50_ms" bson:"p50_ms"`               // median millisecond
+	P95Milli     int     `json:"p95_ms" bson:"p95_ms"`               // 95th percentile millisecond
+	P99Milli     int     `json:"p99_ms" bson:"p99_ms"`               // 99th percentile millisecond
 	QueryPattern string  `json:"query_pattern" bson:"query_pattern"` // query pattern
 	Reslen       int     `json:"total_reslen" bson:"total_reslen"`   // total reslen
 	TotalMilli   int     `json:"total_ms" bson:"total_ms"`           // total milliseconds
 
+	Histogram []LatencyBucket `json:"-" bson:"histogram"` // ops by latency, merged into percentiles
+
+	DocsRatio      float64 `json:"docs_examined_returned"` // docsExamined per document returned or matched
+	KeysRatio      float64 `json:"keys_examined_returned"` // keysExamined per document returned or matched
+	PoorlyTargeted bool    `json:"poorly_targeted"`        // examined more than QUERY_TARGETING_RATIO per returned
+	PlanFlip       bool    `json:"plan_flip"`              // a pattern of several plan cache keys or a hash of several plans
+
+	PlanCacheKey   string `json:"plan_cache_key" bson:"plan_cache_key"`     // planCacheKey, distinct ones separated by DISTINCT_SEPARATOR
+	QueryHash      string `json:"query_hash" bson:"query_hash"`             // queryHash
+	QueryShapeHash string `json:"query_shape_hash" bson:"query_shape_hash"` // queryShapeHash of 8.0+
+
+	HasSortStage  int             `json:"has_sort_stage" bson:"has_sort_stage"` // number of ops of in-memory sorts
+	Pipeline      []PipelineStage `json:"pipeline,omitempty" bson:"-"`          // stages of aggregation pipelines
+	PipelineShape string          `json:"-" bson:"pipeline"`                    // fingerprint of pipeline stages in JSON
+	UsedDisk      int             `json:"used_disk" bson:"used_disk"`           // number of ops used disk
+
+	Hint       string `json:"hint" bson:"hint"`             // index key or name of hint
+	LimitSkip  string `json:"limit_skip" bson:"limit_skip"` // limit and skip if given, e.g. { limit:1 }
+	Projection string `json:"projection" bson:"projection"` // keys of projection
+	SortShape  string `json:"sort" bson:"sort_shape"`       // keys of sort, e.g. { a:1, b:-1 }
+
+	BytesRead          int `json:"bytes_read" bson:"bytes_read"`                   // total storage.data.bytesRead
+	CPUNanos           int `json:"cpu_nanos" bson:"cpu_nanos"`                     // total cpuNanos
+	FlowControlMicros  int `json:"flow_control_micros" bson:"flow_control_micros"` // total flowControl.timeAcquiringMicros
+	LockWaitMicros     int `json:"lock_wait_micros" bson:"lock_wait_micros"`       // total locks.*.timeAcquiringMicros
+	RemoteOpWaitMillis int `json:"remote_wait_ms" bson:"remote_wait_ms"`           // total remoteOpWaitMillis
+	TimeReadingMicros  int `json:"time_reading_micros" bson:"time_reading_micros"` // total storage.data.timeReadingMicros
+
+	UpdateShape string `json:"update" bson:"update_shape"`     // operators of updates, e.g. { $inc:1, $set:1 }, replacement or pipeline
+	WriteFlags  string `json:"write_flags" bson:"write_flags"` // multi and upsert of writes, e.g. { multi:1, upsert:1 }
+
+	KeysDeleted  int `json:"keys_deleted" bson:"keys_deleted"`   // total keysDeleted
+	KeysInserted int `json:"keys_inserted" bson:"keys_inserted"` // total keysInserted
+	NDeleted     int `json:"ndeleted" bson:"ndeleted"`           // total ndeleted
+	NInserted    int `json:"ninserted" bson:"ninserted"`         // total ninserted, or documents of insert batches
+	NUpserted    int `json:"nupserted" bson:"nupserted"`         // total nUpserted
+
+	DocsPerWrite   float64 `json:"docs_per_write"`  // documents inserted, modified, deleted or upserted per write op
+	KeysPerDoc     float64 `json:"keys_per_doc"`    // index keys inserted and deleted per document written
+	UnboundedMulti bool    `json:"unbounded_multi"` // multi writes of more than MULTI_WRITE_DOCS documents per op
+	WriteAmplified bool    `json:"write_amplified"` // more than KEYS_PER_DOC_RATIO index keys written per document
+
 	Marker int
 }
 
@@ -157,8 +281,11 @@ func isMongoDBLog(filename string) bool {
 	if err != nil {
 		return false
 	}
+	return isMongoDBLogReader(reader)
+}
 
-	// Read first non-empty line
+// isMongoDBLogReader checks if the first non-empty line of a reader is a MongoDB log
+func isMongoDBLogReader(reader *bufio.Reader) bool {
 	for {
 		line, _, err := reader.ReadLine()
 		if err != nil {
@@ -167,9 +294,13 @@ func isMongoDBLog(filename string) bool {
 		if len(line) == 0 {
 			continue
 		}
-		// Try to parse as MongoDB logv2 JSON
+		event := AuditEvent{}
+		if isAuditLog(string(line)) && ParseAuditLog(string(line), &event) == nil {
+			return true
+		}
+		// Try to parse as MongoDB logv2 JSON or legacy text log
 		doc := Logv2Info{}
-		if err := bson.UnmarshalExtJSON(line, false, &doc); err != nil {
+		if err := ParseLogLine(string(line), &doc); err != nil {
 			return false
 		}
 		// Check for required logv2 fields: timestamp and severity
@@ -177,75 +308,59 @@ func isMongoDBLog(filename string) bool {
 	}
 }
 
-// Analyze analyzes logs from a file or directory (1 level only)
+// Analyze analyzes logs from a file, an archive, or a directory recursively
 func (ptr *Logv2) Analyze(logname string, marker int) error {
-	// Check if input is a directory
-	fileInfo, err := os.Stat(logname)
-	if err != nil {
-		return err
-	}
-	if fileInfo.IsDir() {
-		// Process directory (1 level only, no recursion)
-		entries, err := os.ReadDir(logname)
-		if err != nil {
+	var err error
+	isHTTP := strings.HasPrefix(logname, "http://") || strings.HasPrefix(logname, "https://")
+	if ptr.s3client != nil && IsS3Prefix(logname) {
+		return ptr.analyzeS3Prefix(logname, marker)
+	} else if ptr.s3client == nil && !isHTTP {
+		// Check if input is a directory
+		var fileInfo os.FileInfo
+		if fileInfo, err = os.Stat(logname); err != nil {
 			return err
 		}
-		// Get existing names once, then track new names locally to avoid DB query timing issues
-		existingNames, _ := GetExistingHatchetNames()
-		processedNames := make([]string, 0)
-		fileCount := 0
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue // skip subdirectories
-			}
-			name := entry.Name()
-			if strings.HasPrefix(name, ".") {
-				continue // skip hidden files
-			}
-			fullPath := filepath.Join(logname, name)
-			// Check if file is a MongoDB log by reading first line
-			if !isMongoDBLog(fullPath) {
-				log.Printf("skipping %s (not a MongoDB log)", name)
-				continue
-			}
-			fileCount++
-			// Generate unique name using combined existing + processed names
-			allNames := append(existingNames, processedNames...)
-			hatchetName := getUniqueHatchetName(fullPath, allNames)
-			processedNames = append(processedNames, hatchetName)
-			// Create a new Logv2 instance for each file to avoid state issues
-			fileLogv2 := &Logv2{
-				hatchetName: hatchetName,
-				url:         ptr.url,
-				legacy:      ptr.legacy,
-				from:        ptr.from,
-				to:          ptr.to,
-			}
-			if err := fileLogv2.Analyze(fullPath, 0); err != nil { // marker=0 to skip name regeneration
-				log.Printf("error processing %s: %v", fullPath, err)
-				// continue with other files
-			}
-			// Print summary for each file
-			if !fileLogv2.legacy {
-				fileLogv2.PrintSummary()
-			}
+		if fileInfo.IsDir() && ptr.follow {
+			return fmt.Errorf("cannot follow directory %v", logname)
+		} else if fileInfo.IsDir() {
+			return ptr.analyzeDirectory(logname)
 		}
-		if fileCount == 0 {
-			log.Printf("no MongoDB log files found in directory %s", logname)
+	}
+
+	if archiveType := getArchiveType(logname); archiveType != "" && ptr.s3client == nil && !isHTTP {
+		if ptr.follow {
+			return fmt.Errorf("cannot follow archive %v", logname)
 		}
-		// Clear hatchetName so caller knows not to print summary again
-		ptr.hatchetName = ""
-		return nil
+		return ptr.analyzeArchive(logname, archiveType, marker)
 	}
 
 	var buf []byte
 	var file *os.File
 	var reader *bufio.Reader
+	var tail *TailReader
+	var counter *countingReader
+	var identity string
+	var checkpoint *Checkpoint
 	ptr.logname = logname
+	if ptr.merge {
+		ptr.merged++
+		marker = ptr.merged
+	}
+	if ptr.resume && ptr.s3client == nil {
+		if identity = GetFileIdentity(logname); identity != "" {
+			checkpoint = GetCheckpoint(identity)
+		}
+	}
 	// Generate unique hatchet name for each file when not merging
 	// marker=0: name pre-set by directory handler or upload handler, skip regeneration
 	// marker>=1: command line files, generate name for each
-	if !ptr.merge && marker > 0 {
+	if checkpoint != nil {
+		ptr.hatchetName = checkpoint.Name
+		marker = checkpoint.Marker
+		if ptr.rebuild {
+			checkpoint = nil // rebuild under the same name
+		}
+	} else if !ptr.merge && marker > 0 {
 		existingNames, _ := GetExistingHatchetNames()
 		ptr.hatchetName = getUniqueHatchetName(ptr.logname, existingNames)
 	}
@@ -255,14 +370,15 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 	}
 
 	if ptr.s3client != nil {
-		var buf []byte
-		if buf, err = ptr.s3client.GetObject(logname); err != nil {
+		var body io.ReadCloser
+		if body, err = ptr.s3client.GetObjectReader(logname); err != nil {
 			return err
 		}
-		if reader, err = GetBufioReader(buf); err != nil {
+		defer body.Close()
+		if reader, err = NewStreamReader(body); err != nil {
 			return err
 		}
-	} else if strings.HasPrefix(logname, "http://") || strings.HasPrefix(logname, "https://") {
+	} else if isHTTP {
 		var username, password string
 		if ptr.user != "" {
 			toks := strings.Split(ptr.user, ":")
@@ -271,15 +387,33 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 				password = toks[1]
 			}
 		}
-		if ptr.isDigest {
-			if reader, err = GetHTTPDigestContent(logname, username, password); err != nil {
-				return err
-			}
-		} else {
-			if reader, err = GetHTTPContent(logname, username, password); err != nil {
-				return err
-			}
+		var httpReader *HTTPReader
+		if httpReader, err = NewHTTPReader(logname, username, password, ptr.isDigest, ptr.timeout); err != nil {
+			return err
+		}
+		defer httpReader.Close()
+		httpReader.SetProgress(!ptr.testing && !ptr.legacy)
+		if reader, err = NewStreamReader(httpReader); err != nil {
+			return err
 		}
+	} else if ptr.follow {
+		if tail, err = NewTailReader(logname, FOLLOW_POLL_INTERVAL); err != nil {
+			return err
+		}
+		defer tail.Close()
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+		go func() {
+			select {
+			case <-sigs:
+				log.Println("stop following", logname)
+				tail.Stop()
+			case <-tail.stop:
+			}
+		}()
+		log.Println("following", logname, "press Ctrl-C to stop")
+		reader = bufio.NewReader(tail)
 	} else {
 		if file, err = os.Open(logname); err != nil {
 			return err
@@ -316,11 +450,25 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 				}
 			}
 		}
+		if identity != "" {
+			counter = &countingReader{reader: reader}
+			reader = bufio.NewReader(counter)
+		}
+		if checkpoint != nil {
+			if _, err = io.CopyN(io.Discard, reader, checkpoint.Offset); err != nil {
+				return fmt.Errorf("%v is shorter than its checkpoint, rerun with -rebuild: %v", logname, err)
+			}
+			if next, _ := reader.Peek(1); len(next) == 1 && next[0] == '\n' {
+				reader.Discard(1) // newline of a last line ingested without one
+			}
+			log.Printf("resuming %v from line %v (%v)", ptr.hatchetName, checkpoint.Line, checkpoint.Date)
+		}
 	}
 
 	var isPrefix bool
 	index := 0
 	var start, end string
+	var prior HatchetInfo // of hatchet appended to
 	var dbase Database
 	var mu sync.Mutex   // protects buildInfo, start, and end
 	var dbMu sync.Mutex // protects database operations (SQLite prepared statements aren't thread-safe)
@@ -330,7 +478,17 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 			return err
 		}
 		defer dbase.Close()
-		if err = dbase.Begin(); err != nil {
+		if checkpoint != nil {
+			prior = dbase.GetHatchetInfo()
+			index = checkpoint.Line
+			err = dbase.Append()
+		} else if ptr.merge && ptr.merged > 1 {
+			prior = dbase.GetHatchetInfo()
+			err = dbase.Append()
+		} else {
+			err = dbase.Begin()
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -342,6 +500,29 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 	log.Printf("using %v threads\n", threads)
 	failedMap := FailedMessages{counters: map[string]int{}}
 	var wg = gox.NewWaitGroup(threads)
+	if tail != nil {
+		// refresh stats periodically whenever caught up with the log writer
+		lastRefresh := time.Now()
+		tail.onIdle = func() {
+			if time.Since(lastRefresh) < ptr.refresh {
+				return
+			}
+			lastRefresh = time.Now()
+			wg.Wait()
+			if err := ptr.saveLogData(dbase, &failedMap, start, end); err != nil {
+				log.Println("error saving logs", err)
+				tail.Stop()
+				return
+			}
+			if err := dbase.CreateMetaData(); err != nil {
+				log.Println("error refresh metadata", err)
+			}
+			if err := dbase.Append(); err != nil {
+				log.Println("error append logs", err)
+				tail.Stop()
+			}
+		}
+	}
 	var lineBuf bytes.Buffer // Reusable buffer for multi-line entries
 	for {
 		if !ptr.testing && !ptr.legacy && index%50 == 0 && ptr.totalLines > 0 {
@@ -385,9 +566,28 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 		go func(index int, instr string, marker int) {
 			defer wg.Done()
 			var localErr error // Use local error variable to avoid race condition
+			event := AuditEvent{Marker: marker}
+			if isAuditLog(instr) && ParseAuditLog(instr, &event) == nil {
+				if ptr.legacy || (!ptr.to.IsZero() && (event.Timestamp.Before(ptr.from) || event.Timestamp.After(ptr.to))) {
+					return
+				}
+				docEnd := getDateTimeStr(event.Timestamp)
+				mu.Lock()
+				if start == "" || docEnd < start {
+					start = docEnd
+				}
+				if docEnd > end {
+					end = docEnd
+				}
+				mu.Unlock()
+				if localErr = insertAuditLog(dbase, &dbMu, index, docEnd, &event); localErr != nil {
+					log.Println("error inserting audit log line", index, localErr)
+				}
+				return
+			}
 			doc := Logv2Info{}
-			if localErr = bson.UnmarshalExtJSON([]byte(instr), false, &doc); localErr != nil {
-				log.Println("error UnmarshalExtJSON line", index, localErr)
+			if localErr = ParseLogLine(instr, &doc); localErr != nil {
+				log.Println("error parsing line", index, localErr)
 				return
 			}
 			doc.Marker = marker
@@ -398,12 +598,11 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 			// Protect buildInfo access with mutex
 			mu.Lock()
 			if ptr.buildInfo == nil && doc.Msg == "Build Info" {
-				attrMap := BsonD2M(doc.Attr)
-				ptr.buildInfo = attrMap["buildInfo"].(bson.M)
+				ptr.buildInfo = doc.getAttrMap()["buildInfo"].(bson.M)
 			}
 			buildInfoCopy := ptr.buildInfo
 			mu.Unlock()
-			if buildInfoCopy != nil && (doc.Timestamp.Before(ptr.from) || doc.Timestamp.After(ptr.to)) {
+			if (buildInfoCopy != nil || checkpoint != nil) && (doc.Timestamp.Before(ptr.from) || doc.Timestamp.After(ptr.to)) {
 				return
 			}
 			if ptr.legacy {
@@ -416,6 +615,14 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 				return
 			}
 			stat, _ := AnalyzeSlowOp(&doc)
+			doc.Error = GetLogError(&doc, stat)
+			doc.Txn = GetTransaction(&doc, stat)
+			if doc.Event = GetProcessEvent(&doc); doc.Event == nil {
+				doc.Event = GetReplEvent(&doc)
+			}
+			doc.Shard = GetShardEvent(&doc)
+			doc.Startup = GetStartupEvent(&doc)
+			doc.Storage = GetStorageEvent(&doc)
 			docEnd := getDateTimeStr(doc.Timestamp)
 			// Protect start and end access with mutex
 			mu.Lock()
@@ -451,16 +658,62 @@ func (ptr *Logv2) Analyze(logname string, marker int) error {
 	if ptr.legacy {
 		return nil
 	}
+	if prior.Start != "" && (start == "" || prior.Start < start) {
+		start = prior.Start
+	}
+	if prior.End > end {
+		end = prior.End
+	}
+	if counter != nil {
+		if err = dbase.SaveCheckpoint(Checkpoint{Identity: identity, Filename: logname,
+			Offset: counter.lastNewline, Line: index, Date: end, Marker: marker}); err != nil {
+			log.Println("error save checkpoint", err)
+			return err
+		}
+	}
+	return ptr.saveLogData(dbase, &failedMap, start, end)
+}
+
+// analyzeFile analyzes a file of a directory or an archive into its own hatchet
+func (ptr *Logv2) analyzeFile(fullPath string, hatchetName string) {
+	// Create a new Logv2 instance for each file to avoid state issues
+	fileLogv2 := ptr.cloneFor(hatchetName)
+	if err := fileLogv2.Analyze(fullPath, 0); err != nil { // marker=0 to skip name regeneration
+		log.Printf("error processing %s: %v", fullPath, err)
+		// continue with other files
+	}
+	// Print summary for each file
+	if !fileLogv2.legacy {
+		fileLogv2.PrintSummary()
+	}
+}
+
+// cloneFor copies the configuration, e.g. filters, timeouts and credentials, to analyze
+// files into a hatchet, with states of files analyzed before reset
+func (ptr *Logv2) cloneFor(hatchetName string) *Logv2 {
+	clone := *ptr
+	clone.buildInfo, clone.logname, clone.totalLines = nil, "", 0
+	clone.hatchetName, clone.merge, clone.merged = hatchetName, false, 0
+	return &clone
+}
+
+// saveLogData commits inserted logs and updates failed messages and hatchet info
+func (ptr *Logv2) saveLogData(dbase Database, failedMap *FailedMessages, start string, end string) error {
+	var err error
 	if err = dbase.Commit(); err != nil {
 		log.Println("error commit", err)
 		return err
 	}
-	if err = dbase.InsertFailedMessages(&failedMap); err != nil {
+	if err = dbase.InsertFailedMessages(failedMap); err != nil {
 		log.Println("error insert failed messages", err)
 		return err
 	}
+	failedMap.reset()
 	info := HatchetInfo{Start: start, End: end, Merge: ptr.merge}
-	if ptr.buildInfo != nil {
+	if ptr.buildInfo == nil { // resumed after build info, keep existing values
+		existing := dbase.GetHatchetInfo()
+		info.Arch, info.OS, info.Module, info.Version = existing.Arch, existing.OS, existing.Module, existing.Version
+	} else {
 		if ptr.buildInfo["environment"] != nil {
 			env := ptr.buildInfo["environment"].(bson.M)
 			info.Arch, _ = env["distarch"].(string)
@@ -502,9 +755,46 @@ func insertLogData(dbase Database, dbMu *sync.Mutex, index int, docEnd string, d
 			}
 		}
 	}
+	if doc.Error != nil {
+		if err := dbase.InsertError(index, docEnd, doc.Error); err != nil {
+			return err
+		}
+	}
+	if doc.Txn != nil {
+		if err := dbase.InsertTransaction(index, docEnd, doc.Txn); err != nil {
+			return err
+		}
+	}
+	if doc.Event != nil {
+		if err := dbase.InsertReplEvent(index, docEnd, doc.Event); err != nil {
+			return err
+		}
+	}
+	if doc.Shard != nil {
+		if err := dbase.InsertShardEvent(index, docEnd, doc.Shard); err != nil {
+			return err
+		}
+	}
+	if doc.Startup != nil {
+		if err := dbase.InsertStartupEvent(index, docEnd, doc.Startup); err != nil {
+			return err
+		}
+	}
+	if doc.Storage != nil {
+		if err := dbase.InsertStorageEvent(index, docEnd, doc.Storage); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// insertAuditLog serializes inserting audit events with a mutex
+func insertAuditLog(dbase Database, dbMu *sync.Mutex, index int, docEnd string, event *AuditEvent) error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	return dbase.InsertAuditLog(index, docEnd, event)
+}
+
 func (ptr *Logv2) PrintSummary() error {
 	// Skip if no hatchet name (e.g., after directory processing)
 	if ptr.hatchetName == "" {
@@ -525,7 +815,7 @@ func (ptr *Logv2) PrintSummary() error {
 	buffer.WriteString(fmt.Sprintf("| Command  |COLLSCAN|avg ms| Count| %-32s| %-50s |\n", "Namespace", "Query Pattern"))
 	buffer.WriteString("|----------+--------+------+------+---------------------------------+----------------------------------------------------|\n")
 	var ops []OpStat
-	if ops, err = dbase.GetSlowOps("avg_ms", "DESC", false); err != nil {
+	if ops, err = dbase.GetSlowOps("avg_ms", "DESC", false, ""); err != nil {
 		return err
 	}
 	lines := 5
@@ -6,9 +6,13 @@
 package hatchet
 
 import (
+	"fmt"
+	"html/template"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Note: sqlite regexp function is registered in audit_test.go init()
@@ -150,3 +154,78 @@ func TestAnalyzeDirectoryUniqueNames(t *testing.T) {
 		nameSet[name] = true
 	}
 }
+
+func TestCloneFor(t *testing.T) {
+	logv2 := &Logv2{url: "hatchet.db", include: "mongod*", exclude: "*.gz", timeout: time.Minute, user: "user:secret",
+		cacheSize: 64, verbose: true, merge: true, merged: 3, totalLines: 100, buildInfo: map[string]interface{}{}}
+	clone := logv2.cloneFor("rs0_mongod")
+	if clone.include != logv2.include || clone.exclude != logv2.exclude || clone.timeout != logv2.timeout ||
+		clone.user != logv2.user || clone.cacheSize != logv2.cacheSize || !clone.verbose {
+		t.Fatalf("expected the configuration copied, got %+v", clone)
+	}
+	if clone.hatchetName != "rs0_mongod" || clone.merge || clone.merged != 0 || clone.totalLines != 0 || clone.buildInfo != nil {
+		t.Fatalf("expected states of files reset, got %+v", clone)
+	}
+}
+
+// useTestDatabase points the instance to a SQLite database in a temporary directory, which is
+// removed, and the instance restored, when the test ends
+func useTestDatabase(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	saved := instance
+	t.Cleanup(func() { instance = saved })
+	instance = &Logv2{url: filepath.Join(tmpDir, "hatchet.db")}
+	return tmpDir
+}
+
+// createTestHatchet creates tables of a hatchet of a temporary database for rows inserted by a test
+func createTestHatchet(t *testing.T, name string) *SQLite3DB {
+	t.Helper()
+	useTestDatabase(t)
+	dbase, err := NewSQLite3DB(instance.url, name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbase.Close() })
+	if _, err = CreateTables(dbase.db, name); err != nil {
+		t.Fatal(err)
+	}
+	return dbase
+}
+
+// analyzeTestLogs analyzes lines of logs into a hatchet of a temporary database, one log file of
+// a marker per group of lines, and returns the database of the hatchet with metadata created
+func analyzeTestLogs(t *testing.T, name string, groups ...[]string) *SQLite3DB {
+	t.Helper()
+	tmpDir := useTestDatabase(t)
+	logv2 := &Logv2{testing: true, url: instance.url, to: time.Now(), merge: true, hatchetName: name}
+	for i, lines := range groups {
+		logname := filepath.Join(tmpDir, fmt.Sprintf("%v_%d.log", name, i+1))
+		if err := os.WriteFile(logname, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := logv2.Analyze(logname, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbase, err := NewSQLite3DB(instance.url, name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbase.Close() })
+	if err = dbase.CreateMetaData(); err != nil {
+		t.Fatal(err)
+	}
+	return dbase
+}
+
+// executeTestTemplate executes a template of a document and returns the output
+func executeTestTemplate(t *testing.T, templ *template.Template, doc map[string]interface{}) string {
+	t.Helper()
+	var buf strings.Builder
+	if err := templ.Execute(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -29,9 +30,17 @@ type MongoDB struct {
 	url         string
 	verbose     bool
 
-	clients []interface{}
-	drivers []interface{}
-	logs    []interface{}
+	auditlogs []interface{}
+	clients   []interface{}
+	drivers   []interface{}
+	errors    []interface{}
+	events    []interface{}
+	logs      []interface{}
+	metrics   []interface{}
+	shards    []interface{}
+	startups  []interface{}
+	storage   []interface{}
+	txns      []interface{}
 }
 
 func NewMongoDB(connstr string, hatchetName string) (*MongoDB, error) {
@@ -97,6 +106,11 @@ func (ptr *MongoDB) Begin() error {
 	return err
 }
 
+// Append adds logs to existing collections
+func (ptr *MongoDB) Append() error {
+	return nil
+}
+
 func (ptr *MongoDB) Commit() error {
 	if len(ptr.logs) > 0 {
 		ptr.db.Collection(ptr.hatchetName).InsertMany(context.Background(), ptr.logs)
@@ -110,6 +124,38 @@ func (ptr *MongoDB) Commit() error {
 		ptr.db.Collection(ptr.hatchetName+"_drivers").InsertMany(context.Background(), ptr.drivers)
 		ptr.drivers = []interface{}{}
 	}
+	if len(ptr.errors) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_errors").InsertMany(context.Background(), ptr.errors)
+		ptr.errors = []interface{}{}
+	}
+	if len(ptr.events) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_events").InsertMany(context.Background(), ptr.events)
+		ptr.events = []interface{}{}
+	}
+	if len(ptr.auditlogs) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_auditlog").InsertMany(context.Background(), ptr.auditlogs)
+		ptr.auditlogs = []interface{}{}
+	}
+	if len(ptr.metrics) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_metrics").InsertMany(context.Background(), ptr.metrics)
+		ptr.metrics = []interface{}{}
+	}
+	if len(ptr.shards) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_sharding").InsertMany(context.Background(), ptr.shards)
+		ptr.shards = []interface{}{}
+	}
+	if len(ptr.startups) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_startup").InsertMany(context.Background(), ptr.startups)
+		ptr.startups = []interface{}{}
+	}
+	if len(ptr.storage) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_storage").InsertMany(context.Background(), ptr.storage)
+		ptr.storage = []interface{}{}
+	}
+	if len(ptr.txns) > 0 {
+		ptr.db.Collection(ptr.hatchetName+"_txns").InsertMany(context.Background(), ptr.txns)
+		ptr.txns = []interface{}{}
+	}
 	return nil
 }
 
@@ -123,11 +169,21 @@ func (ptr *MongoDB) Close() error {
 func (ptr *MongoDB) Drop() error {
 	var err error
 	ptr.db.Collection(ptr.hatchetName + "_audit").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_auditlog").Drop(context.Background())
 	ptr.db.Collection(ptr.hatchetName + "_clients").Drop(context.Background())
 	ptr.db.Collection(ptr.hatchetName + "_drivers").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_errors").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_events").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_metrics").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_migrations").Drop(context.Background())
 	ptr.db.Collection(ptr.hatchetName + "_ops").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_sharding").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_startup").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_storage").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_txns").Drop(context.Background())
 	ptr.db.Collection(ptr.hatchetName).Drop(context.Background())
 	ptr.db.Collection("hatchet").DeleteOne(context.Background(), bson.M{"name": ptr.hatchetName})
+	ptr.db.Collection("checkpoints").DeleteMany(context.Background(), bson.M{"name": ptr.hatchetName})
 	return err
 }
 
@@ -156,10 +212,18 @@ func (ptr *MongoDB) Rename(newName string) error {
 	adminDB := ptr.client.Database("admin")
 	dbName := ptr.db.Name()
 
-	collections := []string{"", "_audit", "_clients", "_drivers", "_ops"}
+	existing, err := ptr.db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	collections := []string{"", "_audit", "_auditlog", "_clients", "_drivers", "_errors", "_events", "_metrics", "_migrations", "_ops",
+		"_sharding", "_startup", "_storage", "_txns"}
 	for _, suffix := range collections {
 		oldColl := oldName + suffix
 		newColl := newName + suffix
+		if !slices.Contains(existing, oldColl) { // tables added in later versions
+			continue
+		}
 		cmd := bson.D{
 			{Key: "renameCollection", Value: dbName + "." + oldColl},
 			{Key: "to", Value: dbName + "." + newColl},
@@ -176,6 +240,12 @@ func (ptr *MongoDB) Rename(newName string) error {
 	if err != nil {
 		return fmt.Errorf("failed to update hatchet registry: %v", err)
 	}
+	_, err = ptr.db.Collection("checkpoints").UpdateMany(ctx,
+		bson.M{"name": oldName},
+		bson.M{"$set": bson.M{"name": newName}})
+	if err != nil {
+		return fmt.Errorf("failed to update checkpoints: %v", err)
+	}
 
 	// Update internal name
 	ptr.hatchetName = newName
@@ -187,9 +257,18 @@ func (ptr *MongoDB) InsertLog(index int, end string, doc *Logv2Info, stat *OpSta
 	var err error
 	data := bson.M{
 		"_id": index, "date": end, "severity": doc.Severity, "component": doc.Component, "context": doc.Context,
-		"msg": doc.Msg, "plan": doc.Attributes.PlanSummary, "type": BsonD2M(doc.Attr)["type"], "ns": doc.Attributes.NS, "message": doc.Message,
+		"msg": doc.Msg, "plan": doc.Attributes.PlanSummary, "type": doc.getAttrMap()["type"], "ns": doc.Attributes.NS, "message": doc.Message,
 		"op": stat.Op, "filter": stat.QueryPattern, "_index": stat.Index, "milli": doc.Attributes.Milli, "reslen": doc.Attributes.Reslen,
-		"appname": doc.Attributes.AppName}
+		"appname": doc.Attributes.AppName, "keys_examined": doc.Attributes.KeysExamined, "docs_examined": doc.Attributes.DocsExamined,
+		"nreturned": doc.Attributes.NReturned, "nmatched": doc.Attributes.NMatched, "nmodified": doc.Attributes.NModified,
+		"num_yields": doc.Attributes.NumYields, "query_hash": doc.Attributes.QueryHash, "plan_cache_key": doc.Attributes.PlanCacheKey,
+		"query_shape_hash": doc.Attributes.QueryShapeHash, "has_sort_stage": stat.HasSortStage, "used_disk": stat.UsedDisk,
+		"pipeline": stat.PipelineShape, "sort_shape": stat.SortShape, "projection": stat.Projection, "limit_skip": stat.LimitSkip,
+		"hint": stat.Hint, "bytes_read": stat.BytesRead, "cpu_nanos": stat.CPUNanos, "flow_control_micros": stat.FlowControlMicros,
+		"lock_wait_micros": stat.LockWaitMicros, "remote_wait_ms": stat.RemoteOpWaitMillis, "time_reading_micros": stat.TimeReadingMicros,
+		"update_shape": stat.UpdateShape, "write_flags": stat.WriteFlags, "keys_inserted": stat.KeysInserted,
+		"keys_deleted": stat.KeysDeleted, "ninserted": stat.NInserted, "ndeleted": stat.NDeleted, "nupserted": stat.NUpserted,
+		"marker": doc.Marker}
 	ptr.logs = append(ptr.logs, data)
 	if len(ptr.logs) > BATCH_SIZE {
 		collName := ptr.hatchetName
@@ -228,6 +307,131 @@ func (ptr *MongoDB) InsertDriver(index int, doc *Logv2Info) error {
 	return err
 }
 
+// InsertError inserts an error of a log line
+func (ptr *MongoDB) InsertError(index int, end string, logError *LogError) error {
+	var err error
+	data := bson.M{
+		"_id": index, "date": end, "code": logError.Code, "name": logError.Name, "category": logError.Category,
+		"ns": logError.Namespace, "op": logError.Op, "appname": logError.AppName, "ip": logError.IP,
+		"component": logError.Component, "marker": logError.Marker}
+	ptr.errors = append(ptr.errors, data)
+	if len(ptr.errors) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_errors"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.errors)
+		ptr.errors = []interface{}{}
+	}
+	return err
+}
+
+// InsertReplEvent inserts a replica set event
+func (ptr *MongoDB) InsertReplEvent(index int, end string, event *ReplEvent) error {
+	var err error
+	data := bson.M{
+		"_id": index, "date": end, "type": event.Type, "name": event.Name, "from_state": event.FromState,
+		"to_state": event.ToState, "detail": event.Detail, "component": event.Component, "context": event.Context,
+		"marker": event.Marker}
+	ptr.events = append(ptr.events, data)
+	if len(ptr.events) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_events"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.events)
+		ptr.events = []interface{}{}
+	}
+	return err
+}
+
+// InsertShardEvent inserts a chunk migration, a range deletion, a balancer round or a resharding progress
+func (ptr *MongoDB) InsertShardEvent(index int, end string, event *ShardEvent) error {
+	var err error
+	data := bson.M{
+		"_id": index, "date": end, "type": event.Type, "name": event.Name, "ns": event.Namespace,
+		"donor": event.Donor, "recipient": event.Recipient, "milli": event.Milli, "status": event.Status,
+		"detail": event.Detail, "marker": event.Marker}
+	ptr.shards = append(ptr.shards, data)
+	if len(ptr.shards) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_sharding"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.shards)
+		ptr.shards = []interface{}{}
+	}
+	return err
+}
+
+// InsertStorageEvent inserts a checkpoint or an eviction warning of WiredTiger
+func (ptr *MongoDB) InsertStorageEvent(index int, end string, event *StorageEvent) error {
+	var err error
+	data := bson.M{
+		"_id": index, "date": end, "type": event.Type, "phase": event.Phase, "milli": event.Milli,
+		"pages": event.Pages, "mb": event.MB, "detail": event.Detail, "marker": event.Marker}
+	ptr.storage = append(ptr.storage, data)
+	if len(ptr.storage) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_storage"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.storage)
+		ptr.storage = []interface{}{}
+	}
+	return err
+}
+
+// InsertStartupEvent inserts settings of a startup message, one document per setting
+func (ptr *MongoDB) InsertStartupEvent(index int, end string, event *StartupEvent) error {
+	var err error
+	for _, setting := range event.Settings { // of the same line, _id is generated
+		ptr.startups = append(ptr.startups, bson.M{
+			"id": index, "date": end, "type": event.Type, "name": setting.Name, "value": setting.Value,
+			"marker": event.Marker})
+	}
+	if len(ptr.startups) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_startup"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.startups)
+		ptr.startups = []interface{}{}
+	}
+	return err
+}
+
+// InsertAuditLog inserts an event of MongoDB auditLog
+func (ptr *MongoDB) InsertAuditLog(index int, end string, event *AuditEvent) error {
+	var err error
+	data := bson.M{
+		"_id": index, "date": end, "atype": event.AType, "category": event.Category, "user": event.User,
+		"roles": event.Roles, "remote": event.Remote, "target": event.Target, "param": event.Param,
+		"result": event.Result, "marker": event.Marker}
+	ptr.auditlogs = append(ptr.auditlogs, data)
+	if len(ptr.auditlogs) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_auditlog"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.auditlogs)
+		ptr.auditlogs = []interface{}{}
+	}
+	return err
+}
+
+// InsertMetric inserts a metric of FTDC
+func (ptr *MongoDB) InsertMetric(metric Metric) error {
+	var err error
+	ptr.metrics = append(ptr.metrics, metric)
+	if len(ptr.metrics) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_metrics"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.metrics)
+		ptr.metrics = []interface{}{}
+	}
+	return err
+}
+
+// InsertTransaction inserts a transaction, or a slow op of a transaction or with conflicts
+func (ptr *MongoDB) InsertTransaction(index int, end string, txn *Transaction) error {
+	var err error
+	data := bson.M{
+		"_id": index, "date": end, "lsid": txn.LSID, "txn_number": txn.TxnNumber, "autocommit": txn.Autocommit,
+		"ns": txn.Namespace, "op": txn.Op, "termination": txn.Termination, "reason": txn.Reason, "milli": txn.Milli,
+		"time_active_micros": txn.TimeActiveMicros, "time_inactive_micros": txn.TimeInactiveMicros,
+		"write_conflicts": txn.WriteConflicts, "prepare_conflict_ms": txn.PrepareConflictMillis,
+		"was_prepared": txn.WasPrepared, "marker": txn.Marker}
+	ptr.txns = append(ptr.txns, data)
+	if len(ptr.txns) > BATCH_SIZE {
+		collName := ptr.hatchetName + "_txns"
+		_, err = ptr.db.Collection(collName).InsertMany(context.Background(), ptr.txns)
+		ptr.txns = []interface{}{}
+	}
+	return err
+}
+
 func (ptr *MongoDB) UpdateHatchetInfo(info HatchetInfo) error {
 	var err error
 	filter := bson.M{"name": ptr.hatchetName}
@@ -245,44 +449,73 @@ func (ptr *MongoDB) UpdateHatchetInfo(info HatchetInfo) error {
 	return err
 }
 
+// GetCheckpoint returns the checkpoint of a file identity
+func (ptr *MongoDB) GetCheckpoint(identity string) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := ptr.db.Collection("checkpoints").FindOne(context.Background(), bson.M{"_id": identity}).Decode(&checkpoint)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint saves the checkpoint of a file identity
+func (ptr *MongoDB) SaveCheckpoint(checkpoint Checkpoint) error {
+	checkpoint.Name = ptr.hatchetName
+	upsertOptions := options.Replace().SetUpsert(true)
+	_, err := ptr.db.Collection("checkpoints").ReplaceOne(context.Background(),
+		bson.M{"_id": checkpoint.Identity}, checkpoint, upsertOptions)
+	return err
+}
+
+// getOpsPipeline returns stages of ops grouped by patterns, of logs of a match, e.g. of a process
+// lifetime, as documents of the {hatchet}_ops collection.  Ops of each pattern are counted by latency
+// buckets of histograms, merged into percentiles of the grouping shown by GetSlowOps, as of SQLite.
+func getOpsPipeline(match bson.M) []bson.M {
+	branches := []bson.M{}
+	for _, b := range getLatencyBuckets() {
+		branches = append(branches, bson.M{"case": bson.M{"$lt": []interface{}{"$milli", b[0]}},
+			"then": bson.M{"$multiply": []interface{}{bson.M{"$floor": bson.M{"$divide": []interface{}{"$milli", b[1]}}}, b[1]}}})
+	}
+	keys := append([]string{"op", "ns", "filter", "_index", "marker"}, SLOWOP_TEXT_COLUMNS...)
+	bucketID := bson.M{"ms": bson.M{"$switch": bson.M{"branches": branches, "default": "$milli"}}}
+	opID := bson.M{}
+	project := bson.M{"_id": 0, "count": 1, "max_ms": 1, "total_ms": 1, "reslen": 1, "histogram": 1,
+		"avg_ms": bson.M{"$round": []interface{}{bson.M{"$divide": []interface{}{"$total_ms", "$count"}}, 1}}}
+	for _, key := range keys {
+		bucketID[key] = "$" + key
+		opID[key] = "$_id." + key
+		project[key] = "$_id." + key
+	}
+	buckets := bson.M{"_id": bucketID, "count": bson.M{"$sum": 1}, "max_ms": bson.M{"$max": "$milli"},
+		"total_ms": bson.M{"$sum": "$milli"}, "reslen": bson.M{"$sum": "$reslen"}}
+	ops := bson.M{"_id": opID, "count": bson.M{"$sum": "$count"}, "max_ms": bson.M{"$max": "$max_ms"},
+		"total_ms": bson.M{"$sum": "$total_ms"}, "reslen": bson.M{"$sum": "$reslen"},
+		"histogram": bson.M{"$push": bson.M{"ms": "$_id.ms", "n": "$count"}}}
+	for _, column := range SLOWOP_METRIC_COLUMNS {
+		buckets[column] = bson.M{"$sum": "$" + column}
+		ops[column] = bson.M{"$sum": "$" + column}
+		project[column] = 1
+	}
+	return []bson.M{
+		{"$match": match},
+		{"$group": buckets},
+		{"$group": ops},
+		{"$project": project},
+	}
+}
+
 func (ptr *MongoDB) CreateMetaData() error {
 	var err error
+	// remove metadata of the previous run
+	ptr.db.Collection(ptr.hatchetName + "_ops").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_migrations").Drop(context.Background())
+	ptr.db.Collection(ptr.hatchetName + "_audit").Drop(context.Background())
 	log.Printf("insert ops into %v_ops\n", ptr.hatchetName)
-	pipeline := []bson.M{
-		{"$match": bson.M{
-			"op": bson.M{
-				"$nin": []interface{}{nil, ""},
-			},
-		}},
-		{"$group": bson.M{
-			"_id": bson.M{
-				"op":     "$op",
-				"ns":     "$ns",
-				"filter": "$filter",
-				"_index": "$_index",
-			},
-			"count":    bson.M{"$sum": 1},
-			"avg_ms":   bson.M{"$avg": "$milli"},
-			"max_ms":   bson.M{"$max": "$milli"},
-			"total_ms": bson.M{"$sum": "$milli"},
-			"reslen":   bson.M{"$sum": "$reslen"},
-		}},
-		{"$project": bson.M{
-			"_id":      0,
-			"op":       "$_id.op",
-			"count":    1,
-			"avg_ms":   bson.M{"$round": []interface{}{"$avg_ms", 0}},
-			"max_ms":   1,
-			"total_ms": 1,
-			"ns":       "$_id.ns",
-			"_index":   "$_id._index",
-			"reslen":   1,
-			"filter":   "$_id.filter",
-		}},
-		{"$merge": bson.M{
-			"into": ptr.hatchetName + "_ops",
-		}},
-	}
+	pipeline := append(getOpsPipeline(bson.M{"op": bson.M{"$nin": []interface{}{nil, ""}}}),
+		bson.M{"$merge": bson.M{"into": ptr.hatchetName + "_ops"}})
 	if _, err = ptr.db.Collection(ptr.hatchetName).Aggregate(context.Background(), pipeline); err != nil {
 		return err
 	}
@@ -547,7 +780,77 @@ func (ptr *MongoDB) CreateMetaData() error {
 	if _, err = ptr.db.Collection(ptr.hatchetName).Aggregate(context.Background(), pipeline); err != nil {
 		return err
 	}
-	return nil
+	return ptr.createShardingMetaData()
+}
+
+// createShardingMetaData summarizes chunk migrations by namespaces and donor/recipient pairs
+// into the migrations collection, and counts of range deletions, balancer rounds and migration
+// decisions into the audit collection
+func (ptr *MongoDB) createShardingMetaData() error {
+	var err error
+	log.Printf("insert migrations into %v_migrations\n", ptr.hatchetName)
+	pipeline := []bson.M{
+		{"$match": bson.M{"type": SHARDING_MIGRATION}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"ns":        "$ns",
+				"donor":     "$donor",
+				"recipient": "$recipient",
+			},
+			"count":    bson.M{"$sum": 1},
+			"failed":   bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{"$status", MIGRATION_FAILED}}, 1, 0}}},
+			"avg_ms":   bson.M{"$avg": "$milli"},
+			"max_ms":   bson.M{"$max": "$milli"},
+			"total_ms": bson.M{"$sum": "$milli"},
+		}},
+		{"$project": bson.M{
+			"_id":       0,
+			"ns":        "$_id.ns",
+			"donor":     "$_id.donor",
+			"recipient": "$_id.recipient",
+			"count":     1,
+			"failed":    1,
+			"avg_ms":    bson.M{"$round": []interface{}{"$avg_ms", 1}},
+			"max_ms":    1,
+			"total_ms":  1,
+		}},
+		{"$merge": bson.M{
+			"into": ptr.hatchetName + "_migrations",
+		}},
+	}
+	if _, err = ptr.db.Collection(ptr.hatchetName+"_sharding").Aggregate(context.Background(), pipeline); err != nil {
+		return err
+	}
+
+	for _, shardType := range []string{SHARDING_BALANCER, SHARDING_DECISION, SHARDING_RANGE_DELETION} {
+		name := "$ns" // range deletions by namespaces
+		if shardType == SHARDING_BALANCER {
+			name = "$name"
+		} else if shardType == SHARDING_DECISION {
+			name = "$status"
+		}
+		log.Printf("insert [%v] into %v_audit\n", shardType, ptr.hatchetName)
+		pipeline = []bson.M{
+			{"$match": bson.M{"type": shardType}},
+			{"$group": bson.M{
+				"_id":   name,
+				"count": bson.M{"$sum": 1},
+			}},
+			{"$project": bson.M{
+				"_id":   0,
+				"type":  shardType,
+				"name":  "$_id",
+				"value": "$count",
+			}},
+			{"$merge": bson.M{
+				"into": ptr.hatchetName + "_audit",
+			}},
+		}
+		if _, err = ptr.db.Collection(ptr.hatchetName+"_sharding").Aggregate(context.Background(), pipeline); err != nil {
+			return err
+		}
+	}
+	return err
 }
 
 func (ptr *MongoDB) InsertFailedMessages(m *FailedMessages) error {
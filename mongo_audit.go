@@ -119,6 +119,72 @@ func (ptr *MongoDB) GetAuditData() (map[string][]NameValues, error) {
 		data[category] = append(data[category], doc)
 	}
 
+	if err = ptr.getAuditLogData(data); err != nil {
+		return data, err
+	}
+
+	// get errors by names, and by namespaces, appNames and client IPs
+	collection = ptr.db.Collection(ptr.hatchetName + "_errors")
+	pipeline = []bson.M{
+		{"$group": bson.M{"_id": bson.M{"name": "$name", "category": "$category"}, "code": bson.M{"$max": "$code"},
+			"count": bson.M{"$sum": 1}, "namespaces": bson.M{"$addToSet": "$ns"}, "last": bson.M{"$max": "$date"}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": LIMIT},
+	}
+	if cur, err = collection.Aggregate(ctx, pipeline); err != nil {
+		return data, err
+	}
+	for cur.Next(ctx) {
+		var errorData struct {
+			ID struct {
+				Category string `bson:"category"`
+				Name     string `bson:"name"`
+			} `bson:"_id"`
+			Code       int      `bson:"code"`
+			Count      int      `bson:"count"`
+			Last       string   `bson:"last"`
+			Namespaces []string `bson:"namespaces"`
+		}
+		if err = cur.Decode(&errorData); err != nil {
+			cur.Close(ctx)
+			return data, err
+		}
+		data["errors"] = append(data["errors"], NameValues{errorData.ID.Name, []interface{}{errorData.Code,
+			errorData.ID.Category, errorData.Count, len(errorData.Namespaces), errorData.Last}})
+	}
+	cur.Close(ctx)
+	pipeline = []bson.M{
+		{"$group": bson.M{"_id": bson.M{"name": "$name", "ns": "$ns", "appname": "$appname", "ip": "$ip"},
+			"count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": LIMIT},
+	}
+	if cur, err = collection.Aggregate(ctx, pipeline); err != nil {
+		return data, err
+	}
+	for cur.Next(ctx) {
+		var sourceData struct {
+			ID struct {
+				AppName string `bson:"appname"`
+				IP      string `bson:"ip"`
+				Name    string `bson:"name"`
+				NS      string `bson:"ns"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		if err = cur.Decode(&sourceData); err != nil {
+			cur.Close(ctx)
+			return data, err
+		}
+		appname := sourceData.ID.AppName
+		if appname == "" {
+			appname = "unknown"
+		}
+		data["error-sources"] = append(data["error-sources"], NameValues{sourceData.ID.Name, []interface{}{sourceData.ID.NS,
+			appname, sourceData.ID.IP, sourceData.Count}})
+	}
+	cur.Close(ctx)
+
 	// get reslen-ip and reslen-ns data
 	for _, category := range []string{"ip", "ns"} {
 		collection = ptr.db.Collection(ptr.hatchetName + "_audit")
@@ -271,3 +337,127 @@ func (ptr *MongoDB) GetAuditData() (map[string][]NameValues, error) {
 	}
 	return data, err
 }
+
+// getAuditLogData adds authentication failures, privilege changes, DDL events,
+// and activities by users from events of MongoDB auditLog
+func (ptr *MongoDB) getAuditLogData(data map[string][]NameValues) error {
+	ctx := context.Background()
+	collection := ptr.db.Collection(ptr.hatchetName + "_auditlog")
+	user := bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{"$user", ""}}, "unknown", "$user"}}
+	failed := bson.M{"$cond": []interface{}{bson.M{"$ne": []interface{}{"$result", 0}}, 1, 0}}
+
+	category := "authfail"
+	pipeline := []bson.M{
+		{"$match": bson.M{"category": AUDIT_AUTH, "result": bson.M{"$ne": 0}}},
+		{"$group": bson.M{"_id": bson.M{"user": user, "atype": "$atype", "remote": "$remote"},
+			"count": bson.M{"$sum": 1}, "last": bson.M{"$max": "$date"}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": LIMIT},
+	}
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	for cur.Next(ctx) {
+		var authData struct {
+			ID struct {
+				AType  string `bson:"atype"`
+				Remote string `bson:"remote"`
+				User   string `bson:"user"`
+			} `bson:"_id"`
+			Count int    `bson:"count"`
+			Last  string `bson:"last"`
+		}
+		if err = cur.Decode(&authData); err != nil {
+			cur.Close(ctx)
+			return err
+		}
+		data[category] = append(data[category], NameValues{authData.ID.User, []interface{}{authData.ID.AType,
+			authData.ID.Remote, authData.Count, authData.Last}})
+	}
+	cur.Close(ctx)
+
+	category = "privilege"
+	pipeline = []bson.M{
+		{"$match": bson.M{"category": AUDIT_PRIVILEGE}},
+		{"$sort": bson.M{"date": 1}},
+		{"$limit": LIMIT},
+		{"$project": bson.M{"atype": 1, "date": 1, "user": user, "target": 1, "result": 1}},
+	}
+	if cur, err = collection.Aggregate(ctx, pipeline); err != nil {
+		return err
+	}
+	for cur.Next(ctx) {
+		var privilegeData struct {
+			AType  string `bson:"atype"`
+			Date   string `bson:"date"`
+			Result int    `bson:"result"`
+			Target string `bson:"target"`
+			User   string `bson:"user"`
+		}
+		if err = cur.Decode(&privilegeData); err != nil {
+			cur.Close(ctx)
+			return err
+		}
+		data[category] = append(data[category], NameValues{privilegeData.AType, []interface{}{privilegeData.Date,
+			privilegeData.User, privilegeData.Target, privilegeData.Result}})
+	}
+	cur.Close(ctx)
+
+	category = "ddl"
+	pipeline = []bson.M{
+		{"$match": bson.M{"category": AUDIT_DDL}},
+		{"$group": bson.M{"_id": bson.M{"atype": "$atype", "target": "$target"},
+			"count": bson.M{"$sum": 1}, "last": bson.M{"$max": "$date"}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": LIMIT},
+	}
+	if cur, err = collection.Aggregate(ctx, pipeline); err != nil {
+		return err
+	}
+	for cur.Next(ctx) {
+		var ddlData struct {
+			ID struct {
+				AType  string `bson:"atype"`
+				Target string `bson:"target"`
+			} `bson:"_id"`
+			Count int    `bson:"count"`
+			Last  string `bson:"last"`
+		}
+		if err = cur.Decode(&ddlData); err != nil {
+			cur.Close(ctx)
+			return err
+		}
+		data[category] = append(data[category], NameValues{ddlData.ID.AType, []interface{}{ddlData.ID.Target,
+			ddlData.Count, ddlData.Last}})
+	}
+	cur.Close(ctx)
+
+	category = "user"
+	pipeline = []bson.M{
+		{"$group": bson.M{"_id": user, "count": bson.M{"$sum": 1}, "failed": bson.M{"$sum": failed},
+			"atypes": bson.M{"$addToSet": "$atype"}, "first": bson.M{"$min": "$date"}, "last": bson.M{"$max": "$date"}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": LIMIT},
+	}
+	if cur, err = collection.Aggregate(ctx, pipeline); err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var userData struct {
+			ID     string   `bson:"_id"`
+			ATypes []string `bson:"atypes"`
+			Count  int      `bson:"count"`
+			Failed int      `bson:"failed"`
+			First  string   `bson:"first"`
+			Last   string   `bson:"last"`
+		}
+		if err = cur.Decode(&userData); err != nil {
+			return err
+		}
+		data[category] = append(data[category], NameValues{userData.ID, []interface{}{userData.Count, userData.Failed,
+			len(userData.ATypes), userData.First, userData.Last}})
+	}
+	return nil
+}
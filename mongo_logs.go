@@ -8,6 +8,7 @@ package hatchet
 import (
 	"context"
 	"log"
+	"slices"
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,12 +16,36 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (ptr *MongoDB) GetSlowOps(orderBy string, order string, collscan bool) ([]OpStat, error) {
+// GetSlowOps returns slow ops grouped by Hatchet query patterns, or by a column of SLOWOP_GROUPS.
+// Options of duration=start,end and marker=n filter slow ops, e.g. of a process lifetime.
+func (ptr *MongoDB) GetSlowOps(orderBy string, order string, collscan bool, groupBy string, opts ...string) ([]OpStat, error) {
 	db := ptr.db
+	// ops of a duration or a marker are aggregated from logs instead of the {hatchet}_ops
+	// collection of the whole hatchet
+	match := bson.M{}
+	for _, opt := range opts {
+		toks := strings.Split(opt, "=")
+		if len(toks) < 2 || toks[1] == "" {
+			continue
+		}
+		if toks[0] == "duration" {
+			start, end, err := GetDuration(toks[1])
+			if err != nil {
+				return nil, err
+			}
+			match["date"] = bson.M{"$gte": start, "$lt": end}
+		} else if toks[0] == "marker" {
+			match["marker"] = ToInt(toks[1])
+		}
+	}
 	sortOrder := 1
 	if order == "DESC" {
 		sortOrder = -1
 	}
+	sortBy := orderBy // percentiles are sorted after histograms are merged
+	if slices.Contains(PERCENTILE_COLUMNS, orderBy) {
+		sortBy = "max_ms"
+	}
 	pipeline := []bson.M{
 		{
 			"$match": bson.M{
@@ -30,46 +55,136 @@ func (ptr *MongoDB) GetSlowOps(orderBy string, order string, collscan bool) ([]O
 		{
 			"$group": bson.M{
 				"_id": bson.M{
-					"op":     "$op",
-					"ns":     "$ns",
-					"filter": "$filter",
-					"_index": "$_index",
+					"op":         "$op",
+					"ns":         "$ns",
+					"filter":     "$filter",
+					"_index":     "$_index",
+					"pipeline":   "$pipeline",
+					"sort_shape": "$sort_shape",
+					"projection": "$projection",
+					"limit_skip": "$limit_skip",
+					"hint":       "$hint",
+
+					"update_shape": "$update_shape",
+					"write_flags":  "$write_flags",
 				},
-				"count":    bson.M{"$sum": "$count"},
-				"avg_ms":   bson.M{"$avg": "$avg_ms"},
-				"max_ms":   bson.M{"$max": "$max_ms"},
-				"total_ms": bson.M{"$sum": "$total_ms"},
-				"reslen":   bson.M{"$sum": "$reslen"},
+				"count":            bson.M{"$sum": "$count"},
+				"max_ms":           bson.M{"$max": "$max_ms"},
+				"total_ms":         bson.M{"$sum": "$total_ms"},
+				"reslen":           bson.M{"$sum": "$reslen"},
+				"keys_examined":    bson.M{"$sum": "$keys_examined"},
+				"docs_examined":    bson.M{"$sum": "$docs_examined"},
+				"nreturned":        bson.M{"$sum": "$nreturned"},
+				"nmatched":         bson.M{"$sum": "$nmatched"},
+				"nmodified":        bson.M{"$sum": "$nmodified"},
+				"num_yields":       bson.M{"$sum": "$num_yields"},
+				"histograms":       bson.M{"$push": "$histogram"},
+				"marker":           bson.M{"$max": "$marker"},
+				"indexes":          bson.M{"$addToSet": "$_index"},
+				"filters":          bson.M{"$addToSet": "$filter"},
+				"query_hash":       bson.M{"$addToSet": "$query_hash"},
+				"plan_cache_key":   bson.M{"$addToSet": "$plan_cache_key"},
+				"query_shape_hash": bson.M{"$addToSet": "$query_shape_hash"},
+				"has_sort_stage":   bson.M{"$sum": "$has_sort_stage"},
+				"used_disk":        bson.M{"$sum": "$used_disk"},
+				"pipelines":        bson.M{"$addToSet": "$pipeline"},
+				"sorts":            bson.M{"$addToSet": "$sort_shape"},
+				"projections":      bson.M{"$addToSet": "$projection"},
+				"limit_skips":      bson.M{"$addToSet": "$limit_skip"},
+				"hints":            bson.M{"$addToSet": "$hint"},
+				"update_shapes":    bson.M{"$addToSet": "$update_shape"},
+				"write_flags":      bson.M{"$addToSet": "$write_flags"},
+
+				"bytes_read":          bson.M{"$sum": "$bytes_read"},
+				"cpu_nanos":           bson.M{"$sum": "$cpu_nanos"},
+				"flow_control_micros": bson.M{"$sum": "$flow_control_micros"},
+				"lock_wait_micros":    bson.M{"$sum": "$lock_wait_micros"},
+				"remote_wait_ms":      bson.M{"$sum": "$remote_wait_ms"},
+				"time_reading_micros": bson.M{"$sum": "$time_reading_micros"},
+
+				"keys_inserted": bson.M{"$sum": "$keys_inserted"},
+				"keys_deleted":  bson.M{"$sum": "$keys_deleted"},
+				"ninserted":     bson.M{"$sum": "$ninserted"},
+				"ndeleted":      bson.M{"$sum": "$ndeleted"},
+				"nupserted":     bson.M{"$sum": "$nupserted"},
 			},
 		},
 		{
 			"$project": bson.M{
-				"_id":           0,
-				"op":            "$_id.op",
-				"count":         1,
-				"avg_ms":        bson.M{"$round": []interface{}{"$avg_ms", 0}},
-				"max_ms":        1,
-				"total_ms":      1,
-				"ns":            "$_id.ns",
-				"index":         "$_id._index",
-				"reslen":        1,
-				"query_pattern": "$_id.filter",
+				"_id":              0,
+				"op":               "$_id.op",
+				"count":            1,
+				"avg_ms":           bson.M{"$round": []interface{}{bson.M{"$divide": []interface{}{"$total_ms", "$count"}}, 1}},
+				"max_ms":           1,
+				"total_ms":         1,
+				"ns":               "$_id.ns",
+				"index":            "$_id._index",
+				"reslen":           1,
+				"query_pattern":    "$_id.filter",
+				"keys_examined":    1,
+				"docs_examined":    1,
+				"nreturned":        1,
+				"nmatched":         1,
+				"nmodified":        1,
+				"num_yields":       1,
+				"marker":           1,
+				"query_hash":       joinDistinct("$query_hash"),
+				"plan_cache_key":   joinDistinct("$plan_cache_key"),
+				"query_shape_hash": joinDistinct("$query_shape_hash"),
+				"has_sort_stage":   1,
+				"used_disk":        1,
+				"sort_shape":       joinDistinct("$sorts"),
+				"projection":       joinDistinct("$projections"),
+				"limit_skip":       joinDistinct("$limit_skips"),
+				"hint":             joinDistinct("$hints"),
+				"update_shape":     joinDistinct("$update_shapes"),
+				"write_flags":      joinDistinct("$write_flags"),
+				"pipeline": bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{bson.M{"$size": "$pipelines"}, 1}},
+					bson.M{"$arrayElemAt": []interface{}{"$pipelines", 0}}, ""}},
+
+				"bytes_read":          1,
+				"cpu_nanos":           1,
+				"flow_control_micros": 1,
+				"lock_wait_micros":    1,
+				"remote_wait_ms":      1,
+				"time_reading_micros": 1,
+
+				"keys_inserted": 1,
+				"keys_deleted":  1,
+				"ninserted":     1,
+				"ndeleted":      1,
+				"nupserted":     1,
+
+				"histogram": bson.M{"$reduce": bson.M{"input": "$histograms", "initialValue": bson.A{},
+					"in": bson.M{"$concatArrays": []interface{}{"$$value", "$$this"}}}},
 			},
 		},
 		{
 			"$sort": bson.M{
-				orderBy: sortOrder,
+				sortBy: sortOrder,
 			},
 		},
 	}
 	if !collscan {
 		pipeline[0]["$match"] = bson.M{"op": bson.M{"$nin": []interface{}{nil, ""}}}
 	}
+	if groupBy != "" {
+		pipeline[0]["$match"].(bson.M)[groupBy] = bson.M{"$nin": []interface{}{nil, ""}}
+		pipeline[1]["$group"].(bson.M)["_id"] = bson.M{"op": "$op", "ns": "$ns", groupBy: "$" + groupBy}
+		pipeline[2]["$project"].(bson.M)["index"] = joinDistinct("$indexes")
+		pipeline[2]["$project"].(bson.M)["query_pattern"] = joinDistinct("$filters")
+	}
+	collection := db.Collection(ptr.hatchetName + "_ops")
+	if len(match) > 0 {
+		match["op"] = bson.M{"$nin": []interface{}{nil, ""}}
+		pipeline = append(getOpsPipeline(match), pipeline...)
+		collection = db.Collection(ptr.hatchetName)
+	}
 	if ptr.verbose {
 		log.Println(pipeline)
 	}
 	var ops []OpStat
-	cur, err := db.Collection(ptr.hatchetName+"_ops").Aggregate(context.Background(), pipeline)
+	cur, err := collection.Aggregate(context.Background(), pipeline)
 	if err != nil {
 		return ops, err
 	}
@@ -79,14 +194,32 @@ func (ptr *MongoDB) GetSlowOps(orderBy string, order string, collscan bool) ([]O
 		if err = cur.Decode(&op); err != nil {
 			return ops, err
 		}
+		SetPercentiles(&op)
+		SetQueryTargeting(&op)
+		SetWriteAmplification(&op)
+		SetPlanFlip(&op, groupBy)
+		SetPipeline(&op)
 		ops = append(ops, op)
 	}
 	if err = cur.Err(); err != nil {
 		return ops, err
 	}
+	if sortBy != orderBy {
+		SortByPercentile(ops, orderBy, order)
+	}
 	return ops, nil
 }
 
+// joinDistinct joins non-empty values of an array with DISTINCT_SEPARATOR
+func joinDistinct(array string) bson.M {
+	return bson.M{"$reduce": bson.M{
+		"input":        bson.M{"$filter": bson.M{"input": array, "cond": bson.M{"$gt": []interface{}{"$$this", ""}}}},
+		"initialValue": "",
+		"in": bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{"$$value", ""}}, "$$this",
+			bson.M{"$concat": []interface{}{"$$value", DISTINCT_SEPARATOR, "$$this"}}}},
+	}}
+}
+
 func (ptr *MongoDB) GetLogs(opts ...string) ([]LegacyLog, error) {
 	docs := []LegacyLog{}
 	collection := ptr.db.Collection(ptr.hatchetName)
@@ -102,8 +235,13 @@ func (ptr *MongoDB) GetLogs(opts ...string) ([]LegacyLog, error) {
 			continue
 		}
 		if toks[0] == "duration" {
-			dates := strings.Split(toks[1], ",")
-			filter["date"] = bson.M{"$gte": dates[0], "$lt": dates[1]}
+			start, end, err := GetDuration(toks[1])
+			if err != nil {
+				return docs, err
+			}
+			filter["date"] = bson.M{"$gte": start, "$lt": end}
+		} else if toks[0] == "marker" { // of a member of merged logs
+			filter["marker"] = ToInt(toks[1])
 		} else if toks[0] == "limit" {
 			offset, nlimit = GetOffsetLimit(toks[1])
 			qlimit = ToInt(nlimit) + 1
@@ -150,7 +288,10 @@ func (ptr *MongoDB) SearchLogs(opts ...string) ([]LegacyLog, error) {
 	var offset, nlimit int
 	ctx := context.Background()
 
-	filter := buildMongoSearchFilter(opts)
+	filter, err := buildMongoSearchFilter(opts)
+	if err != nil {
+		return docs, err
+	}
 	for _, opt := range opts {
 		toks := strings.Split(opt, "=")
 		if len(toks) < 2 || toks[1] == "" {
@@ -182,13 +323,16 @@ func (ptr *MongoDB) SearchLogs(opts ...string) ([]LegacyLog, error) {
 func (ptr *MongoDB) CountLogs(opts ...string) (int, error) {
 	collection := ptr.db.Collection(ptr.hatchetName)
 	ctx := context.Background()
-	filter := buildMongoSearchFilter(opts)
+	filter, err := buildMongoSearchFilter(opts)
+	if err != nil {
+		return 0, err
+	}
 	count, err := collection.CountDocuments(ctx, filter)
 	return int(count), err
 }
 
 // buildMongoSearchFilter builds a MongoDB filter from search options
-func buildMongoSearchFilter(opts []string) bson.M {
+func buildMongoSearchFilter(opts []string) (bson.M, error) {
 	filter := bson.M{}
 	for _, opt := range opts {
 		toks := strings.Split(opt, "=")
@@ -196,8 +340,13 @@ func buildMongoSearchFilter(opts []string) bson.M {
 			continue
 		}
 		if toks[0] == "duration" {
-			dates := strings.Split(toks[1], ",")
-			filter["date"] = bson.M{"$gte": dates[0], "$lt": dates[1]}
+			start, end, err := GetDuration(toks[1])
+			if err != nil {
+				return filter, err
+			}
+			filter["date"] = bson.M{"$gte": start, "$lt": end}
+		} else if toks[0] == "marker" {
+			filter["marker"] = ToInt(toks[1])
 		} else if toks[0] == "limit" {
 			// skip limit for filter
 			continue
@@ -216,7 +365,7 @@ func buildMongoSearchFilter(opts []string) bson.M {
 			filter[toks[0]] = EscapeString(toks[1])
 		}
 	}
-	return filter
+	return filter, nil
 }
 
 func (ptr *MongoDB) GetSlowestLogs(topN int) ([]LegacyLog, error) {
@@ -17,7 +17,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (ptr *MongoDB) GetAverageOpTime(op string, duration string) ([]OpCount, error) {
+func (ptr *MongoDB) GetAverageOpTime(op string, duration string, marker string) ([]OpCount, error) {
 	var docs []OpCount
 	var substr bson.M
 	ctx := context.Background()
@@ -25,6 +25,9 @@ func (ptr *MongoDB) GetAverageOpTime(op string, duration string) ([]OpCount, err
 	if op != "" {
 		opcond = bson.M{"op": op}
 	}
+	if marker != "" { // of a member of merged logs
+		opcond["marker"] = ToInt(marker)
+	}
 	if duration != "" {
 		toks := strings.Split(duration, ",")
 		substr = GetMongoDateSubString(toks[0], toks[1])
@@ -83,6 +86,284 @@ func (ptr *MongoDB) GetAverageOpTime(op string, duration string) ([]OpCount, err
 	return docs, nil
 }
 
+// GetTimeBreakdown returns seconds of TIME_BREAKDOWN spent by slow ops over a period of time
+func (ptr *MongoDB) GetTimeBreakdown(op string, duration string) ([]Metric, error) {
+	docs := []Metric{}
+	var substr bson.M
+	ctx := context.Background()
+	opcond := bson.M{"op": bson.M{"$ne": ""}}
+	if op != "" {
+		opcond = bson.M{"op": op}
+	}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		substr = GetMongoDateSubString(toks[0], toks[1])
+		opcond["$and"] = []bson.M{
+			{"date": bson.M{"$gte": toks[0]}},
+			{"date": bson.M{"$lt": toks[1]}},
+		}
+	} else {
+		info := ptr.GetHatchetInfo()
+		substr = GetMongoDateSubString(info.Start, info.End)
+	}
+	group := bson.M{
+		"_id":                 substr,
+		"total_ms":            bson.M{"$sum": "$milli"},
+		"cpu_nanos":           bson.M{"$sum": "$cpu_nanos"},
+		"time_reading_micros": bson.M{"$sum": "$time_reading_micros"},
+		"lock_wait_micros":    bson.M{"$sum": "$lock_wait_micros"},
+		"flow_control_micros": bson.M{"$sum": "$flow_control_micros"},
+		"remote_wait_ms":      bson.M{"$sum": "$remote_wait_ms"},
+	}
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	cursor, err := ptr.db.Collection(ptr.hatchetName).Aggregate(ctx, []bson.M{
+		{"$match": opcond},
+		{"$group": group},
+		{"$sort": bson.M{"_id": 1}},
+	}, opts)
+	if err != nil {
+		return docs, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var stat OpStat
+		if err := cursor.Decode(&stat); err != nil {
+			return docs, err
+		}
+		date, _ := cursor.Current.Lookup("_id").StringValueOK()
+		docs = append(docs, GetTimeBreakdownMetrics(date, stat)...)
+	}
+	return docs, cursor.Err()
+}
+
+// GetErrorRates returns counts of errors by names over a period of time, of a marker of merged
+// logs or of all markers if empty
+func (ptr *MongoDB) GetErrorRates(duration string, marker string) ([]Metric, error) {
+	docs := []Metric{}
+	var substr bson.M
+	ctx := context.Background()
+	match := bson.M{}
+	if marker != "" {
+		match["marker"] = ToInt(marker)
+	}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		substr = GetMongoDateSubString(toks[0], toks[1])
+		match["$and"] = []bson.M{
+			{"date": bson.M{"$gte": toks[0]}},
+			{"date": bson.M{"$lt": toks[1]}},
+		}
+	} else {
+		info := ptr.GetHatchetInfo()
+		substr = GetMongoDateSubString(info.Start, info.End)
+	}
+	cursor, err := ptr.db.Collection(ptr.hatchetName+"_errors").Aggregate(ctx, []bson.M{
+		{"$match": match},
+		{"$group": bson.M{"_id": bson.M{"date": substr, "name": "$name"}, "value": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "_id.date", Value: 1}, {Key: "_id.name", Value: 1}}},
+		{"$project": bson.M{"_id": 0, "date": "$_id.date", "type": bson.M{"$literal": "errors"}, "name": "$_id.name", "value": 1}},
+	})
+	if err != nil {
+		return docs, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
+// GetStorageMetrics returns average op time, the longest checkpoint running time and counts of
+// eviction warnings over a period of time, of the same time axis as GetAverageOpTime
+func (ptr *MongoDB) GetStorageMetrics(duration string) ([]Metric, error) {
+	docs := []Metric{}
+	var substr bson.M
+	ctx := context.Background()
+	match := bson.M{}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		substr = GetMongoDateSubString(toks[0], toks[1])
+		match["$and"] = []bson.M{
+			{"date": bson.M{"$gte": toks[0]}},
+			{"date": bson.M{"$lt": toks[1]}},
+		}
+	} else {
+		info := ptr.GetHatchetInfo()
+		substr = GetMongoDateSubString(info.Start, info.End)
+	}
+	opMatch := bson.M{"op": bson.M{"$nin": []interface{}{nil, ""}}}
+	checkpointMatch := bson.M{"type": STORAGE_CHECKPOINT, "milli": bson.M{"$gt": 0}}
+	evictionMatch := bson.M{"type": STORAGE_EVICTION}
+	for k, v := range match {
+		opMatch[k] = v
+		checkpointMatch[k] = v
+		evictionMatch[k] = v
+	}
+	cursor, err := ptr.db.Collection(ptr.hatchetName).Aggregate(ctx, []bson.M{
+		{"$match": opMatch},
+		{"$group": bson.M{"_id": substr, "value": bson.M{"$avg": "$milli"}}},
+		{"$project": bson.M{"_id": 0, "date": "$_id", "name": bson.M{"$literal": SERIES_AVG_OP_TIME},
+			"value": bson.M{"$round": []interface{}{bson.M{"$divide": []interface{}{"$value", 1000}}, 3}}}},
+		{"$unionWith": bson.M{"coll": ptr.hatchetName + "_storage", "pipeline": []bson.M{
+			{"$match": checkpointMatch},
+			{"$group": bson.M{"_id": substr, "value": bson.M{"$max": "$milli"}}},
+			{"$project": bson.M{"_id": 0, "date": "$_id", "name": bson.M{"$literal": SERIES_CHECKPOINT},
+				"value": bson.M{"$divide": []interface{}{"$value", 1000}}}},
+		}}},
+		{"$unionWith": bson.M{"coll": ptr.hatchetName + "_storage", "pipeline": []bson.M{
+			{"$match": evictionMatch},
+			{"$group": bson.M{"_id": substr, "value": bson.M{"$sum": 1}}},
+			{"$project": bson.M{"_id": 0, "date": "$_id", "name": bson.M{"$literal": SERIES_EVICTION}, "value": 1}},
+		}}},
+		{"$sort": bson.M{"date": 1}},
+		{"$addFields": bson.M{"type": bson.M{"$literal": "storage"}}},
+	})
+	if err != nil {
+		return docs, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
+// GetReplEvents returns replica set events of a type, or of all types if empty, of a marker or of
+// all markers if empty, ordered by date and marker so that events of members of merged logs line up
+func (ptr *MongoDB) GetReplEvents(eventType string, duration string, marker string) ([]ReplEvent, error) {
+	docs := []ReplEvent{}
+	ctx := context.Background()
+	filter := bson.M{}
+	if eventType != "" {
+		filter["type"] = eventType
+	}
+	if marker != "" {
+		filter["marker"] = ToInt(marker)
+	}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		filter["$and"] = []bson.M{
+			{"date": bson.M{"$gte": toks[0]}},
+			{"date": bson.M{"$lt": toks[1]}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "marker", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := ptr.db.Collection(ptr.hatchetName+"_events").Find(ctx, filter, opts)
+	if err != nil {
+		return docs, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
+// GetProcessLifetimes returns process lifetimes of each marker segmented by restarts, with their
+// shutdowns, fatal errors and unclean shutdowns
+func (ptr *MongoDB) GetProcessLifetimes() ([]ProcessLifetime, error) {
+	spans := []ProcessLifetime{}
+	ctx := context.Background()
+	collection := ptr.db.Collection(ptr.hatchetName)
+	cursor, err := collection.Aggregate(ctx, []bson.M{
+		{"$group": bson.M{"_id": bson.M{"$ifNull": []interface{}{"$marker", 0}},
+			"start": bson.M{"$min": "$date"}, "end": bson.M{"$max": "$date"}}},
+		{"$sort": bson.M{"_id": 1}},
+		{"$project": bson.M{"_id": 0, "marker": "$_id", "start": 1, "end": 1}},
+	})
+	if err != nil {
+		return spans, err
+	}
+	for cursor.Next(ctx) {
+		var doc struct {
+			End    string `bson:"end"`
+			Marker int    `bson:"marker"`
+			Start  string `bson:"start"`
+		}
+		if err = cursor.Decode(&doc); err != nil {
+			cursor.Close(ctx)
+			return spans, err
+		}
+		spans = append(spans, ProcessLifetime{End: doc.End, Marker: doc.Marker, Start: doc.Start})
+	}
+	cursor.Close(ctx)
+
+	events := []ReplEvent{}
+	opts := options.Find().SetSort(bson.D{{Key: "marker", Value: 1}, {Key: "date", Value: 1}, {Key: "_id", Value: 1}})
+	filter := bson.M{"type": bson.M{"$in": PROCESS_EVENT_TYPES}}
+	if cursor, err = ptr.db.Collection(ptr.hatchetName+"_events").Find(ctx, filter, opts); err != nil {
+		return spans, err
+	}
+	if err = cursor.All(ctx, &events); err != nil {
+		return spans, err
+	}
+	priors := make([]string, len(events))
+	for i, event := range events {
+		if event.Type != EVENT_RESTART {
+			continue
+		}
+		var doc struct {
+			Date string `bson:"date"`
+		}
+		filter := bson.M{"marker": event.Marker, "date": bson.M{"$lt": event.Date}, "context": bson.M{"$ne": "main"}}
+		opts := options.FindOne().SetSort(bson.M{"date": -1}).SetProjection(bson.M{"date": 1})
+		if err = collection.FindOne(ctx, filter, opts).Decode(&doc); err == nil {
+			priors[i] = doc.Date
+		} else if err != mongo.ErrNoDocuments {
+			return spans, err
+		}
+	}
+	return SegmentLifetimes(spans, events, priors), nil
+}
+
+// GetMetrics returns averaged FTDC metrics of a chart type over a period of time
+func (ptr *MongoDB) GetMetrics(chartType string, duration string) ([]Metric, error) {
+	var docs []Metric
+	var substr bson.M
+	ctx := context.Background()
+	match := bson.M{"type": chartType}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		substr = GetMongoDateSubString(toks[0], toks[1])
+		match["$and"] = []bson.M{
+			{"date": bson.M{"$gte": toks[0]}},
+			{"date": bson.M{"$lt": toks[1]}},
+		}
+	} else { // span of the metrics, which may be beyond the span of logs
+		var span struct {
+			Start string `bson:"start"`
+			End   string `bson:"end"`
+		}
+		cursor, err := ptr.db.Collection(ptr.hatchetName+"_metrics").Aggregate(ctx, []bson.M{
+			{"$match": match},
+			{"$group": bson.M{"_id": nil, "start": bson.M{"$min": "$date"}, "end": bson.M{"$max": "$date"}}},
+		})
+		if err != nil {
+			return docs, err
+		}
+		if cursor.Next(ctx) {
+			err = cursor.Decode(&span)
+		}
+		cursor.Close(ctx)
+		if err != nil {
+			return docs, err
+		}
+		substr = GetMongoDateSubString(span.Start, span.End)
+	}
+	group := bson.M{
+		"_id":   bson.M{"date": substr, "name": "$name"},
+		"value": bson.M{"$avg": "$value"},
+	}
+	project := bson.M{"_id": 0, "date": "$_id.date", "type": chartType, "name": "$_id.name", "value": "$value"}
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	cursor, err := ptr.db.Collection(ptr.hatchetName+"_metrics").Aggregate(ctx, []bson.M{
+		{"$match": match},
+		{"$group": group},
+		{"$project": project},
+		{"$sort": bson.M{"date": 1}},
+	}, opts)
+	if err != nil {
+		return docs, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
 func (ptr *MongoDB) GetHatchetInfo() HatchetInfo {
 	ctx := context.Background()
 	var info HatchetInfo
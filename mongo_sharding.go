@@ -0,0 +1,101 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * mongo_sharding.go
+ */
+
+package hatchet
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetShardingStats returns chunk migrations by collections and donor/recipient pairs, stale
+// config errors, range deletions, balancer rounds, migration decisions and resharding progress
+func (ptr *MongoDB) GetShardingStats() (*ShardingStats, error) {
+	stats := &ShardingStats{}
+	ctx := context.Background()
+	collection := ptr.db.Collection(ptr.hatchetName + "_migrations")
+
+	cur, err := collection.Aggregate(ctx, []bson.M{
+		{"$group": bson.M{"_id": "$ns", "count": bson.M{"$sum": "$count"}, "failed": bson.M{"$sum": "$failed"},
+			"max_ms": bson.M{"$max": "$max_ms"}, "total_ms": bson.M{"$sum": "$total_ms"}}},
+		{"$sort": bson.M{"total_ms": -1}},
+		{"$limit": TOP_N},
+		{"$project": bson.M{"_id": 0, "ns": "$_id", "count": 1, "failed": 1, "max_ms": 1, "total_ms": 1,
+			"avg_ms": bson.M{"$round": []interface{}{bson.M{"$divide": []interface{}{"$total_ms", "$count"}}, 1}}}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	if err = cur.All(ctx, &stats.Collections); err != nil {
+		return stats, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"total_ms": -1}).SetLimit(TOP_N)
+	if cur, err = collection.Find(ctx, bson.M{}, opts); err != nil {
+		return stats, err
+	}
+	if err = cur.All(ctx, &stats.Pairs); err != nil {
+		return stats, err
+	}
+
+	opts = options.Find().SetSort(bson.D{{Key: "type", Value: 1}, {Key: "value", Value: -1}})
+	filter := bson.M{"type": bson.M{"$in": []string{SHARDING_BALANCER, SHARDING_DECISION, SHARDING_RANGE_DELETION}}}
+	if cur, err = ptr.db.Collection(ptr.hatchetName+"_audit").Find(ctx, filter, opts); err != nil {
+		return stats, err
+	}
+	for cur.Next(ctx) {
+		var m bson.M
+		if err = cur.Decode(&m); err != nil {
+			cur.Close(ctx)
+			return stats, err
+		}
+		doc := NameValue{Value: ToInt(m["value"])}
+		doc.Name, _ = m["name"].(string)
+		if m["type"] == SHARDING_BALANCER {
+			stats.Balancer = append(stats.Balancer, doc)
+		} else if m["type"] == SHARDING_DECISION {
+			stats.Decisions = append(stats.Decisions, doc)
+		} else {
+			stats.RangeDeletions = append(stats.RangeDeletions, doc)
+		}
+	}
+	cur.Close(ctx)
+
+	if cur, err = ptr.db.Collection(ptr.hatchetName+"_errors").Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"name": bson.M{"$in": STALE_CONFIG_ERRORS}}},
+		{"$group": bson.M{
+			"_id":   bson.M{"ns": "$ns", "minute": bson.M{"$substrBytes": []interface{}{"$date", 0, 16}}},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id.minute", Value: 1}}},
+		{"$group": bson.M{"_id": "$_id.ns", "count": bson.M{"$sum": "$count"}, "peak": bson.M{"$first": "$count"},
+			"peak_minute": bson.M{"$first": "$_id.minute"}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": TOP_N},
+	}); err != nil {
+		return stats, err
+	}
+	for cur.Next(ctx) {
+		var m bson.M
+		if err = cur.Decode(&m); err != nil {
+			cur.Close(ctx)
+			return stats, err
+		}
+		doc := StaleConfigStat{Count: ToInt(m["count"]), Peak: ToInt(m["peak"])}
+		doc.Namespace, _ = m["_id"].(string)
+		doc.PeakMinute, _ = m["peak_minute"].(string)
+		stats.StaleConfig = append(stats.StaleConfig, doc)
+	}
+	cur.Close(ctx)
+
+	opts = options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(LIMIT)
+	if cur, err = ptr.db.Collection(ptr.hatchetName+"_sharding").Find(ctx, bson.M{"type": SHARDING_RESHARDING}, opts); err != nil {
+		return stats, err
+	}
+	err = cur.All(ctx, &stats.Resharding)
+	return stats, err
+}
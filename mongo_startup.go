@@ -0,0 +1,40 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * mongo_startup.go
+ */
+
+package hatchet
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetStartupDigests returns process info, options, storage engine settings, startup warnings
+// and the feature compatibility version of each restart
+func (ptr *MongoDB) GetStartupDigests() ([]StartupDigest, error) {
+	digests := []StartupDigest{}
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "marker", Value: 1}, {Key: "id", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := ptr.db.Collection(ptr.hatchetName+"_startup").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return digests, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var doc struct {
+			Date   string `bson:"date"`
+			Marker int    `bson:"marker"`
+			Name   string `bson:"name"`
+			Type   string `bson:"type"`
+			Value  string `bson:"value"`
+		}
+		if err = cur.Decode(&doc); err != nil {
+			return digests, err
+		}
+		digests = addStartupSetting(digests, doc.Marker, doc.Date, doc.Type, StartupSetting{doc.Name, doc.Value})
+	}
+	return digests, cur.Err()
+}
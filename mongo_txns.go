@@ -0,0 +1,117 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * mongo_txns.go
+ */
+
+package hatchet
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTransactionStats returns abort reasons, long-running transactions, conflict hot-spot
+// namespaces and retry storms
+func (ptr *MongoDB) GetTransactionStats() (*TransactionStats, error) {
+	stats := &TransactionStats{}
+	ctx := context.Background()
+	collection := ptr.db.Collection(ptr.hatchetName + "_txns")
+
+	cur, err := collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"termination": bson.M{"$ne": ""}}},
+		{"$group": bson.M{"_id": "$termination", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	for cur.Next(ctx) {
+		var m bson.M
+		if err = cur.Decode(&m); err != nil {
+			cur.Close(ctx)
+			return stats, err
+		}
+		if m["_id"] == TXN_ABORTED {
+			stats.Aborted = ToInt(m["count"])
+		} else if m["_id"] == TXN_COMMITTED {
+			stats.Committed = ToInt(m["count"])
+		}
+	}
+	cur.Close(ctx)
+
+	if cur, err = collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"termination": TXN_ABORTED}},
+		{"$group": bson.M{"_id": "$reason", "value": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"value": -1}},
+		{"$limit": LIMIT},
+		{"$project": bson.M{"_id": 0, "name": "$_id", "value": 1}},
+	}); err != nil {
+		return stats, err
+	}
+	if err = cur.All(ctx, &stats.AbortReasons); err != nil {
+		return stats, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"milli": -1}).SetLimit(TOP_N)
+	if cur, err = collection.Find(ctx, bson.M{"termination": bson.M{"$ne": ""}}, opts); err != nil {
+		return stats, err
+	}
+	if err = cur.All(ctx, &stats.LongRunning); err != nil {
+		return stats, err
+	}
+
+	if cur, err = collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"ns": bson.M{"$ne": ""}, "$or": []bson.M{
+			{"write_conflicts": bson.M{"$gt": 0}}, {"prepare_conflict_ms": bson.M{"$gt": 0}}}}},
+		{"$group": bson.M{"_id": "$ns", "count": bson.M{"$sum": 1}, "write_conflicts": bson.M{"$sum": "$write_conflicts"},
+			"prepare_conflict_ms": bson.M{"$sum": "$prepare_conflict_ms"}, "total_ms": bson.M{"$sum": "$milli"}}},
+		{"$sort": bson.D{{Key: "write_conflicts", Value: -1}, {Key: "prepare_conflict_ms", Value: -1}}},
+		{"$limit": TOP_N},
+	}); err != nil {
+		return stats, err
+	}
+	for cur.Next(ctx) {
+		var m bson.M
+		if err = cur.Decode(&m); err != nil {
+			cur.Close(ctx)
+			return stats, err
+		}
+		ns, _ := m["_id"].(string)
+		stats.HotSpots = append(stats.HotSpots, TxnHotSpot{Count: ToInt(m["count"]), Namespace: ns,
+			PrepareConflictMillis: ToInt(m["prepare_conflict_ms"]), TotalMilli: ToInt(m["total_ms"]),
+			WriteConflicts: ToInt(m["write_conflicts"])})
+	}
+	cur.Close(ctx)
+
+	if cur, err = collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"lsid": bson.M{"$ne": ""}}},
+		{"$group": bson.M{
+			"_id":             bson.M{"lsid": "$lsid", "minute": bson.M{"$substrBytes": []interface{}{"$date", 0, 16}}},
+			"aborted":         bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{"$termination", TXN_ABORTED}}, 1, 0}}},
+			"count":           bson.M{"$sum": 1},
+			"txn_numbers":     bson.M{"$addToSet": "$txn_number"},
+			"write_conflicts": bson.M{"$sum": "$write_conflicts"},
+		}},
+		{"$match": bson.M{"aborted": bson.M{"$gte": RETRY_STORM_ABORTS}}},
+		{"$sort": bson.D{{Key: "aborted", Value: -1}, {Key: "_id.minute", Value: 1}}},
+		{"$limit": TOP_N},
+		{"$project": bson.M{"_id": 0, "lsid": "$_id.lsid", "minute": "$_id.minute", "count": 1, "aborted": 1,
+			"txn_numbers": bson.M{"$size": "$txn_numbers"}, "write_conflicts": 1}},
+	}); err != nil {
+		return stats, err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var m bson.M
+		if err = cur.Decode(&m); err != nil {
+			return stats, err
+		}
+		doc := RetryStorm{Aborted: ToInt(m["aborted"]), Count: ToInt(m["count"]),
+			TxnNumbers: ToInt(m["txn_numbers"]), WriteConflicts: ToInt(m["write_conflicts"])}
+		doc.LSID, _ = m["lsid"].(string)
+		doc.Minute, _ = m["minute"].(string)
+		stats.RetryStorms = append(stats.RetryStorms, doc)
+	}
+	return stats, cur.Err()
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * pipeline.go
+ */
+
+package hatchet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// blockingStages consume all input documents before outputting any
+var blockingStages = map[string]bool{"$bucket": true, "$bucketAuto": true, "$count": true, "$facet": true,
+	"$group": true, "$setWindowFields": true, "$sortByCount": true}
+
+// PipelineStage is a stage of the fingerprint of an aggregation pipeline
+type PipelineStage struct {
+	Blocking bool   `json:"blocking,omitempty"` // blocking stage, or $sort not using an index
+	From     string `json:"from,omitempty"`     // foreign collection of $lookup, $graphLookup and $unionWith, or output of $out and $merge
+	Shape    string `json:"shape,omitempty"`    // keys of $group, $sort, $lookup and $match
+	Stage    string `json:"stage"`
+}
+
+// PipelineStageStat stores ops of pipelines of a stage
+type PipelineStageStat struct {
+	Blocking   int    `json:"blocking"`  // number of ops of which the stage is blocking
+	Count      int    `json:"count"`     // number of ops
+	Patterns   int    `json:"patterns"`  // number of pipeline patterns
+	Stage      string `json:"stage"`     // e.g. $lookup
+	TotalMilli int    `json:"total_ms"`  // total milliseconds of ops
+	UsedDisk   int    `json:"used_disk"` // number of ops used disk
+}
+
+// GetPipelineStages returns the fingerprint of a pipeline. A $sort is blocking when the plan
+// has an in-memory SORT stage or it follows stages other than $match, which can't use indexes.
+// Stages of bson.D, e.g. of the raw command, keep the order of keys of $sort.
+func GetPipelineStages(pipeline []interface{}, hasSortStage bool) []PipelineStage {
+	stages := []PipelineStage{}
+	leading := true // only $match stages so far
+	for _, v := range pipeline {
+		stageMap := toMap(v)
+		if len(stageMap) != 1 {
+			continue
+		}
+		for name, spec := range stageMap {
+			stage := PipelineStage{Stage: name, Blocking: blockingStages[name]}
+			specMap := toMap(spec)
+			switch name {
+			case "$match":
+				if specMap != nil && !isRegex(specMap) {
+					stage.Shape = toShape(mapWalker.Walk(specMap))
+				}
+			case "$group":
+				if specMap != nil {
+					stage.Shape = toShape(map[string]interface{}{"_id": getGroupKey(specMap["_id"])})
+				}
+			case "$sort":
+				stage.Shape = getKeyShape(getRawValue(v, name, spec))
+				stage.Blocking = hasSortStage || !leading
+			case "$lookup", "$graphLookup":
+				if specMap != nil {
+					stage.From = getCollectionName(specMap["from"])
+					if specMap["localField"] != nil || specMap["foreignField"] != nil {
+						stage.Shape = fmt.Sprintf("{ localField:%v, foreignField:%v }", specMap["localField"], specMap["foreignField"])
+					} else if specMap["connectFromField"] != nil {
+						stage.Shape = fmt.Sprintf("{ connectFromField:%v, connectToField:%v }", specMap["connectFromField"], specMap["connectToField"])
+					}
+				}
+			case "$out", "$unionWith":
+				stage.From = getCollectionName(spec)
+			case "$merge":
+				if specMap != nil {
+					stage.From = getCollectionName(specMap["into"])
+				} else {
+					stage.From = getCollectionName(spec)
+				}
+			}
+			if name != "$match" {
+				leading = false
+			}
+			stages = append(stages, stage)
+		}
+	}
+	return stages
+}
+
+// GetPipelineShape returns the fingerprint of pipeline stages in JSON
+func GetPipelineShape(stages []PipelineStage) string {
+	if len(stages) == 0 {
+		return ""
+	}
+	buf, err := json.Marshal(stages)
+	if err != nil {
+		return ""
+	}
+	return string(buf)
+}
+
+// SetPipeline sets pipeline stages of an op from its fingerprint
+func SetPipeline(stat *OpStat) {
+	stat.Pipeline = nil
+	if stat.PipelineShape != "" {
+		json.Unmarshal([]byte(stat.PipelineShape), &stat.Pipeline)
+	}
+}
+
+// GetPipelineStageStats breaks down ops of pipelines by stages, sorted by total milliseconds
+func GetPipelineStageStats(ops []OpStat) []PipelineStageStat {
+	stats := map[string]*PipelineStageStat{}
+	for _, op := range ops {
+		blocking := map[string]bool{} // stages of the pipeline, blocking or not
+		for _, stage := range op.Pipeline {
+			blocking[stage.Stage] = blocking[stage.Stage] || stage.Blocking
+		}
+		for name, isBlocking := range blocking {
+			stat := stats[name]
+			if stat == nil {
+				stat = &PipelineStageStat{Stage: name}
+				stats[name] = stat
+			}
+			if isBlocking {
+				stat.Blocking += op.Count
+			}
+			stat.Count += op.Count
+			stat.Patterns++
+			stat.TotalMilli += op.TotalMilli
+			stat.UsedDisk += op.UsedDisk
+		}
+	}
+	list := []PipelineStageStat{}
+	for _, stat := range stats {
+		list = append(list, *stat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TotalMilli == list[j].TotalMilli {
+			return list[i].Stage < list[j].Stage
+		}
+		return list[i].TotalMilli > list[j].TotalMilli
+	})
+	return list
+}
+
+// getGroupKey returns the _id of $group with field paths kept and other values normalized
+func getGroupKey(id interface{}) interface{} {
+	if s, ok := id.(string); ok && strings.HasPrefix(s, "$") {
+		return s
+	} else if m := toMap(id); m != nil {
+		keys := map[string]interface{}{}
+		for k, v := range m {
+			keys[k] = getGroupKey(v)
+		}
+		return keys
+	} else if id == nil {
+		return nil
+	}
+	return 1
+}
+
+// getCollectionName returns a collection name, or db.coll of { db, coll }
+func getCollectionName(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	} else if m := toMap(v); m != nil {
+		if m["db"] != nil {
+			return fmt.Sprintf("%v.%v", m["db"], m["coll"])
+		}
+		return fmt.Sprintf("%v", m["coll"])
+	}
+	return ""
+}
+
+// toShape formats a document as query patterns, e.g. { _id:{ a:$a } }
+func toShape(doc interface{}) string {
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	str := reIn.ReplaceAllString(string(buf), `$1:[...]`)
+	str = reKey.ReplaceAllString(str, ` $1:`)
+	str = strings.ReplaceAll(str, `"`, "")
+	return strings.ReplaceAll(str, "}", " }")
+}
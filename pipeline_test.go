@@ -0,0 +1,98 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * pipeline_test.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const pipelineLog = `{"t":{"$date":"2024-03-18T10:00:0%d.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"sales.orders","command":{"aggregate":"orders","pipeline":[{"$match":{"status":"A","date":{"$gte":{"$date":"2024-01-01T00:00:00Z"}}}},{"$lookup":{"from":"customers","localField":"cust_id","foreignField":"_id","as":"customer"}},{"$unwind":"$customer"},{"$group":{"_id":{"region":"$customer.region"},"total":{"$sum":"$amount"}}},{"$sort":{"total":-1}},{"$unionWith":{"coll":"archived_orders","pipeline":[]}},{"$limit":%d}],"cursor":{},"$db":"sales"},"planSummary":"IXSCAN { status: 1 }","keysExamined":100,"docsExamined":100,"hasSortStage":%v,"usedDisk":%v,"nreturned":10,"reslen":1000,"durationMillis":%d}}`
+
+func TestGetPipelineStages(t *testing.T) {
+	stat, err := AnalyzeLog(fmt.Sprintf(pipelineLog, 0, 10, true, true, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.QueryPattern != `{ date:{ $gte:1 }, status:1 }` || stat.HasSortStage != 1 || stat.UsedDisk != 1 {
+		t.Fatalf("unexpected slow op %+v", stat)
+	}
+	expected := []PipelineStage{
+		{Stage: "$match", Shape: "{ date:{ $gte:1 }, status:1 }"},
+		{Stage: "$lookup", From: "customers", Shape: "{ localField:cust_id, foreignField:_id }"},
+		{Stage: "$unwind"},
+		{Stage: "$group", Shape: "{ _id:{ region:$customer.region } }", Blocking: true},
+		{Stage: "$sort", Shape: "{ total:-1 }", Blocking: true},
+		{Stage: "$unionWith", From: "archived_orders"},
+		{Stage: "$limit"},
+	}
+	if len(stat.Pipeline) != len(expected) {
+		t.Fatalf("expected %d stages, got %+v", len(expected), stat.Pipeline)
+	}
+	for i, stage := range stat.Pipeline {
+		if stage != expected[i] {
+			t.Fatalf("expected %+v, got %+v", expected[i], stage)
+		}
+	}
+
+	// keys of $sort are in order of the command
+	sortLog := `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"sales.orders","command":{"aggregate":"orders","pipeline":[{"$match":{"status":"A"}},{"$sort":%v}],"cursor":{},"$db":"sales"},"planSummary":"IXSCAN { status: 1 }","durationMillis":100}}`
+	descending, err := AnalyzeLog(fmt.Sprintf(sortLog, `{"b":-1,"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ascending, err := AnalyzeLog(fmt.Sprintf(sortLog, `{"a":1,"b":-1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if descending.Pipeline[1].Shape != "{ b:-1, a:1 }" || ascending.Pipeline[1].Shape != "{ a:1, b:-1 }" ||
+		descending.PipelineShape == ascending.PipelineShape {
+		t.Fatalf("expected fingerprints of sorts in order, got %v and %v", descending.PipelineShape, ascending.PipelineShape)
+	}
+
+	// a $sort following $match only uses an index without an in-memory SORT stage
+	pipeline := []interface{}{bson.M{"$match": bson.M{"a": 1}}, bson.M{"$sort": bson.M{"b": 1}},
+		bson.M{"$merge": bson.M{"into": bson.M{"db": "reports", "coll": "daily"}}}}
+	stages := GetPipelineStages(pipeline, false)
+	if len(stages) != 3 || stages[1].Blocking || stages[2].From != "reports.daily" {
+		t.Fatalf("unexpected stages %+v", stages)
+	}
+	if stages = GetPipelineStages(pipeline, true); !stages[1].Blocking {
+		t.Fatalf("expected a blocking $sort, got %+v", stages)
+	}
+}
+
+func TestGetSlowOpsPipelines(t *testing.T) {
+	var logs []string
+	for i := 0; i < 4; i++ { // same $match with 2 pipelines of different limits, normalized to one
+		logs = append(logs, fmt.Sprintf(pipelineLog, i, 10+i, i == 0, i < 2, 100*(i+1)))
+	}
+	dbase := analyzeTestLogs(t, "pipelines", logs)
+	ops, err := dbase.GetSlowOps("avg_ms", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Count != 4 || ops[0].UsedDisk != 2 || ops[0].HasSortStage != 1 || len(ops[0].Pipeline) != 7 {
+		t.Fatalf("expected a pipeline of 4 ops, got %+v", ops)
+	}
+	stages := GetPipelineStageStats(ops)
+	if len(stages) != 7 || stages[0].TotalMilli != 1000 || stages[0].Count != 4 {
+		t.Fatalf("unexpected stages %+v", stages)
+	}
+	for _, stage := range stages {
+		if (stage.Stage == "$group" || stage.Stage == "$sort") != (stage.Blocking == 4) || stage.UsedDisk != 2 {
+			t.Fatalf("unexpected stage %+v", stage)
+		}
+	}
+	templ, err := GetStatsTableTemplate(false, "avg_ms", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "pipelines", "Ops": ops, "Stages": stages, "Summary": "", "Version": ""}
+	executeTestTemplate(t, templ, doc)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * process.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	EVENT_FATAL    = "fatal"
+	EVENT_RESTART  = "restart"
+	EVENT_SHUTDOWN = "shutdown"
+	EVENT_UNCLEAN  = "unclean-shutdown"
+
+	LIFETIME_CRASHED  = "crashed"
+	LIFETIME_RUNNING  = "running"
+	LIFETIME_SHUTDOWN = "shutdown"
+	LIFETIME_UNCLEAN  = "unclean"
+)
+
+// PROCESS_EVENT_TYPES are types of events of process restarts, shutdowns and crashes
+var PROCESS_EVENT_TYPES = []string{EVENT_FATAL, EVENT_RESTART, EVENT_SHUTDOWN, EVENT_UNCLEAN}
+
+// EVENT_TYPES are types of replica set and process events of the events table
+var EVENT_TYPES = append(append([]string{}, REPL_EVENT_TYPES...), PROCESS_EVENT_TYPES...)
+
+// lower-cased prefixes of shutdown messages of logv2 and legacy logs
+var shutdownPrefixes = []string{"got signal", "now exiting", "received signal", "shutting down with code",
+	"terminating via shutdown command"}
+
+// lower-cased prefixes of fatal messages, of which backtraces may not be of F severity
+var fatalPrefixes = []string{"backtrace", "fatal assertion", "invariant failure", "writing fatal message"}
+
+// attributes of process events shown as details
+var processEventDetails = []string{"pid", "port", "host", "dbPath", "exitCode", "signal", "error", "msgid",
+	"file", "line", "expr"}
+
+// ProcessLifetime is the span of a process from a restart, or from the beginning of a log, to
+// the next restart or the end of the log
+type ProcessLifetime struct {
+	Detail   string `json:"detail"`   // pid, port and host of the restart
+	Duration string `json:"duration"` // start,end to filter charts, logs, events and stats with the marker
+	End      string `json:"end"`
+	Fatals   int    `json:"fatals"`
+	Marker   int    `json:"marker"`
+	Start    string `json:"start"`
+	Status   string `json:"status"` // running, shutdown, crashed or unclean
+}
+
+// GetProcessEvent returns a restart, a shutdown, a fatal error or a detected unclean shutdown
+// of a log line, or nil if it is none of them
+func GetProcessEvent(doc *Logv2Info) *ReplEvent {
+	event := &ReplEvent{Component: doc.Component, Context: doc.Context, Name: doc.Msg, Marker: doc.Marker}
+	msg := strings.ToLower(doc.Msg)
+	if doc.Msg == "MongoDB starting" {
+		event.Type = EVENT_RESTART
+	} else if doc.Severity == "F" || hasAnyPrefix(msg, fatalPrefixes) {
+		event.Type = EVENT_FATAL
+	} else if strings.Contains(msg, "unclean shutdown") {
+		event.Type = EVENT_UNCLEAN
+	} else if msg == "shutting down" || hasAnyPrefix(msg, shutdownPrefixes) {
+		event.Type = EVENT_SHUTDOWN
+	} else {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	details := []string{}
+	for _, key := range processEventDetails {
+		if v, ok := attrMap[key]; ok && v != nil && v != "" {
+			details = append(details, fmt.Sprintf("%v: %v", key, toShape(v)))
+		}
+	}
+	event.Detail = strings.Join(details, ", ")
+	return event
+}
+
+// hasAnyPrefix returns true if a string begins with any of prefixes
+func hasAnyPrefix(str string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(str, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentLifetimes segments spans of logs of markers into process lifetimes by process events
+// ordered by markers and dates.  Of each restart, prior is the date of the last log line before it,
+// other than of the main thread initializing the process, which ends the previous lifetime.
+func SegmentLifetimes(spans []ProcessLifetime, events []ReplEvent, priors []string) []ProcessLifetime {
+	lifetimes := []ProcessLifetime{}
+	for _, span := range spans {
+		current := ProcessLifetime{Marker: span.Marker, Start: span.Start, Status: LIFETIME_RUNNING}
+		previous := -1 // index of the previous lifetime of the same marker
+		for i, event := range events {
+			if event.Marker != span.Marker {
+				continue
+			}
+			switch event.Type {
+			case EVENT_RESTART:
+				if priors[i] != "" { // not the beginning of the log
+					current.End = priors[i]
+					if current.Status == LIFETIME_RUNNING { // no shutdown logged
+						current.Status = LIFETIME_UNCLEAN
+					}
+					lifetimes = append(lifetimes, current)
+					previous = len(lifetimes) - 1
+				}
+				current = ProcessLifetime{Detail: event.Detail, Marker: span.Marker, Start: event.Date,
+					Status: LIFETIME_RUNNING}
+			case EVENT_FATAL:
+				current.Fatals++
+				current.Status = LIFETIME_CRASHED
+			case EVENT_SHUTDOWN:
+				if current.Status != LIFETIME_CRASHED {
+					current.Status = LIFETIME_SHUTDOWN
+				}
+			case EVENT_UNCLEAN: // detected after a restart, of the previous lifetime
+				if previous >= 0 && lifetimes[previous].Status != LIFETIME_CRASHED {
+					lifetimes[previous].Status = LIFETIME_UNCLEAN
+				}
+			}
+		}
+		current.End = span.End
+		lifetimes = append(lifetimes, current)
+	}
+	for i := range lifetimes {
+		lifetimes[i].Duration = lifetimes[i].Start + "," + lifetimes[i].End
+	}
+	return lifetimes
+}
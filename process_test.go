@@ -0,0 +1,153 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * process_test.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var processLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":100}}`,
+	`{"t":{"$date":"2024-03-18T10:01:00.000+00:00"},"s":"I","c":"CONTROL","id":23377,"ctx":"SignalHandler","msg":"Received signal","attr":{"signal":15,"error":"Terminated"}}`,
+	`{"t":{"$date":"2024-03-18T10:01:01.000+00:00"},"s":"I","c":"CONTROL","id":23138,"ctx":"SignalHandler","msg":"Shutting down","attr":{"exitCode":0}}`,
+	`{"t":{"$date":"2024-03-18T10:01:02.000+00:00"},"s":"I","c":"CONTROL","id":20565,"ctx":"SignalHandler","msg":"Now exiting"}`,
+	`{"t":{"$date":"2024-03-18T10:02:00.000+00:00"},"s":"I","c":"CONTROL","id":23285,"ctx":"main","msg":"Automatically disabling TLS 1.0, to force-enable TLS 1.0 specify --sslDisabledProtocols 'none'"}`,
+	`{"t":{"$date":"2024-03-18T10:02:01.000+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":2,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"node1"}}`,
+	`{"t":{"$date":"2024-03-18T10:03:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn2","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":200}}`,
+	`{"t":{"$date":"2024-03-18T10:04:00.000+00:00"},"s":"F","c":"ASSERT","id":23089,"ctx":"conn5","msg":"Fatal assertion","attr":{"msgid":40507,"file":"src/mongo/db/repl/oplog.cpp","line":123}}`,
+	`{"t":{"$date":"2024-03-18T10:04:00.100+00:00"},"s":"I","c":"CONTROL","id":31380,"ctx":"conn5","msg":"BACKTRACE","attr":{"bt":{"backtrace":[{"a":"55A1","b":"55A0","o":"1"}]}}}`,
+	`{"t":{"$date":"2024-03-18T10:05:00.000+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":3,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"node1"}}`,
+	`{"t":{"$date":"2024-03-18T10:05:01.000+00:00"},"s":"W","c":"STORAGE","id":22271,"ctx":"initandlisten","msg":"Detected unclean shutdown - Lock file is not empty","attr":{"lockFile":"/data/db/mongod.lock"}}`,
+	`{"t":{"$date":"2024-03-18T10:06:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":300}}`,
+	`{"t":{"$date":"2024-03-18T10:07:00.000+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":4,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"node1"}}`,
+	`{"t":{"$date":"2024-03-18T10:08:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":400}}`,
+}
+
+func TestGetProcessEvent(t *testing.T) {
+	types := []string{"", EVENT_SHUTDOWN, EVENT_SHUTDOWN, EVENT_SHUTDOWN, "", EVENT_RESTART, "", EVENT_FATAL, EVENT_FATAL,
+		EVENT_RESTART, EVENT_UNCLEAN, "", EVENT_RESTART, ""}
+	for i, str := range processLogs {
+		doc := Logv2Info{}
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		event := GetProcessEvent(&doc)
+		if types[i] == "" && event != nil {
+			t.Fatalf("expected no event of %v, got %+v", doc.Msg, event)
+		} else if types[i] != "" && (event == nil || event.Type != types[i]) {
+			t.Fatalf("expected %v event of line %d, got %+v", types[i], i+1, event)
+		}
+	}
+	doc := Logv2Info{}
+	line := `2019-07-25T09:55:00.000+0000 I  CONTROL  [signalProcessingThread] got signal 15 (Terminated), will terminate after current cmd ends`
+	if err := ParseTextLog(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if event := GetProcessEvent(&doc); event == nil || event.Type != EVENT_SHUTDOWN {
+		t.Fatalf("expected a shutdown of a legacy text log, got %+v", event)
+	}
+}
+
+func TestGetProcessLifetimes(t *testing.T) {
+	// merged logs of 2 members of the same lifetimes
+	dbase := analyzeTestLogs(t, "process", processLogs, processLogs)
+	lifetimes, err := dbase.GetProcessLifetimes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lifetimes) != 8 {
+		t.Fatalf("expected a lifetime of each restart of each member, got %+v", lifetimes)
+	}
+	statuses := []string{LIFETIME_SHUTDOWN, LIFETIME_CRASHED, LIFETIME_UNCLEAN, LIFETIME_RUNNING}
+	ends := []string{"2024-03-18T10:01:02.000-0000", "2024-03-18T10:04:00.100-0000", "2024-03-18T10:06:00.000-0000",
+		"2024-03-18T10:08:00.000-0000"}
+	for i, lifetime := range lifetimes {
+		if lifetime.Status != statuses[i%4] || lifetime.End != ends[i%4] || lifetime.Marker != lifetimes[i/4*4].Marker {
+			t.Fatalf("expected %v lifetime ended at %v, got %+v", statuses[i%4], ends[i%4], lifetime)
+		}
+	}
+	if lifetimes[0].Start != "2024-03-18T10:00:00.000-0000" || lifetimes[1].Fatals != 2 ||
+		!strings.HasPrefix(lifetimes[1].Detail, "pid: 2") || lifetimes[0].Marker == lifetimes[4].Marker ||
+		lifetimes[2].Duration != "2024-03-18T10:05:00.000-0000,2024-03-18T10:06:00.000-0000" {
+		t.Fatalf("unexpected process lifetimes %+v", lifetimes)
+	}
+
+	// of a lifetime, of its member only
+	lifetime := lifetimes[2]
+	marker := fmt.Sprintf("%v", lifetime.Marker)
+	ops, err := dbase.GetAverageOpTime("", lifetime.Duration, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Count != 1 {
+		t.Fatalf("expected slow ops of a lifetime only, got %+v", ops)
+	}
+	if ops, err = dbase.GetAverageOpTime("", lifetime.Duration, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Count != 2 {
+		t.Fatalf("expected slow ops of both members, got %+v", ops)
+	}
+	stats, err := dbase.GetSlowOps("avg_ms", "DESC", false, "", "duration="+lifetime.Duration, "marker="+marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Count != 1 || stats[0].MaxMilli != 300 || stats[0].P50Milli != 300 {
+		t.Fatalf("expected slow ops stats of a lifetime only, got %+v", stats)
+	}
+	if stats, err = dbase.GetSlowOps("avg_ms", "DESC", false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Count != 8 {
+		t.Fatalf("expected slow ops stats of the hatchet, got %+v", stats)
+	}
+	events, err := dbase.GetReplEvents("", lifetime.Duration, marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != EVENT_RESTART || events[1].Type != EVENT_UNCLEAN {
+		t.Fatalf("expected a restart and an unclean shutdown of a lifetime, got %+v", events)
+	}
+	logs, err := dbase.GetLogs("duration="+lifetime.Duration, "marker="+marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("expected logs of a lifetime only, got %+v", logs)
+	}
+	for _, duration := range []string{"2024-03-18T10:05:00.000-0000", "2024-03-18' OR '1'='1,2024-03-18"} {
+		if _, err = dbase.GetSlowOps("avg_ms", "DESC", false, "", "duration="+duration); err == nil {
+			t.Fatalf("expected an error of slow ops of duration %v", duration)
+		}
+		if _, err = dbase.GetLogs("duration=" + duration); err == nil {
+			t.Fatalf("expected an error of logs of duration %v", duration)
+		}
+		if _, err = dbase.CountLogs("duration=" + duration); err == nil {
+			t.Fatalf("expected an error of counting logs of duration %v", duration)
+		}
+	}
+
+	if events, err = dbase.GetReplEvents("", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	counts, markers := GetReplEventSummary(events)
+	templ, err := GetEventsTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "process", "Merge": true, "Events": events, "Counts": counts,
+		"Markers": markers, "Type": "", "Types": EVENT_TYPES, "Duration": "", "Summary": "", "Lifetimes": lifetimes,
+		"Download": "", "Version": "test"}
+	html := executeTestTemplate(t, templ, doc)
+	if !strings.Contains(html, "Process Lifetimes") || !strings.Contains(html, LIFETIME_CRASHED) ||
+		!strings.Contains(html, "slow ops stats") {
+		t.Fatalf("expected process lifetimes in the events template")
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * repl_events.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	EVENT_ELECTION    = "election"
+	EVENT_OPLOG       = "oplog"
+	EVENT_ROLLBACK    = "rollback"
+	EVENT_STATE       = "state"
+	EVENT_STEPDOWN    = "stepdown"
+	EVENT_SYNC_SOURCE = "sync-source"
+)
+
+// REPL_EVENT_TYPES are types of replica set events
+var REPL_EVENT_TYPES = []string{EVENT_ELECTION, EVENT_OPLOG, EVENT_ROLLBACK, EVENT_STATE, EVENT_STEPDOWN, EVENT_SYNC_SOURCE}
+
+// replEventPrefixes maps lower-cased prefixes of REPL and ELECTION messages to event types
+var replEventPrefixes = map[string]string{
+	"conducting a dry run election":  EVENT_ELECTION,
+	"dry election run succeeded":     EVENT_ELECTION,
+	"election succeeded":             EVENT_ELECTION,
+	"lost election":                  EVENT_ELECTION,
+	"not becoming primary":           EVENT_ELECTION,
+	"not running for primary":        EVENT_ELECTION,
+	"scheduling catchup takeover":    EVENT_ELECTION,
+	"scheduling priority takeover":   EVENT_ELECTION,
+	"starting an election":           EVENT_ELECTION,
+	"starting election":              EVENT_ELECTION,
+	"transition to primary complete": EVENT_ELECTION,
+
+	"rollback complete": EVENT_ROLLBACK,
+	"rollback finished": EVENT_ROLLBACK,
+	"rollback summary":  EVENT_ROLLBACK,
+	"rollback using":    EVENT_ROLLBACK,
+	"rolling back":      EVENT_ROLLBACK,
+	"starting rollback": EVENT_ROLLBACK,
+
+	"attempting to step down":    EVENT_STEPDOWN,
+	"stepping down from primary": EVENT_STEPDOWN,
+	"stepdown":                   EVENT_STEPDOWN,
+
+	"changed sync source":          EVENT_SYNC_SOURCE,
+	"choosing new sync source":     EVENT_SYNC_SOURCE,
+	"clearing sync source":         EVENT_SYNC_SOURCE,
+	"sync source candidate chosen": EVENT_SYNC_SOURCE,
+}
+
+// attributes of replica set events shown as details
+var replEventDetails = []string{"term", "newTerm", "reason", "syncSource", "newSyncSource", "oldSyncSource",
+	"candidate", "primary", "error", "status", "rollbackId", "commonPoint", "lastApplied"}
+
+// ReplEvent stores a replica set event, e.g. a state transition, an election or a rollback
+type ReplEvent struct {
+	Component string `json:"component" bson:"component"`
+	Context   string `json:"context" bson:"context"`
+	Date      string `json:"date" bson:"date"`
+	Detail    string `json:"detail" bson:"detail"` // e.g. term, reason or sync source
+	FromState string `json:"from_state" bson:"from_state"`
+	Name      string `json:"name" bson:"name"` // log message
+	ToState   string `json:"to_state" bson:"to_state"`
+	Type      string `json:"type" bson:"type"` // e.g. election, state or rollback
+
+	Marker int `json:"marker" bson:"marker"`
+}
+
+// GetReplEvent returns a replica set event of a REPL, ELECTION or ROLLBACK log line, or nil
+// if it is none of state transitions, elections, stepdowns, rollbacks, sync source changes
+// and oplog warnings
+func GetReplEvent(doc *Logv2Info) *ReplEvent {
+	c := doc.Component
+	if c != "REPL" && c != "ELECTION" && c != "ROLLBACK" {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	event := &ReplEvent{Component: c, Context: doc.Context, Name: doc.Msg, Marker: doc.Marker}
+	msg := strings.ToLower(doc.Msg)
+	if msg == "replica set state transition" {
+		event.Type = EVENT_STATE
+		event.FromState, _ = attrMap["oldState"].(string)
+		event.ToState, _ = attrMap["newState"].(string)
+		return event
+	} else if strings.HasPrefix(msg, "transition to ") { // legacy, transition to PRIMARY from SECONDARY
+		event.Type = EVENT_STATE
+		toks := strings.Fields(doc.Msg)
+		if len(toks) >= 5 && toks[3] == "from" {
+			event.FromState = toks[4]
+			event.ToState = toks[2]
+			return event
+		}
+	}
+	for prefix, eventType := range replEventPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			event.Type = eventType
+			break
+		}
+	}
+	if event.Type == "" {
+		if c == "ROLLBACK" {
+			event.Type = EVENT_ROLLBACK
+		} else if strings.Contains(msg, "too stale") ||
+			(strings.Contains(msg, "oplog") && (doc.Severity == "W" || doc.Severity == "E")) {
+			event.Type = EVENT_OPLOG
+		} else {
+			return nil
+		}
+	}
+	details := []string{}
+	for _, key := range replEventDetails {
+		if v, ok := attrMap[key]; ok && v != nil && v != "" {
+			details = append(details, fmt.Sprintf("%v: %v", key, toShape(v)))
+		}
+	}
+	event.Detail = strings.Join(details, ", ")
+	return event
+}
+
+// GetReplEventSummary returns counts of events by types and distinct markers of members of
+// merged logs of which events are found
+func GetReplEventSummary(events []ReplEvent) ([]NameValue, []int) {
+	counts := map[string]int{}
+	markers := map[int]bool{}
+	for _, event := range events {
+		counts[event.Type]++
+		markers[event.Marker] = true
+	}
+	docs := []NameValue{}
+	for _, eventType := range EVENT_TYPES {
+		if counts[eventType] > 0 {
+			docs = append(docs, NameValue{Name: eventType, Value: counts[eventType]})
+		}
+	}
+	list := []int{}
+	for marker := range markers {
+		list = append(list, marker)
+	}
+	sort.Ints(list)
+	return docs, list
+}
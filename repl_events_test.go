@@ -0,0 +1,91 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * repl_events_test.go
+ */
+
+package hatchet
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var primaryLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"ELECTION","id":4615652,"ctx":"ReplCoord-1","msg":"Starting an election, since we've seen no PRIMARY in election timeout period","attr":{"electionTimeoutPeriodMillis":10000}}`,
+	`{"t":{"$date":"2024-03-18T10:00:01.000+00:00"},"s":"I","c":"ELECTION","id":21450,"ctx":"ReplCoord-2","msg":"Election succeeded, assuming primary role","attr":{"term":5}}`,
+	`{"t":{"$date":"2024-03-18T10:00:01.100+00:00"},"s":"I","c":"REPL","id":21358,"ctx":"ReplCoord-2","msg":"Replica set state transition","attr":{"newState":"PRIMARY","oldState":"SECONDARY"}}`,
+	`{"t":{"$date":"2024-03-18T10:00:02.000+00:00"},"s":"I","c":"ELECTION","id":5972100,"ctx":"conn9","msg":"Received vote request","attr":{"term":5}}`,
+	`{"t":{"$date":"2024-03-18T10:05:00.000+00:00"},"s":"I","c":"REPL","id":21402,"ctx":"conn10","msg":"Stepping down from primary, stats","attr":{"userOpsKilled":0,"userOpsRunning":3}}`,
+	`{"t":{"$date":"2024-03-18T10:05:00.100+00:00"},"s":"I","c":"REPL","id":21358,"ctx":"conn10","msg":"Replica set state transition","attr":{"newState":"SECONDARY","oldState":"PRIMARY"}}`,
+}
+
+var secondaryLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:01.200+00:00"},"s":"I","c":"REPL","id":21799,"ctx":"BackgroundSync","msg":"Sync source candidate chosen","attr":{"syncSource":"node1:27017"}}`,
+	`{"t":{"$date":"2024-03-18T10:05:02.000+00:00"},"s":"I","c":"ROLLBACK","id":21593,"ctx":"BackgroundSync","msg":"Rollback using 'recoverToStableTimestamp' method"}`,
+	`{"t":{"$date":"2024-03-18T10:06:00.000+00:00"},"s":"W","c":"REPL","id":21799,"ctx":"BackgroundSync","msg":"We are too stale to use candidate as a sync source","attr":{"candidate":"node1:27017"}}`,
+}
+
+func TestGetReplEvent(t *testing.T) {
+	types := []string{EVENT_ELECTION, EVENT_ELECTION, EVENT_STATE, "", EVENT_STEPDOWN, EVENT_STATE}
+	for i, str := range primaryLogs {
+		doc := Logv2Info{}
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		event := GetReplEvent(&doc)
+		if types[i] == "" && event != nil {
+			t.Fatalf("expected no event of %v, got %+v", doc.Msg, event)
+		} else if types[i] != "" && (event == nil || event.Type != types[i]) {
+			t.Fatalf("expected %v event of %v, got %+v", types[i], doc.Msg, event)
+		}
+	}
+	doc := Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(primaryLogs[2]), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if event := GetReplEvent(&doc); event.FromState != "SECONDARY" || event.ToState != "PRIMARY" {
+		t.Fatalf("unexpected state transition %+v", event)
+	}
+	doc = Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(secondaryLogs[0]), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if event := GetReplEvent(&doc); event.Type != EVENT_SYNC_SOURCE || event.Detail != "syncSource: node1:27017" {
+		t.Fatalf("unexpected sync source change %+v", event)
+	}
+}
+
+func TestGetReplEvents(t *testing.T) {
+	dbase := analyzeTestLogs(t, "rs_events", primaryLogs, secondaryLogs)
+	events, err := dbase.GetReplEvents("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 8 || events[2].Type != EVENT_STATE || events[2].Marker != 1 ||
+		events[3].Type != EVENT_SYNC_SOURCE || events[3].Marker != 2 || events[7].Type != EVENT_OPLOG {
+		t.Fatalf("expected events of members lined up by dates, got %+v", events)
+	}
+	counts, markers := GetReplEventSummary(events)
+	if len(counts) != 6 || counts[0].Name != EVENT_ELECTION || counts[0].Value != 2 || len(markers) != 2 {
+		t.Fatalf("unexpected summary of events %v %v", counts, markers)
+	}
+	if events, err = dbase.GetReplEvents(EVENT_STATE, "2024-03-18T10:00:00,2024-03-18T10:01:00", ""); err != nil {
+		t.Fatal(err)
+	} else if len(events) != 1 || events[0].ToState != "PRIMARY" {
+		t.Fatalf("unexpected state transitions %+v", events)
+	}
+
+	templ, err := GetEventsTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, _ = dbase.GetReplEvents("", "", "")
+	doc := map[string]interface{}{"Hatchet": "rs_events", "Merge": true, "Events": events, "Counts": counts,
+		"Markers": markers, "Type": "", "Types": REPL_EVENT_TYPES, "Duration": "", "Summary": "", "Version": "test"}
+	html := executeTestTemplate(t, templ, doc)
+	if !strings.Contains(html, "SECONDARY &rarr; PRIMARY") {
+		t.Fatalf("expected state transitions in the events template")
+	}
+}
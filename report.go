@@ -56,6 +56,10 @@ func generateAuditReport(dbase Database, hatchetName string, info HatchetInfo, s
 	if err != nil {
 		return err
 	}
+	startups, err := dbase.GetStartupDigests()
+	if err != nil {
+		return err
+	}
 
 	templ, err := GetAuditTablesTemplate("true") // download mode for standalone HTML
 	if err != nil {
@@ -63,11 +67,12 @@ func generateAuditReport(dbase Database, hatchetName string, info HatchetInfo, s
 	}
 
 	doc := map[string]interface{}{
-		"Hatchet": hatchetName,
-		"Info":    info,
-		"Summary": summary,
-		"Data":    data,
-		"Version": version,
+		"Hatchet":  hatchetName,
+		"Info":     info,
+		"Summary":  summary,
+		"Data":     data,
+		"Startups": startups,
+		"Version":  version,
 	}
 
 	var buf bytes.Buffer
@@ -85,12 +90,12 @@ func generateAuditReport(dbase Database, hatchetName string, info HatchetInfo, s
 
 func generateStatsReport(dbase Database, hatchetName string, info HatchetInfo, summary string, version string) error {
 	// Get slow ops sorted by avg_ms DESC
-	ops, err := dbase.GetSlowOps("avg_ms", "DESC", false)
+	ops, err := dbase.GetSlowOps("avg_ms", "DESC", false, "")
 	if err != nil {
 		return err
 	}
 
-	templ, err := GetStatsTableTemplate(false, "avg_ms", "true") // download mode
+	templ, err := GetStatsTableTemplate(false, "avg_ms", "", "true") // download mode
 	if err != nil {
 		return err
 	}
@@ -99,6 +104,7 @@ func generateStatsReport(dbase Database, hatchetName string, info HatchetInfo, s
 		"Hatchet": hatchetName,
 		"Merge":   info.Merge,
 		"Ops":     ops,
+		"Stages":  GetPipelineStageStats(ops),
 		"Summary": summary,
 		"Version": version,
 	}
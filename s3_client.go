@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -19,6 +20,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
+const S3_SNIFF_SIZE = 64 * 1024 // bytes read to detect MongoDB logs
+
 // S3Client provides methods to interact with an S3 service.
 type S3Client struct {
 	service *s3.S3
@@ -100,9 +103,17 @@ func (c *S3Client) PutObject(bucket, key, filePath string) error {
 
 // GetObject retrieves an object from S3 and returns its contents as a byte slice.
 func (c *S3Client) GetObject(logname string) ([]byte, error) {
-	toks := strings.Split(logname, "/")
-	bucket := toks[0]
-	key := strings.Join(toks[1:], "/")
+	body, err := c.GetObjectReader(logname)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// GetObjectReader retrieves an object from S3 and returns its body to be streamed.
+func (c *S3Client) GetObjectReader(logname string) (io.ReadCloser, error) {
+	bucket, key := ParseS3Name(logname)
 	resp, err := c.service.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
@@ -110,6 +121,101 @@ func (c *S3Client) GetObject(logname string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving S3 object: %v", err)
 	}
+	return resp.Body, nil
+}
+
+// ListObjects lists keys of objects under a prefix, page by page.
+func (c *S3Client) ListObjects(bucket, prefix string) ([]string, error) {
+	var keys []string
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	err := c.service.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if key := aws.StringValue(object.Key); !strings.HasSuffix(key, "/") {
+				keys = append(keys, key)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing S3 objects: %v", err)
+	}
+	return keys, nil
+}
+
+// IsMongoDBLog checks if an object is a MongoDB log by reading its first kilobytes.
+func (c *S3Client) IsMongoDBLog(logname string) bool {
+	bucket, key := ParseS3Name(logname)
+	resp, err := c.service.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", S3_SNIFF_SIZE-1)),
+	})
+	if err != nil {
+		return false
+	}
 	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
+	reader, err := NewStreamReader(resp.Body)
+	if err != nil {
+		return false
+	}
+	return isMongoDBLogReader(reader)
+}
+
+// ParseS3Name returns the bucket and key of bucket/key or s3://bucket/key.
+func ParseS3Name(logname string) (string, string) {
+	toks := strings.SplitN(strings.TrimPrefix(logname, "s3://"), "/", 2)
+	if len(toks) == 1 {
+		return toks[0], ""
+	}
+	return toks[0], toks[1]
+}
+
+// IsS3Prefix checks if a name is a bucket or a folder, e.g. s3://bucket/case-123/
+func IsS3Prefix(logname string) bool {
+	_, key := ParseS3Name(logname)
+	return key == "" || strings.HasSuffix(key, "/")
+}
+
+// analyzeS3Prefix analyzes MongoDB logs of objects under a prefix, each as its
+// own hatchet, e.g. case_123_mongod for case-123/mongod.log, or all into one
+// hatchet when merging
+func (ptr *Logv2) analyzeS3Prefix(logname string, marker int) error {
+	bucket, prefix := ParseS3Name(logname)
+	keys, err := ptr.s3client.ListObjects(bucket, prefix)
+	if err != nil {
+		return err
+	}
+	existingNames, _ := GetExistingHatchetNames()
+	fileCount := 0
+	for _, key := range keys {
+		if !ptr.isIncluded(key) {
+			continue
+		}
+		name := bucket + "/" + key
+		if !ptr.s3client.IsMongoDBLog(name) {
+			log.Printf("skipping %s (not a MongoDB log)", name)
+			continue
+		}
+		fileCount++
+		if ptr.merge {
+			if err = ptr.Analyze(name, marker); err != nil {
+				return err
+			}
+			continue
+		}
+		hatchetName := getUniqueHatchetName(key, existingNames)
+		existingNames = append(existingNames, hatchetName)
+		ptr.analyzeFile(name, hatchetName)
+	}
+	if fileCount == 0 {
+		log.Printf("no MongoDB log files found in %s", logname)
+	}
+	if !ptr.merge {
+		// Clear hatchetName so caller knows not to print summary again
+		ptr.hatchetName = ""
+	}
+	return nil
 }
@@ -7,9 +7,21 @@ package hatchet
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 )
 
 const (
@@ -79,3 +91,110 @@ func TestNewS3Client(t *testing.T) {
 		t.Fatalf("failed to delete S3 bucket: %v", err)
 	}
 }
+
+// newS3Server stands in for S3 with objects of a bucket, listed 2 keys a page
+func newS3Server(bucket string, objects map[string][]byte) *httptest.Server {
+	var keys []string
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+bucket || r.URL.Path == "/"+bucket+"/" {
+			prefix := r.URL.Query().Get("prefix")
+			var matched []string
+			for _, key := range keys {
+				if strings.HasPrefix(key, prefix) {
+					matched = append(matched, key)
+				}
+			}
+			from, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+			to := min(from+2, len(matched))
+			fmt.Fprintf(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>%v</Name><Prefix>%v</Prefix><KeyCount>%d</KeyCount>`,
+				bucket, prefix, to-from)
+			if to < len(matched) {
+				fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", to)
+			} else {
+				fmt.Fprint(w, "<IsTruncated>false</IsTruncated>")
+			}
+			for _, key := range matched[from:to] {
+				fmt.Fprintf(w, "<Contents><Key>%v</Key><Size>%d</Size></Contents>", key, len(objects[key]))
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+			return
+		}
+		data, ok := objects[strings.TrimPrefix(r.URL.Path, "/"+bucket+"/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+func TestAnalyzeS3Prefix(t *testing.T) {
+	useTestDatabase(t)
+	var logs []string
+	for i := 0; i < 3; i++ {
+		logs = append(logs, fmt.Sprintf(`{"t":{"$date":"2024-03-18T10:0%d:00.000+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"10.0.0.1:5000","connectionCount":%d}}`, i, i+1))
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(strings.Join(logs, "\n") + "\n"))
+	zw.Close()
+	server := newS3Server(testBucket, map[string][]byte{
+		"case-123/mongod.log":        []byte(strings.Join(logs[:2], "\n") + "\n"),
+		"case-123/notes.txt":         []byte("not a log\n"),
+		"case-123/rs1/mongod.log.gz": gz.Bytes(),
+		"case-456/mongod.log":        []byte(logs[0] + "\n"),
+	})
+	defer server.Close()
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(testAccessKey, testSecretKey, ""),
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String(testRegion),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3client := &S3Client{service: s3.New(sess)}
+	keys, err := s3client.ListObjects(testBucket, "case-123/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("expected 3 keys of 2 pages, got %v", keys)
+	}
+	if !IsS3Prefix("s3://"+testBucket+"/case-123/") || IsS3Prefix(testBucket+"/case-123/mongod.log") {
+		t.Fatal("unexpected prefix detection")
+	}
+
+	logv2 := &Logv2{testing: true, url: instance.url, to: time.Now(), s3client: s3client}
+	if err = logv2.Analyze("s3://"+testBucket+"/case-123/", 1); err != nil {
+		t.Fatal(err)
+	}
+	names, err := GetExistingHatchetNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "case_123_mongod" || names[1] != "rs1_mongod" {
+		t.Fatalf("expected a hatchet of each log, got %v", names)
+	}
+	for i, name := range names {
+		dbase, err := NewSQLite3DB(instance.url, name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count int
+		err = dbase.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %v", name)).Scan(&count)
+		dbase.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2+i {
+			t.Fatalf("expected %d logs of %v, got %d", 2+i, name, count)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * sharding.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	SHARDING_BALANCER       = "balancer"
+	SHARDING_DECISION       = "migration-decision"
+	SHARDING_MIGRATION      = "migration"
+	SHARDING_RANGE_DELETION = "range-deletion"
+	SHARDING_RESHARDING     = "resharding"
+
+	MIGRATION_FAILED    = "failed"
+	MIGRATION_SUCCEEDED = "succeeded"
+)
+
+// STALE_CONFIG_ERRORS are names of errors of stale routing tables retried by mongos
+var STALE_CONFIG_ERRORS = []string{"StaleConfig", "StaleDbVersion", "StaleEpoch", "StaleShardVersion"}
+
+// commands of chunk migrations of donor shards
+var migrationCommands = []string{"moveChunk", "_shardsvrMoveRange"}
+
+// lower-cased prefixes of balancer and range deletion messages
+var balancerPrefixes = []string{"balancer move", "end balancing round", "end of balancing round", "start balancing round"}
+var rangeDeletionPrefixes = []string{"finished deleting documents in range", "finished deleting range"}
+
+// ShardEvent stores a chunk migration, a range deletion, a balancer round or a resharding
+// progress of a log line
+type ShardEvent struct {
+	Date      string `json:"date" bson:"date"`
+	Detail    string `json:"detail" bson:"detail"` // e.g. error name, numDeleted or resharding state
+	Donor     string `json:"donor" bson:"donor"`   // fromShard
+	Milli     int    `json:"milli" bson:"milli"`
+	Name      string `json:"name" bson:"name"` // command or log message
+	Namespace string `json:"ns" bson:"ns"`
+	Recipient string `json:"recipient" bson:"recipient"` // toShard
+	Status    string `json:"status" bson:"status"`       // succeeded or failed of migrations, decision of coordinators
+	Type      string `json:"type" bson:"type"`           // e.g. migration, range-deletion or balancer
+
+	Marker int `json:"-" bson:"marker"`
+}
+
+// MigrationStat stores chunk migrations of a collection, or of a donor and recipient pair
+type MigrationStat struct {
+	AvgMilli   float64 `json:"avg_ms" bson:"avg_ms"`
+	Count      int     `json:"count" bson:"count"`
+	Donor      string  `json:"donor" bson:"donor"`
+	Failed     int     `json:"failed" bson:"failed"`
+	MaxMilli   int     `json:"max_ms" bson:"max_ms"`
+	Namespace  string  `json:"ns" bson:"ns"`
+	Recipient  string  `json:"recipient" bson:"recipient"`
+	TotalMilli int     `json:"total_ms" bson:"total_ms"`
+}
+
+// StaleConfigStat stores stale config errors of a namespace and the minute of the most of them
+type StaleConfigStat struct {
+	Count      int    `json:"count"`
+	Namespace  string `json:"ns"`
+	Peak       int    `json:"peak"` // errors within the peak minute
+	PeakMinute string `json:"peak_minute"`
+}
+
+// ShardingStats is the sharding and balancer report of a hatchet
+type ShardingStats struct {
+	Balancer       []NameValue       `json:"balancer"` // balancer messages
+	Collections    []MigrationStat   `json:"collections"`
+	Decisions      []NameValue       `json:"migration_decisions"`
+	Pairs          []MigrationStat   `json:"donor_recipient_pairs"`
+	RangeDeletions []NameValue       `json:"range_deletions"`
+	Resharding     []ShardEvent      `json:"resharding"`
+	StaleConfig    []StaleConfigStat `json:"stale_config"`
+}
+
+// GetShardEvent returns a sharding event of a log line, or nil if it is none of chunk
+// migrations, migration coordinator decisions, range deletions, balancer rounds and
+// resharding progress
+func GetShardEvent(doc *Logv2Info) *ShardEvent {
+	c := doc.Component
+	if c != "SHARDING" && c != "MIGRATE" && c != "RESHARD" && !hasAnyKey(doc.Attr, []string{"command"}) {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	event := &ShardEvent{Marker: doc.Marker, Milli: ToInt(attrMap["durationMillis"]), Name: doc.Msg}
+	event.Namespace, _ = attrMap["namespace"].(string)
+	if event.Namespace == "" {
+		event.Namespace, _ = attrMap["ns"].(string)
+	}
+	msg := strings.ToLower(doc.Msg)
+	if command, ok := attrMap["command"].(bson.M); ok {
+		for _, name := range migrationCommands {
+			ns, ok := command[name].(string)
+			if !ok || command["toShard"] == nil {
+				continue
+			}
+			event.Type = SHARDING_MIGRATION
+			event.Name = name
+			event.Namespace = ns
+			event.Donor, _ = command["fromShard"].(string)
+			event.Recipient, _ = command["toShard"].(string)
+			event.Status = MIGRATION_SUCCEEDED
+			if errName, ok := attrMap["errName"].(string); ok {
+				event.Status = MIGRATION_FAILED
+				event.Detail = errName
+			} else if errMsg, ok := attrMap["errMsg"].(string); ok {
+				event.Status = MIGRATION_FAILED
+				event.Detail = errMsg
+			}
+			return event
+		}
+		return nil
+	} else if doc.Component == "MIGRATE" && attrMap["decision"] != nil {
+		event.Type = SHARDING_DECISION
+		event.Status = fmt.Sprintf("%v", attrMap["decision"])
+		return event
+	} else if doc.Component == "RESHARD" {
+		event.Type = SHARDING_RESHARDING
+		for _, key := range []string{"state", "newState", "coordinatorState"} {
+			if state, ok := attrMap[key].(string); ok {
+				event.Detail = state
+				break
+			}
+		}
+		return event
+	} else if doc.Component != "SHARDING" && doc.Component != "MIGRATE" {
+		return nil
+	}
+	for _, prefix := range rangeDeletionPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			event.Type = SHARDING_RANGE_DELETION
+			if attrMap["numDeleted"] != nil {
+				event.Detail = fmt.Sprintf("numDeleted: %v", attrMap["numDeleted"])
+			}
+			return event
+		}
+	}
+	for _, prefix := range balancerPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			event.Type = SHARDING_BALANCER
+			return event
+		}
+	}
+	return nil
+}
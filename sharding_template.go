@@ -0,0 +1,165 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * sharding_template.go
+ */
+
+package hatchet
+
+import (
+	"html/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// GetShardingTemplate returns HTML of the sharding and balancer report
+func GetShardingTemplate(download string) (*template.Template, error) {
+	html := headers
+	if download == "" {
+		html = getContentHTML()
+	}
+	html += `
+<script>
+	function downloadSharding() {
+		anchor = document.createElement('a');
+		anchor.download = '{{.Hatchet}}_sharding.html';
+		anchor.href = '/hatchets/{{.Hatchet}}/stats/sharding?download=true';
+		anchor.dataset.downloadurl = ['text/html', anchor.download, anchor.href].join(':');
+		anchor.click();
+	}
+</script>
+<div align='left'>`
+	if download == "" {
+		html += `
+<!-- Header Bar -->
+<div style='display: flex; justify-content: space-between; align-items: center; margin-bottom: 15px;'>
+	<h2 style='margin: 0; color: #444; font-size: 1.4em;'><i class='fa fa-th-large' style='color: #1565c0;'></i> Sharding</h2>
+	<button id="download" onClick="downloadSharding(); return false;"
+		class="download-btn"><i class="fa fa-download"></i> Download</button>
+</div>`
+	} else {
+		html += "<div align='center'>{{.Summary}}</div>"
+	}
+	html += `
+{{ $stats := .Stats }}
+<h3>Chunk Migrations by Collections</h3>
+{{ if $stats.Collections }}
+	<table>
+		<tr><th>#</th><th>namespace</th><th>migrations</th><th>failed</th><th>avg ms</th><th>max ms</th><th>total ms</th></tr>
+	{{ range $n, $value := $stats.Collections }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td class='break'>{{ $value.Namespace }}</td>
+			<td align='right'>{{ numPrinter $value.Count }}</td>
+			<td align='right'>{{ if $value.Failed }}<span style='color:red;'>{{ numPrinter $value.Failed }}</span>{{ else }}0{{ end }}</td>
+			<td align='right'>{{ $value.AvgMilli }}</td>
+			<td align='right'>{{ numPrinter $value.MaxMilli }}</td>
+			<td align='right'>{{ numPrinter $value.TotalMilli }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No chunk migration is found.</p>
+{{ end }}
+
+{{ if $stats.Pairs }}
+<h3>Donors and Recipients</h3>
+	<table>
+		<tr><th>#</th><th>namespace</th><th>donor</th><th></th><th>recipient</th><th>migrations</th><th>failed</th>
+			<th>avg ms</th><th>max ms</th><th>total ms</th></tr>
+	{{ range $n, $value := $stats.Pairs }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td class='break'>{{ $value.Namespace }}</td>
+			<td>{{ $value.Donor }}</td>
+			<td>&rarr;</td>
+			<td>{{ $value.Recipient }}</td>
+			<td align='right'>{{ numPrinter $value.Count }}</td>
+			<td align='right'>{{ if $value.Failed }}<span style='color:red;'>{{ numPrinter $value.Failed }}</span>{{ else }}0{{ end }}</td>
+			<td align='right'>{{ $value.AvgMilli }}</td>
+			<td align='right'>{{ numPrinter $value.MaxMilli }}</td>
+			<td align='right'>{{ numPrinter $value.TotalMilli }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ end }}
+
+{{ if $stats.Decisions }}
+<p>Migration coordinator decisions:
+	{{ range $n, $value := $stats.Decisions }}{{ if $n }}, {{ end }}{{ numPrinter $value.Value }} {{ $value.Name }}{{ end }}.</p>
+{{ end }}
+
+<h3>Stale Config Errors</h3>
+<p>Errors of {{ .StaleConfigErrors }} retried by mongos after chunk migrations and routing table changes.
+	See the <a href='/hatchets/{{.Hatchet}}/charts/errors?type=rate'>Error Rate</a> chart for errors over time.</p>
+{{ if $stats.StaleConfig }}
+	<table>
+		<tr><th>#</th><th>namespace</th><th>errors</th><th>peak per minute</th><th>peak minute</th></tr>
+	{{ range $n, $value := $stats.StaleConfig }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td class='break'>{{ if $value.Namespace }}{{ $value.Namespace }}{{ else }}-{{ end }}</td>
+			<td align='right'>{{ numPrinter $value.Count }}</td>
+			<td align='right'>{{ numPrinter $value.Peak }}</td>
+			<td>{{ $value.PeakMinute }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No stale config error is found.</p>
+{{ end }}
+
+<h3>Range Deletions</h3>
+{{ if $stats.RangeDeletions }}
+	<table>
+		<tr><th>#</th><th>namespace</th><th>deletions</th></tr>
+	{{ range $n, $value := $stats.RangeDeletions }}
+		<tr><td align='right'>{{ add $n 1 }}</td><td class='break'>{{ $value.Name }}</td><td align='right'>{{ numPrinter $value.Value }}</td></tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No range deletion is found.</p>
+{{ end }}
+
+<h3>Balancer</h3>
+{{ if $stats.Balancer }}
+	<table>
+		<tr><th>#</th><th>message</th><th>count</th></tr>
+	{{ range $n, $value := $stats.Balancer }}
+		<tr><td align='right'>{{ add $n 1 }}</td><td class='break'>{{ $value.Name }}</td><td align='right'>{{ numPrinter $value.Value }}</td></tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No balancer round is logged.</p>
+{{ end }}
+
+{{ if $stats.Resharding }}
+<h3>Resharding</h3>
+	<table width='100%'>
+		<tr><th>#</th><th>date</th><th>namespace</th><th>message</th><th>state</th></tr>
+	{{ range $n, $value := $stats.Resharding }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td style='white-space: nowrap;'>{{ $value.Date }}</td>
+			<td class='break'>{{ $value.Namespace }}</td>
+			<td class='break'>{{ $value.Name }}</td>
+			<td>{{ $value.Detail }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ end }}
+	<div align='center'><hr/><p/>{{.Version}}</div>
+</div>`
+	if download == "" {
+		html += "</div><!-- end content-container -->"
+	}
+	html += "</body></html>"
+	return template.New("hatchet").Funcs(template.FuncMap{
+		"add": func(a int, b int) int {
+			return a + b
+		},
+		"numPrinter": func(n interface{}) string {
+			printer := message.NewPrinter(language.English)
+			return printer.Sprintf("%v", ToInt(n))
+		}}).Parse(html)
+}
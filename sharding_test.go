@@ -0,0 +1,98 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * sharding_test.go
+ */
+
+package hatchet
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var shardingLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"SHARDING","id":21856,"ctx":"Balancer","msg":"Start balancing round","attr":{"waitForDelete":false,"secondaryThrottle":{}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:05.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"admin.$cmd","command":{"moveChunk":"test.users","configdb":"cfg/c1:27019","fromShard":"shard01","toShard":"shard02","min":{"_id":0},"max":{"_id":100},"$db":"admin"},"numYields":0,"reslen":333,"durationMillis":1500}}`,
+	`{"t":{"$date":"2024-03-18T10:00:09.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"admin.$cmd","command":{"moveChunk":"test.users","configdb":"cfg/c1:27019","fromShard":"shard01","toShard":"shard02","min":{"_id":100},"max":{"_id":200},"$db":"admin"},"numYields":0,"ok":0,"errMsg":"chunk too big to move","errName":"ChunkTooBig","errCode":1,"reslen":333,"durationMillis":500}}`,
+	`{"t":{"$date":"2024-03-18T10:00:10.000+00:00"},"s":"I","c":"MIGRATE","id":23894,"ctx":"MoveChunk","msg":"MigrationCoordinator setting migration decision","attr":{"decision":"aborted","migrationId":{"uuid":"1"}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:11.000+00:00"},"s":"I","c":"SHARDING","id":21990,"ctx":"range-deleter","msg":"Finished deleting documents in range","attr":{"namespace":"test.users","range":"[{ _id: 0 }, { _id: 100 })","numDeleted":100}}`,
+	`{"t":{"$date":"2024-03-18T10:00:12.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn20","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"ok":0,"errName":"StaleConfig","errCode":13388,"reslen":200,"durationMillis":120}}`,
+	`{"t":{"$date":"2024-03-18T10:00:13.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn21","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"ok":0,"errName":"StaleConfig","errCode":13388,"reslen":200,"durationMillis":110}}`,
+	`{"t":{"$date":"2024-03-18T10:02:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn21","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"ok":0,"errName":"StaleConfig","errCode":13388,"reslen":200,"durationMillis":110}}`,
+	`{"t":{"$date":"2024-03-18T10:03:00.000+00:00"},"s":"I","c":"RESHARD","id":5343001,"ctx":"ReshardingCoordinator","msg":"Transitioned resharding coordinator state","attr":{"newState":"cloning","namespace":"test.orders"}}`,
+	`{"t":{"$date":"2024-03-18T10:04:00.000+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"10.0.0.1:50000","connectionCount":1}}`,
+}
+
+func TestGetShardEvent(t *testing.T) {
+	types := []string{SHARDING_BALANCER, SHARDING_MIGRATION, SHARDING_MIGRATION, SHARDING_DECISION,
+		SHARDING_RANGE_DELETION, "", "", "", SHARDING_RESHARDING, ""}
+	for i, str := range shardingLogs {
+		doc := Logv2Info{}
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		event := GetShardEvent(&doc)
+		if types[i] == "" && event != nil {
+			t.Fatalf("expected no event of %v, got %+v", doc.Msg, event)
+		} else if types[i] != "" && (event == nil || event.Type != types[i]) {
+			t.Fatalf("expected %v event of %v, got %+v", types[i], doc.Msg, event)
+		}
+	}
+	doc := Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(shardingLogs[2]), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	event := GetShardEvent(&doc)
+	if event.Namespace != "test.users" || event.Donor != "shard01" || event.Recipient != "shard02" ||
+		event.Milli != 500 || event.Status != MIGRATION_FAILED || event.Detail != "ChunkTooBig" {
+		t.Fatalf("unexpected migration %+v", event)
+	}
+	doc = Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(shardingLogs[8]), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if event = GetShardEvent(&doc); event.Namespace != "test.orders" || event.Detail != "cloning" {
+		t.Fatalf("unexpected resharding %+v", event)
+	}
+	if GetErrorCategory("StaleConfig") != ERROR_SHARDING {
+		t.Fatalf("expected StaleConfig of the %v category", ERROR_SHARDING)
+	}
+}
+
+func TestGetShardingStats(t *testing.T) {
+	dbase := analyzeTestLogs(t, "sharding", shardingLogs)
+	stats, err := dbase.GetShardingStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Collections) != 1 || stats.Collections[0].Count != 2 || stats.Collections[0].Failed != 1 ||
+		stats.Collections[0].AvgMilli != 1000 || stats.Collections[0].MaxMilli != 1500 {
+		t.Fatalf("unexpected migrations by collections %+v", stats.Collections)
+	}
+	if len(stats.Pairs) != 1 || stats.Pairs[0].Donor != "shard01" || stats.Pairs[0].Recipient != "shard02" ||
+		stats.Pairs[0].TotalMilli != 2000 {
+		t.Fatalf("unexpected donor/recipient pairs %+v", stats.Pairs)
+	}
+	if len(stats.StaleConfig) != 1 || stats.StaleConfig[0].Namespace != "test.users" || stats.StaleConfig[0].Count != 3 ||
+		stats.StaleConfig[0].Peak != 2 || stats.StaleConfig[0].PeakMinute != "2024-03-18T10:00" {
+		t.Fatalf("unexpected stale config errors %+v", stats.StaleConfig)
+	}
+	if len(stats.RangeDeletions) != 1 || stats.RangeDeletions[0].Name != "test.users" ||
+		len(stats.Balancer) != 1 || len(stats.Decisions) != 1 || stats.Decisions[0].Name != "aborted" ||
+		len(stats.Resharding) != 1 {
+		t.Fatalf("unexpected sharding stats %+v", stats)
+	}
+
+	templ, err := GetShardingTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "sharding", "StaleConfigErrors": strings.Join(STALE_CONFIG_ERRORS, ", "),
+		"Stats": stats, "Summary": "", "Version": "test"}
+	html := executeTestTemplate(t, templ, doc)
+	if !strings.Contains(html, "shard02") || !strings.Contains(html, "cloning") {
+		t.Fatalf("expected donor/recipient pairs and resharding states in the sharding template")
+	}
+}
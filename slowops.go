@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/simagix/gox"
@@ -98,6 +100,33 @@ func AnalyzeSlowOp(doc *Logv2Info) (*OpStat, error) {
 		stat.Index = "ErrMsg: " + doc.Attributes.ErrMsg
 	}
 	stat.Reslen = doc.Attributes.Reslen
+	stat.KeysExamined = doc.Attributes.KeysExamined
+	stat.DocsExamined = doc.Attributes.DocsExamined
+	stat.NReturned = doc.Attributes.NReturned
+	stat.NMatched = doc.Attributes.NMatched
+	stat.NModified = doc.Attributes.NModified
+	stat.NumYields = doc.Attributes.NumYields
+	stat.PlanCacheKey = doc.Attributes.PlanCacheKey
+	stat.QueryHash = doc.Attributes.QueryHash
+	stat.QueryShapeHash = doc.Attributes.QueryShapeHash
+	if doc.Attributes.HasSortStage {
+		stat.HasSortStage = 1
+	}
+	if doc.Attributes.UsedDisk {
+		stat.UsedDisk = 1
+	}
+	stat.BytesRead = doc.Attributes.BytesRead
+	stat.CPUNanos = doc.Attributes.CPUNanos
+	stat.FlowControlMicros = doc.Attributes.FlowControlMicros
+	stat.LockWaitMicros = doc.Attributes.LockWaitMicros
+	stat.RemoteOpWaitMillis = doc.Attributes.RemoteOpWaitMillis
+	stat.TimeReadingMicros = doc.Attributes.TimeReadingMicros
+	stat.KeysDeleted = doc.Attributes.KeysDeleted
+	stat.KeysInserted = doc.Attributes.KeysInserted
+	stat.NDeleted = doc.Attributes.NDeleted
+	stat.NInserted = doc.Attributes.NInserted
+	stat.NUpserted = doc.Attributes.NUpserted
+	SetQueryTargeting(stat)
 	if doc.Attributes.Command == nil {
 		return stat, errors.New("no command found")
 	}
@@ -107,13 +136,26 @@ func AnalyzeSlowOp(doc *Logv2Info) (*OpStat, error) {
 		stat.Op = getOp(command)
 	}
 	var isGetMore bool
+	raw := getRawValue(doc.Attr, "command", nil) // keeps the order of keys of sort
 	if stat.Op == cmdGetMore {
 		isGetMore = true
 		if doc.Attributes.OriginatingCommand != nil {
 			command = doc.Attributes.OriginatingCommand
+			raw = getRawValue(doc.Attr, "originatingCommand", nil)
 			stat.Op = getOp(command)
 		}
 	}
+	if stat.Op != cmdInsert && stat.Op != cmdCollstats && stat.Op != cmdCreateIndexes {
+		setQueryShapes(stat, command, raw)
+	}
+	if stat.Op == cmdUpdate || stat.Op == cmdDelete || stat.Op == cmdRemove || stat.Op == cmdFindAndModify {
+		setWriteShapes(stat, command)
+	} else if stat.Op == cmdInsert && stat.NInserted == 0 {
+		if documents, ok := command["documents"].([]interface{}); ok { // batch size of inserts
+			stat.NInserted = len(documents)
+		}
+	}
+	SetWriteAmplification(stat)
 	if stat.Op == cmdInsert || stat.Op == cmdCollstats {
 		stat.QueryPattern = ""
 	} else if stat.Op == cmdDistinct {
@@ -198,6 +240,12 @@ func AnalyzeSlowOp(doc *Logv2Info) (*OpStat, error) {
 		if len(pipeline) == 0 {
 			return stat, errors.New("pipeline not found")
 		}
+		rawPipeline, ok := getRawValue(raw, "pipeline", nil).(bson.A) // keeps the order of keys of $sort
+		if !ok || len(rawPipeline) != len(pipeline) {
+			rawPipeline = pipeline
+		}
+		stat.Pipeline = GetPipelineStages(rawPipeline, doc.Attributes.HasSortStage)
+		stat.PipelineShape = GetPipelineShape(stat.Pipeline)
 		// Find the first $match stage
 		var matchStage interface{}
 		for _, v := range pipeline {
@@ -283,12 +331,40 @@ func parseAttributes(doc *Logv2Info) {
 			} else if v, ok := elem.Value.(map[string]interface{}); ok {
 				doc.Attributes.Command = v
 			}
+		case "cpuNanos":
+			doc.Attributes.CPUNanos = toInt(elem.Value)
+		case "docsExamined":
+			doc.Attributes.DocsExamined = toInt(elem.Value)
 		case "errMsg":
 			if v, ok := elem.Value.(string); ok {
 				doc.Attributes.ErrMsg = v
 			}
 		case "durationMillis":
 			doc.Attributes.Milli = toInt(elem.Value)
+		case "flowControl":
+			doc.Attributes.FlowControlMicros = toInt(toMap(elem.Value)["timeAcquiringMicros"])
+		case "hasSortStage":
+			doc.Attributes.HasSortStage, _ = elem.Value.(bool)
+		case "keysDeleted":
+			doc.Attributes.KeysDeleted = toInt(elem.Value)
+		case "keysExamined":
+			doc.Attributes.KeysExamined = toInt(elem.Value)
+		case "keysInserted":
+			doc.Attributes.KeysInserted = toInt(elem.Value)
+		case "locks":
+			doc.Attributes.LockWaitMicros = getLockWaitMicros(elem.Value)
+		case "ndeleted":
+			doc.Attributes.NDeleted = toInt(elem.Value)
+		case "ninserted":
+			doc.Attributes.NInserted = toInt(elem.Value)
+		case "nMatched":
+			doc.Attributes.NMatched = toInt(elem.Value)
+		case "nModified":
+			doc.Attributes.NModified = toInt(elem.Value)
+		case "nreturned":
+			doc.Attributes.NReturned = toInt(elem.Value)
+		case "nUpserted":
+			doc.Attributes.NUpserted = toInt(elem.Value)
 		case "ns":
 			if v, ok := elem.Value.(string); ok {
 				doc.Attributes.NS = v
@@ -299,20 +375,335 @@ func parseAttributes(doc *Logv2Info) {
 			} else if v, ok := elem.Value.(map[string]interface{}); ok {
 				doc.Attributes.OriginatingCommand = v
 			}
+		case "numYields":
+			doc.Attributes.NumYields = toInt(elem.Value)
+		case "planCacheKey":
+			doc.Attributes.PlanCacheKey = toHash(elem.Value)
 		case "planSummary":
 			if v, ok := elem.Value.(string); ok {
 				doc.Attributes.PlanSummary = v
 			}
+		case "queryHash":
+			doc.Attributes.QueryHash = toHash(elem.Value)
+		case "queryShapeHash":
+			doc.Attributes.QueryShapeHash = toHash(elem.Value)
+		case "remoteOpWaitMillis":
+			doc.Attributes.RemoteOpWaitMillis = toInt(elem.Value)
 		case "reslen":
 			doc.Attributes.Reslen = toInt(elem.Value)
+		case "storage":
+			data := toMap(toMap(elem.Value)["data"])
+			doc.Attributes.BytesRead = toInt(data["bytesRead"])
+			doc.Attributes.TimeReadingMicros = toInt(data["timeReadingMicros"])
 		case "type":
 			if v, ok := elem.Value.(string); ok {
 				doc.Attributes.Type = v
 			}
+		case "usedDisk":
+			doc.Attributes.UsedDisk, _ = elem.Value.(bool)
+		}
+	}
+}
+
+// getLockWaitMicros returns the total timeAcquiringMicros of all locks and modes, e.g.
+// { Global: { timeAcquiringMicros: { r: 10, w: 20 } }, Collection: { ... } }
+func getLockWaitMicros(locks interface{}) int {
+	micros := 0
+	for _, lock := range toMap(locks) {
+		for _, v := range toMap(toMap(lock)["timeAcquiringMicros"]) {
+			micros += toInt(v)
+		}
+	}
+	return micros
+}
+
+// GetOpTimeBreakdown returns milliseconds of categories of TIME_BREAKDOWN spent by ops, of which
+// other is the remaining duration, including CPU time of logs without cpuNanos
+func GetOpTimeBreakdown(stat OpStat) []float64 {
+	waits := []float64{float64(stat.CPUNanos) / 1000000, float64(stat.TimeReadingMicros) / 1000,
+		float64(stat.LockWaitMicros) / 1000, float64(stat.FlowControlMicros) / 1000, float64(stat.RemoteOpWaitMillis)}
+	other := float64(stat.TotalMilli)
+	for i, v := range waits {
+		waits[i] = math.Round(10*v) / 10
+		other -= v
+	}
+	return append(waits, math.Max(0, math.Round(10*other)/10))
+}
+
+// GetTimeBreakdownMetrics returns seconds of TIME_BREAKDOWN spent by ops at a time as metrics
+func GetTimeBreakdownMetrics(date string, stat OpStat) []Metric {
+	metrics := []Metric{}
+	for i, milli := range GetOpTimeBreakdown(stat) {
+		metrics = append(metrics, Metric{Date: date, Type: "breakdown", Name: TIME_BREAKDOWN[i], Value: math.Round(milli) / 1000})
+	}
+	return metrics
+}
+
+// SetQueryTargeting sets examined per returned ratios, of documents matched by
+// writes, and flags poorly targeted query patterns
+func SetQueryTargeting(stat *OpStat) {
+	returned := stat.NReturned + stat.NMatched
+	if returned == 0 {
+		returned = 1
+	}
+	stat.KeysRatio = math.Round(10*float64(stat.KeysExamined)/float64(returned)) / 10
+	stat.DocsRatio = math.Round(10*float64(stat.DocsExamined)/float64(returned)) / 10
+	stat.PoorlyTargeted = stat.KeysRatio > QUERY_TARGETING_RATIO || stat.DocsRatio > QUERY_TARGETING_RATIO
+}
+
+// SetWriteAmplification sets documents written per op and index keys written per document,
+// and flags multi writes of too many documents and writes of too many index keys
+func SetWriteAmplification(stat *OpStat) {
+	count := stat.Count
+	if count == 0 {
+		count = 1
+	}
+	written := stat.NInserted + stat.NModified + stat.NDeleted + stat.NUpserted
+	stat.DocsPerWrite = math.Round(10*float64(written)/float64(count)) / 10
+	if written > 0 {
+		stat.KeysPerDoc = math.Round(10*float64(stat.KeysInserted+stat.KeysDeleted)/float64(written)) / 10
+	}
+	stat.UnboundedMulti = strings.Contains(stat.WriteFlags, "multi:1") && stat.DocsPerWrite > MULTI_WRITE_DOCS
+	stat.WriteAmplified = stat.KeysPerDoc > KEYS_PER_DOC_RATIO
+}
+
+// SetPlanFlip flags a Hatchet query pattern of several plan cache keys, or a
+// queryHash, planCacheKey or queryShapeHash of several plans when grouped by one
+func SetPlanFlip(stat *OpStat, groupBy string) {
+	if groupBy == "" {
+		stat.PlanFlip = strings.Contains(stat.PlanCacheKey, DISTINCT_SEPARATOR)
+	} else {
+		stat.PlanFlip = strings.Contains(stat.Index, DISTINCT_SEPARATOR)
+	}
+}
+
+// SetPercentiles sets p50, p95 and p99 milliseconds by nearest rank, the latency of rank
+// ceil(p*count/100), of histograms merged of all ops of the grouping
+func SetPercentiles(stat *OpStat) {
+	counts := map[int]int{}
+	total := 0
+	for _, bucket := range stat.Histogram {
+		counts[bucket.Milli] += bucket.Count
+		total += bucket.Count
+	}
+	millis := []int{}
+	for milli := range counts {
+		millis = append(millis, milli)
+	}
+	sort.Ints(millis)
+	percentiles := []*int{&stat.P50Milli, &stat.P95Milli, &stat.P99Milli}
+	ranks := []int{(50*total + 99) / 100, (95*total + 99) / 100, (99*total + 99) / 100}
+	n, i := 0, 0
+	for _, milli := range millis {
+		n += counts[milli]
+		for ; i < len(ranks) && n >= ranks[i]; i++ {
+			*percentiles[i] = milli
+		}
+	}
+}
+
+// SortByPercentile sorts ops by a percentile of PERCENTILE_COLUMNS, which are merged
+// from histograms after ops are queried
+func SortByPercentile(ops []OpStat, orderBy string, order string) {
+	percentile := func(op OpStat) int {
+		switch orderBy {
+		case "p50_ms":
+			return op.P50Milli
+		case "p95_ms":
+			return op.P95Milli
+		}
+		return op.P99Milli
+	}
+	sort.SliceStable(ops, func(i int, j int) bool {
+		if order == "DESC" {
+			return percentile(ops[i]) > percentile(ops[j])
+		}
+		return percentile(ops[i]) < percentile(ops[j])
+	})
+}
+
+// getLatencyBuckets returns upper bounds and widths of latency buckets, 1 ms wide below
+// EXACT_LATENCY_MS and of 3 significant digits to MAX_LATENCY_MS, e.g. 10 ms wide below 10 seconds
+func getLatencyBuckets() [][2]int {
+	buckets := [][2]int{{EXACT_LATENCY_MS, 1}}
+	for bound, width := EXACT_LATENCY_MS*10, 10; bound <= MAX_LATENCY_MS; bound, width = bound*10, width*10 {
+		buckets = append(buckets, [2]int{bound, width})
+	}
+	return buckets
+}
+
+// setQueryShapes sets shapes of sort, projection, limit/skip and hint of a command,
+// or of the first $sort and $project stages and $limit and $skip stages of a pipeline
+func setQueryShapes(stat *OpStat, command map[string]interface{}, raw interface{}) {
+	stat.Hint = getKeyShape(getRawValue(raw, "hint", command["hint"]))
+	var limit, skip bool
+	if stat.Op == cmdAggregate {
+		pipeline, _ := command["pipeline"].([]interface{})
+		rawPipeline, _ := getRawValue(raw, "pipeline", nil).(bson.A)
+		for i, v := range pipeline {
+			var rawStage interface{}
+			if i < len(rawPipeline) {
+				rawStage = rawPipeline[i]
+			}
+			for name, spec := range toMap(v) {
+				switch name {
+				case "$sort":
+					if stat.SortShape == "" {
+						stat.SortShape = getKeyShape(getRawValue(rawStage, name, spec))
+					}
+				case "$project":
+					if stat.Projection == "" {
+						stat.Projection = getKeyShape(getRawValue(rawStage, name, spec))
+					}
+				case "$limit":
+					limit = true
+				case "$skip":
+					skip = true
+				}
+			}
+		}
+	} else {
+		stat.SortShape = getKeyShape(getRawValue(raw, "sort", command["sort"]))
+		if command["projection"] != nil {
+			stat.Projection = getKeyShape(getRawValue(raw, "projection", command["projection"]))
+		} else {
+			stat.Projection = getKeyShape(getRawValue(raw, "fields", command["fields"])) // findAndModify
 		}
+		limit = command["limit"] != nil && ToInt(command["limit"]) != 0
+		skip = command["skip"] != nil && ToInt(command["skip"]) != 0
+	}
+	if limit && skip {
+		stat.LimitSkip = "{ limit:1, skip:1 }"
+	} else if limit {
+		stat.LimitSkip = "{ limit:1 }"
+	} else if skip {
+		stat.LimitSkip = "{ skip:1 }"
+	}
+}
+
+// setWriteShapes sets the update shape and multi, upsert and remove flags of update, delete
+// and findAndModify commands, or of the first statement of updates and deletes of a command
+func setWriteShapes(stat *OpStat, command map[string]interface{}) {
+	stmt := command
+	for _, key := range []string{"updates", "deletes"} {
+		if stmts, ok := command[key].([]interface{}); ok && len(stmts) > 0 {
+			stmt = toMap(stmts[0])
+		}
+	}
+	if stmt["u"] != nil {
+		stat.UpdateShape = getUpdateShape(stmt["u"])
+	} else if stat.Op == cmdFindAndModify {
+		stat.UpdateShape = getUpdateShape(stmt["update"])
+	}
+	flags := []string{}
+	if stmt["multi"] != nil && getKeyValue(stmt["multi"]) != 0 {
+		flags = append(flags, "multi:1")
+	} else if (stat.Op == cmdDelete || stat.Op == cmdRemove) && stmt["limit"] != nil && ToInt(stmt["limit"]) == 0 {
+		flags = append(flags, "multi:1") // deletes all matched of limit 0
+	}
+	if stmt["remove"] != nil && getKeyValue(stmt["remove"]) != 0 {
+		flags = append(flags, "remove:1")
+	}
+	if stmt["upsert"] != nil && getKeyValue(stmt["upsert"]) != 0 {
+		flags = append(flags, "upsert:1")
+	}
+	if len(flags) > 0 {
+		stat.WriteFlags = "{ " + strings.Join(flags, ", ") + " }"
 	}
 }
 
+// getUpdateShape returns sorted operators of an update, e.g. { $inc:1, $set:1 }, stages of
+// a pipeline update, e.g. [ $set, $unset ], or replacement of a replacement document
+func getUpdateShape(update interface{}) string {
+	if pipeline, ok := update.([]interface{}); ok {
+		stages := []string{}
+		for _, stage := range pipeline {
+			for name := range toMap(stage) {
+				stages = append(stages, name)
+			}
+		}
+		return "[ " + strings.Join(stages, ", ") + " ]"
+	}
+	doc := toMap(update)
+	if len(doc) == 0 {
+		return ""
+	}
+	operators := []string{}
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			operators = append(operators, key+":1")
+		}
+	}
+	if len(operators) == 0 {
+		return "replacement"
+	}
+	sort.Strings(operators)
+	return "{ " + strings.Join(operators, ", ") + " }"
+}
+
+// getKeyShape returns keys of sort, projection and hint with numeric values, e.g.
+// { a:1, b:-1 }, other values normalized to 1, or the index name of a hint. Keys of
+// a bson.D are kept in order, as the order of keys of sort and hint matters.
+func getKeyShape(spec interface{}) string {
+	if name, ok := spec.(string); ok {
+		return name
+	} else if d, ok := spec.(bson.D); ok {
+		if len(d) == 0 {
+			return ""
+		}
+		keys := []string{}
+		for _, elem := range d {
+			keys = append(keys, fmt.Sprintf("%v:%v", elem.Key, getKeyValue(elem.Value)))
+		}
+		return "{ " + strings.Join(keys, ", ") + " }"
+	}
+	doc := toMap(spec)
+	if len(doc) == 0 {
+		return ""
+	}
+	keys := map[string]interface{}{}
+	for k, v := range doc {
+		keys[k] = getKeyValue(v)
+	}
+	return toShape(keys)
+}
+
+// getKeyValue returns a numeric value of a key, or 1 of other values
+func getKeyValue(v interface{}) int {
+	switch n := v.(type) {
+	case int, int32, int64, float64:
+		return ToInt(n)
+	case bool:
+		if n {
+			return 1
+		}
+		return 0
+	}
+	return 1
+}
+
+// getRawValue returns the value of a key of a bson.D, or the given value if not found
+func getRawValue(raw interface{}, key string, value interface{}) interface{} {
+	if d, ok := raw.(bson.D); ok {
+		for _, elem := range d {
+			if elem.Key == key {
+				return elem.Value
+			}
+		}
+	}
+	return value
+}
+
+// toHash returns a hash of hex digits, which legacy text logs may parse as a number
+func toHash(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	} else if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
 // toInt converts various numeric types to int efficiently
 func toInt(v interface{}) int {
 	switch n := v.(type) {
@@ -358,6 +749,9 @@ func isRegex(doc map[string]interface{}) bool {
 func getOp(command map[string]interface{}) string {
 	ops := []string{cmdAggregate, cmdCollstats, cmdCount, cmdCreateIndexes, cmdDelete, cmdDistinct,
 		cmdFind, cmdFindAndModify, cmdGetMore, cmdInsert, cmdRemove, cmdUpdate}
+	if command["findAndModify"] != nil { // of which the update is also a key
+		return cmdFindAndModify
+	}
 	for _, v := range ops {
 		if command[v] != nil {
 			return v
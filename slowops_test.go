@@ -6,8 +6,10 @@
 package hatchet
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/simagix/gox"
 	"go.mongodb.org/mongo-driver/bson"
@@ -25,6 +27,9 @@ func TestAnalyzeLog(t *testing.T) {
 	if stat.QueryPattern != expected {
 		t.Fatal("expected", expected, "but got", stat.QueryPattern)
 	}
+	if stat.QueryHash != "6C0186CD" || stat.PlanCacheKey != "6EB1F22F" {
+		t.Fatal("expected queryHash and planCacheKey, but got", stat.QueryHash, stat.PlanCacheKey)
+	}
 	t.Log(gox.Stringify(stat, "", "  "))
 }
 
@@ -212,3 +217,356 @@ func TestAnalyzeSlowOpErrMsg(t *testing.T) {
 	}
 	t.Log(gox.Stringify(stat, "", "  "))
 }
+
+func TestSetQueryTargeting(t *testing.T) {
+	tests := []struct {
+		line      string
+		keys      int
+		docs      int
+		keysRatio float64
+		docsRatio float64
+		poorly    bool
+	}{
+		{`{"t":{"$date":"2021-07-25T09:38:57.078+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn541","msg":"Slow query","attr":{"type":"command","ns":"demo.hatchet","command":{"find":"hatchet","filter":{"status":"completed"}},"planSummary":"IXSCAN { status: 1 }","keysExamined":218,"docsExamined":217,"numYields":6,"nreturned":53,"reslen":6117,"durationMillis":530}}`,
+			218, 217, 4.1, 4.1, false},
+		{`{"t":{"$date":"2021-07-25T09:56:00.691+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"update","ns":"demo.orders","command":{"q":{"sku":"abc"},"u":{"$set":{"qty":1}}},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":50000,"nMatched":2,"nModified":2,"numYields":50,"durationMillis":105}}`,
+			0, 50000, 0, 25000, true},
+		{`2021-07-25T09:56:03.000+0000 I  COMMAND  [conn15] command test.orders command: find { find: "orders", filter: { status: "A" }, $db: "test" } planSummary: COLLSCAN keysExamined:0 docsExamined:5000 numYields:39 nreturned:0 reslen:410 locks:{} protocol:op_msg 1203ms`,
+			0, 5000, 0, 5000, true},
+	}
+	for _, test := range tests {
+		doc := Logv2Info{}
+		if err := ParseLogLine(test.line, &doc); err != nil {
+			t.Fatal(err)
+		}
+		stat, err := AnalyzeSlowOp(&doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.KeysExamined != test.keys || stat.DocsExamined != test.docs || stat.KeysRatio != test.keysRatio ||
+			stat.DocsRatio != test.docsRatio || stat.PoorlyTargeted != test.poorly {
+			t.Fatalf("unexpected query targeting %v", gox.Stringify(stat))
+		}
+	}
+}
+
+func TestGetSlowOpsTargeting(t *testing.T) {
+	useTestDatabase(t)
+	// a hatchet of an earlier version without columns of slow op metrics
+	dbase, err := NewSQLite3DB(instance.url, "legacy_ops", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CreateTables(dbase.db, "hatchet_init"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dbase.db.Exec(`CREATE TABLE legacy_ops_ops (op text, count integer, avg_ms numeric, max_ms integer,
+		total_ms integer, ns text, _index text, reslen integer, filter text, marker integer);
+		INSERT INTO legacy_ops_ops VALUES ('find', 1, 10, 10, 10, 'test.users', 'COLLSCAN', 100, '{ a:1 }', 0);`); err != nil {
+		t.Fatal(err)
+	}
+	dbase.Close()
+	if dbase, err = NewSQLite3DB(instance.url, "legacy_ops", 0); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := dbase.GetSlowOps("avg_ms", "DESC", false, "")
+	dbase.Close()
+	if err != nil || len(ops) != 1 || ops[0].DocsExamined != 0 {
+		t.Fatalf("expected slow ops of a migrated hatchet, got %v %v", ops, err)
+	}
+
+	logv2 := &Logv2{testing: true, url: instance.url, to: time.Now()}
+	if err = logv2.Analyze("logs/sample-mongod.log.gz", 1); err != nil {
+		t.Fatal(err)
+	}
+	if dbase, err = NewSQLite3DB(instance.url, logv2.hatchetName, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer dbase.Close()
+	if err = dbase.CreateMetaData(); err != nil {
+		t.Fatal(err)
+	}
+	if ops, err = dbase.GetSlowOps("docs_examined_returned", "DESC", false, ""); err != nil {
+		t.Fatal(err)
+	}
+	examined := 0
+	for i, op := range ops {
+		examined += op.KeysExamined + op.DocsExamined
+		if i > 0 && op.DocsRatio > ops[i-1].DocsRatio {
+			t.Fatalf("expected slow ops sorted by docs examined per returned, got %v after %v", op.DocsRatio, ops[i-1].DocsRatio)
+		}
+		if op.PoorlyTargeted != (op.KeysRatio > QUERY_TARGETING_RATIO || op.DocsRatio > QUERY_TARGETING_RATIO) {
+			t.Fatalf("unexpected poorly targeted flag %+v", op)
+		}
+	}
+	if len(ops) == 0 || examined == 0 {
+		t.Fatal("expected keys and documents examined of slow ops")
+	}
+	templ, err := GetStatsTableTemplate(false, "docs_examined_returned", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": logv2.hatchetName, "Ops": ops, "Summary": "", "Version": ""}
+	executeTestTemplate(t, templ, doc)
+}
+
+func TestSlowOpPercentiles(t *testing.T) {
+	dbase := createTestHatchet(t, "percentiles")
+	insert := `INSERT INTO percentiles (id, date, op, ns, filter, _index, milli, reslen, marker, query_hash)
+		VALUES (?, '2024-03-18T10:00:00.000Z', ?, 'test.users', ?, 'IXSCAN { a: 1 }', ?, 0, ?, ?)`
+	for i := 1; i <= 200; i++ { // 1 to 200 ms, shuffled, the faster half of a hash and of a merged log
+		milli := (i*37)%200 + 1
+		hash, marker := "6C0186CD", 1
+		if milli > 100 {
+			hash, marker = "7A2B33C0", 2
+		}
+		if _, err := dbase.db.Exec(insert, i, "find", "{ a: 1 }", milli, marker, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, milli := range []int{7, 12345, 12349} { // of 3 significant digits from a second
+		if _, err := dbase.db.Exec(insert, 201+i, "update", "{ b: 1 }", milli, 1, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dbase.CreateMetaData(); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := dbase.GetSlowOps("p99_ms", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[1].Op != "find" || ops[1].P50Milli != 100 || ops[1].P95Milli != 190 || ops[1].P99Milli != 198 {
+		t.Fatalf("expected p50, p95 and p99 of 100, 190 and 198 ms of hashes and logs merged, got %+v", ops)
+	}
+	if ops[0].P50Milli != 12300 || ops[0].P99Milli != 12300 || ops[0].MaxMilli != 12349 {
+		t.Fatalf("expected percentiles of 3 significant digits, got %+v", ops[0])
+	}
+	if ops, err = dbase.GetSlowOps("p50_ms", "ASC", false, SLOWOP_GROUPS["queryHash"]); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].P50Milli != 50 || ops[0].P99Milli != 99 || ops[1].P50Milli != 150 || ops[1].P99Milli != 199 {
+		t.Fatalf("expected percentiles of each hash, got %+v", ops)
+	}
+}
+
+func TestGetSlowOpsByHash(t *testing.T) {
+	dbase := createTestHatchet(t, "hashes")
+	insert := `INSERT INTO hashes (id, date, op, ns, filter, _index, milli, reslen, marker, query_hash, plan_cache_key, query_shape_hash)
+		VALUES (?, '2024-03-18T10:00:00.000Z', 'find', 'test.users', '{ a:1, b:1 }', ?, 10, 0, 0, ?, ?, ?)`
+	rows := [][]interface{}{
+		{"{a:1}", "6C0186CD", "6EB1F22F", "E1B3"}, {"{a:1}", "6C0186CD", "6EB1F22F", "E1B3"},
+		{"{a:1,b:1}", "6C0186CD", "7A2B33C0", "E1B3"}, {"COLLSCAN", "6C0186CD", "7A2B33C0", "E1B3"},
+		{"{a:1}", "", "", ""},
+	}
+	for i, row := range rows {
+		if _, err := dbase.db.Exec(insert, append([]interface{}{i + 1}, row...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dbase.CreateMetaData(); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := dbase.GetSlowOps("count", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 3 || ops[0].Count != 3 || ops[0].PlanCacheKey != "6EB1F22F" || ops[0].PlanFlip {
+		t.Fatalf("expected 3 query patterns of an index, got %+v", ops)
+	}
+	if ops, err = dbase.GetSlowOps("count", "DESC", false, SLOWOP_GROUPS["planCacheKey"]); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Count != 2 || ops[1].Count != 2 || !ops[0].PlanFlip && !ops[1].PlanFlip {
+		t.Fatalf("expected 2 plan cache keys of 4 ops and a plan flip, got %+v", ops)
+	}
+	for _, op := range ops {
+		if op.PlanCacheKey == "7A2B33C0" && (op.Index != "{a:1,b:1} | COLLSCAN" && op.Index != "COLLSCAN | {a:1,b:1}") {
+			t.Fatalf("expected distinct indexes of a plan cache key, got %v", op.Index)
+		}
+	}
+	if ops, err = dbase.GetSlowOps("count", "DESC", false, SLOWOP_GROUPS["queryHash"]); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Count != 4 || ops[0].QueryPattern != "{ a:1, b:1 }" || !ops[0].PlanFlip {
+		t.Fatalf("expected a query hash of 4 ops, got %+v", ops)
+	}
+	templ, err := GetStatsTableTemplate(false, "count", "queryHash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "hashes", "Ops": ops, "Summary": "", "Version": ""}
+	executeTestTemplate(t, templ, doc)
+}
+
+func TestAnalyzeSlowOpQueryShapes(t *testing.T) {
+	str := `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"A","age":{"$gt":21}},"sort":{"name":1,"age":-1},"projection":{"name":1,"_id":0},"limit":20,"skip":40,"hint":{"status":1,"name":1},"$db":"test"},"planSummary":"IXSCAN { status: 1, name: 1 }","nreturned":20,"durationMillis":120}}`
+	stat, err := AnalyzeLog(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.SortShape != "{ name:1, age:-1 }" || stat.Projection != "{ name:1, _id:0 }" ||
+		stat.LimitSkip != "{ limit:1, skip:1 }" || stat.Hint != "{ status:1, name:1 }" {
+		t.Fatalf("unexpected shapes of sort %v, projection %v, limit/skip %v and hint %v", stat.SortShape, stat.Projection, stat.LimitSkip, stat.Hint)
+	}
+
+	str = `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"findAndModify":"users","query":{"status":"A"},"sort":{"date":1},"fields":{"name":true},"update":{"$set":{"status":"B"}},"$db":"test"},"planSummary":"COLLSCAN","durationMillis":120}}`
+	if stat, err = AnalyzeLog(str); err != nil {
+		t.Fatal(err)
+	}
+	if stat.SortShape != "{ date:1 }" || stat.Projection != "{ name:1 }" || stat.LimitSkip != "" || stat.Hint != "" {
+		t.Fatalf("unexpected shapes of findAndModify %+v", stat)
+	}
+
+	str = `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"aggregate":"users","pipeline":[{"$match":{"status":"A"}},{"$sort":{"date":-1}},{"$limit":10},{"$project":{"name":1,"total":{"$add":["$a","$b"]}}}],"hint":"status_1","cursor":{},"$db":"test"},"planSummary":"IXSCAN { status: 1 }","durationMillis":120}}`
+	if stat, err = AnalyzeLog(str); err != nil {
+		t.Fatal(err)
+	}
+	if stat.SortShape != "{ date:-1 }" || stat.Projection != "{ name:1, total:1 }" || stat.LimitSkip != "{ limit:1 }" || stat.Hint != "status_1" {
+		t.Fatalf("unexpected shapes of aggregate %+v", stat)
+	}
+}
+
+func TestGetSlowOpsQueryShapes(t *testing.T) {
+	logline := `{"t":{"$date":"2024-03-18T10:00:0%d.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"A"},"sort":%v,"limit":%d,"$db":"test"},"planSummary":"IXSCAN { status: 1 }","nreturned":20,"durationMillis":100}}`
+	sorts := []string{`{"name":1}`, `{"name":1}`, `{"date":-1}`}
+	var logs []string
+	for i, sort := range sorts {
+		logs = append(logs, fmt.Sprintf(logline, i, sort, 10*(i+1)))
+	}
+	dbase := analyzeTestLogs(t, "shapes", logs)
+	ops, err := dbase.GetSlowOps("count", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Count != 2 || ops[0].SortShape != "{ name:1 }" || ops[1].SortShape != "{ date:-1 }" || ops[0].LimitSkip != "{ limit:1 }" {
+		t.Fatalf("expected patterns of the same filter split by sorts, got %+v", ops)
+	}
+}
+
+const waitsLog = `{"t":{"$date":"2024-03-18T10:0%d:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"A"},"$db":"test"},"planSummary":"COLLSCAN","docsExamined":1000,"nreturned":10,"locks":{"FeatureCompatibilityVersion":{"acquireCount":{"r":1},"acquireWaitCount":{"r":1},"timeAcquiringMicros":{"r":20000}},"Global":{"acquireCount":{"r":1,"w":1},"timeAcquiringMicros":{"r":5000,"w":5000}}},"flowControl":{"acquireCount":1,"timeAcquiringMicros":10000},"storage":{"data":{"bytesRead":4096,"timeReadingMicros":40000}},"remoteOpWaitMillis":5,"cpuNanos":15000000,"durationMillis":100}}`
+
+func TestAnalyzeSlowOpTimeBreakdown(t *testing.T) {
+	stat, err := AnalyzeLog(fmt.Sprintf(waitsLog, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.BytesRead != 4096 || stat.TimeReadingMicros != 40000 || stat.LockWaitMicros != 30000 ||
+		stat.FlowControlMicros != 10000 || stat.RemoteOpWaitMillis != 5 || stat.CPUNanos != 15000000 {
+		t.Fatalf("unexpected waits %+v", stat)
+	}
+	expected := []float64{15, 40, 30, 10, 5, 0}
+	if values := GetOpTimeBreakdown(*stat); fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+	// CPU time of logs without cpuNanos is in the remaining duration
+	stat.CPUNanos = 0
+	stat.TotalMilli = 200
+	expected = []float64{0, 40, 30, 10, 5, 115}
+	if values := GetOpTimeBreakdown(*stat); fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestGetTimeBreakdown(t *testing.T) {
+	dbase := analyzeTestLogs(t, "waits", []string{fmt.Sprintf(waitsLog, 0), fmt.Sprintf(waitsLog, 1)})
+	ops, err := dbase.GetSlowOps("avg_ms", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].BytesRead != 8192 || ops[0].LockWaitMicros != 60000 || ops[0].CPUNanos != 30000000 ||
+		ops[0].FlowControlMicros != 20000 || ops[0].RemoteOpWaitMillis != 10 || ops[0].TimeReadingMicros != 80000 {
+		t.Fatalf("unexpected waits of slow ops %+v", ops)
+	}
+	if html := getTimeBreakdownHTML(ops[0]); !strings.Contains(html, "lock wait 60 ms") || !strings.Contains(html, "(8,192 bytes read)") {
+		t.Fatalf("unexpected time breakdown %v", html)
+	}
+
+	metrics, err := dbase.GetTimeBreakdown("", "2024-03-18T10:00:00,2024-03-18T10:05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := GetMetricsTable(metrics)
+	if len(table.Rows) != 2 || len(table.Names) != len(TIME_BREAKDOWN) || table.Rows[0].Values[2] != 0.03 {
+		t.Fatalf("unexpected time breakdown %+v", table)
+	}
+	templ, err := GetChartTemplate(COLUMN_CHART)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "waits", "Metrics": table, "Chart": charts[T_OPS_BREAKDOWN], "Summary": "",
+		"Start": "", "End": "", "VAxisLabel": "seconds"}
+	executeTestTemplate(t, templ, doc)
+}
+
+func TestAnalyzeSlowOpWrites(t *testing.T) {
+	str := `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"update","ns":"test.users","command":{"q":{"status":"A"},"u":{"$set":{"status":"B"},"$inc":{"n":1}},"multi":true,"upsert":false},"planSummary":"IXSCAN { status: 1 }","keysExamined":5000,"docsExamined":5000,"nMatched":5000,"nModified":5000,"keysInserted":60000,"keysDeleted":60000,"durationMillis":900}}`
+	stat, err := AnalyzeLog(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.UpdateShape != "{ $inc:1, $set:1 }" || stat.WriteFlags != "{ multi:1 }" || stat.KeysInserted != 60000 ||
+		stat.DocsPerWrite != 5000 || stat.KeysPerDoc != 24 || !stat.UnboundedMulti || !stat.WriteAmplified {
+		t.Fatalf("unexpected write detail of update %+v", stat)
+	}
+
+	str = `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"update","ns":"test.users","command":{"q":{"_id":1},"u":{"name":"Ken"},"upsert":true},"planSummary":"IDHACK","nMatched":0,"nModified":0,"nUpserted":1,"keysInserted":3,"durationMillis":200}}`
+	if stat, err = AnalyzeLog(str); err != nil {
+		t.Fatal(err)
+	}
+	if stat.UpdateShape != "replacement" || stat.WriteFlags != "{ upsert:1 }" || stat.NUpserted != 1 || stat.KeysPerDoc != 3 {
+		t.Fatalf("unexpected write detail of upsert %+v", stat)
+	}
+
+	str = `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"findAndModify":"users","query":{"status":"A"},"update":[{"$set":{"status":"B"}},{"$unset":"tmp"}],"$db":"test"},"planSummary":"COLLSCAN","durationMillis":120}}`
+	if stat, err = AnalyzeLog(str); err != nil {
+		t.Fatal(err)
+	}
+	if stat.Op != cmdFindAndModify || stat.UpdateShape != "[ $set, $unset ]" || stat.QueryPattern != "{ status:1 }" {
+		t.Fatalf("unexpected write detail of findAndModify %+v", stat)
+	}
+
+	str = `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"remove","ns":"test.users","command":{"q":{"status":"D"},"limit":0},"planSummary":"COLLSCAN","ndeleted":20,"keysDeleted":60,"durationMillis":300}}`
+	if stat, err = AnalyzeLog(str); err != nil {
+		t.Fatal(err)
+	}
+	if stat.WriteFlags != "{ multi:1 }" || stat.NDeleted != 20 || stat.KeysPerDoc != 3 || stat.UnboundedMulti {
+		t.Fatalf("unexpected write detail of remove %+v", stat)
+	}
+
+	str = `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"insert":"users","documents":[{"_id":1},{"_id":2},{"_id":3}],"ordered":true,"$db":"test"},"keysInserted":6,"durationMillis":150}}`
+	if stat, err = AnalyzeLog(str); err != nil {
+		t.Fatal(err)
+	}
+	if stat.NInserted != 3 || stat.DocsPerWrite != 3 || stat.KeysPerDoc != 2 || stat.WriteFlags != "" {
+		t.Fatalf("unexpected write detail of insert %+v", stat)
+	}
+}
+
+func TestGetSlowOpsWrites(t *testing.T) {
+	logline := `{"t":{"$date":"2024-03-18T10:00:0%d.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"update","ns":"test.users","command":{"q":{"status":"A"},"u":%v,"multi":true},"planSummary":"IXSCAN { status: 1 }","nMatched":2000,"nModified":2000,"keysInserted":%d,"keysDeleted":%d,"durationMillis":100}}`
+	updates := []string{`{"$set":{"status":"B"}}`, `{"$set":{"status":"C"}}`, `{"status":"B"}`}
+	var logs []string
+	for i, update := range updates {
+		logs = append(logs, fmt.Sprintf(logline, i, update, 30000, 30000))
+	}
+	dbase := analyzeTestLogs(t, "writes", logs)
+	ops, err := dbase.GetSlowOps("count", "DESC", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Count != 2 || ops[0].UpdateShape != "{ $set:1 }" || ops[1].UpdateShape != "replacement" ||
+		ops[0].WriteFlags != "{ multi:1 }" || ops[0].KeysInserted != 60000 || ops[0].DocsPerWrite != 2000 ||
+		ops[0].KeysPerDoc != 30 || !ops[0].UnboundedMulti || !ops[0].WriteAmplified {
+		t.Fatalf("expected patterns of the same filter split by update shapes, got %+v", ops)
+	}
+	templ, err := GetStatsTableTemplate(false, "count", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "writes", "Merge": false, "Ops": ops, "Summary": ""}
+	html := executeTestTemplate(t, templ, doc)
+	if !strings.Contains(html, "update: { $set:1 }") || !strings.Contains(html, "unbounded multi write") {
+		t.Fatalf("expected write detail in the stats template")
+	}
+}
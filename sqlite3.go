@@ -14,14 +14,44 @@ import (
 	"strings"
 )
 
+// SLOWOP_METRIC_COLUMNS are columns of slow op metrics added to hatchet and ops tables
+var SLOWOP_METRIC_COLUMNS = []string{"keys_examined", "docs_examined", "nreturned", "nmatched", "nmodified", "num_yields",
+	"has_sort_stage", "used_disk", "bytes_read", "cpu_nanos", "flow_control_micros", "lock_wait_micros", "remote_wait_ms",
+	"time_reading_micros", "keys_inserted", "keys_deleted", "ninserted", "ndeleted", "nupserted"}
+
+// SLOWOP_TEXT_COLUMNS are text columns of MongoDB hashes of queries and pipeline fingerprints
+// added to hatchet and ops tables
+var SLOWOP_TEXT_COLUMNS = []string{"query_hash", "plan_cache_key", "query_shape_hash", "pipeline",
+	"sort_shape", "projection", "limit_skip", "hint", "update_shape", "write_flags"}
+
+// SLOWOP_PATTERN is columns of a query pattern of slow ops
+const SLOWOP_PATTERN = "op, ns, filter, _index, pipeline, sort_shape, projection, limit_skip, hint, update_shape, write_flags"
+
+const CHECKPOINTS_TABLE = `CREATE TABLE IF NOT EXISTS checkpoints (
+			identity text not null primary key,
+			name text,
+			filename text,
+			offset integer,
+			line integer,
+			date text,
+			marker integer);`
+
 type SQLite3DB struct {
+	auditStmt   *sql.Stmt // {hatchet}_auditlog
 	clientStmt  *sql.Stmt // {hatchet}_clients
 	driverStmt  *sql.Stmt // {hatchet}_drivers
+	errorStmt   *sql.Stmt // {hatchet}_errors
+	eventStmt   *sql.Stmt // {hatchet}_events
 	db          *sql.DB
 	dbfile      string
 	hatchetName string
+	metricStmt  *sql.Stmt // {hatchet}_metrics
 	tx          *sql.Tx
+	txnStmt     *sql.Stmt // {hatchet}_txns
 	pstmt       *sql.Stmt // {hatchet}
+	shardStmt   *sql.Stmt // {hatchet}_sharding
+	startupStmt *sql.Stmt // {hatchet}_startup
+	storageStmt *sql.Stmt // {hatchet}_storage
 	verbose     bool
 }
 
@@ -97,6 +127,28 @@ func (ptr *SQLite3DB) migrate() error {
 			return err
 		}
 	}
+
+	// Add checkpoints table if missing
+	if _, err = ptr.db.Exec(CHECKPOINTS_TABLE); err != nil {
+		return err
+	}
+
+	// Add columns of slow op metrics to hatchets created by earlier versions
+	for _, table := range []string{ptr.hatchetName, ptr.hatchetName + "_ops"} {
+		if ptr.hatchetName == "" || !ptr.tableExists(table) {
+			continue
+		}
+		columns := SLOWOP_TEXT_COLUMNS
+		if table == ptr.hatchetName+"_ops" {
+			columns = append(columns[:len(columns):len(columns)], "histogram")
+		}
+		if err = ptr.addColumns(table, "integer", SLOWOP_METRIC_COLUMNS); err != nil {
+			return err
+		}
+		if err = ptr.addColumns(table, "text", columns); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -114,6 +166,11 @@ func (ptr *SQLite3DB) Begin() error {
 	if err := ptr.Drop(); err != nil {
 		log.Println("warning: failed to drop existing tables:", err)
 	}
+	return ptr.Append()
+}
+
+// Append begins a transaction to add logs to existing hatchet tables
+func (ptr *SQLite3DB) Append() error {
 	stmts, err := CreateTables(ptr.db, ptr.hatchetName)
 	if err != nil {
 		return err
@@ -135,6 +192,30 @@ func (ptr *SQLite3DB) Begin() error {
 	if ptr.driverStmt, err = ptr.tx.Prepare(GetDriverPreparedStmt(ptr.hatchetName)); err != nil {
 		return err
 	}
+	if ptr.auditStmt, err = ptr.tx.Prepare(GetAuditLogPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.errorStmt, err = ptr.tx.Prepare(GetErrorPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.eventStmt, err = ptr.tx.Prepare(GetReplEventPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.metricStmt, err = ptr.tx.Prepare(GetMetricPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.shardStmt, err = ptr.tx.Prepare(GetShardEventPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.startupStmt, err = ptr.tx.Prepare(GetStartupEventPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.storageStmt, err = ptr.tx.Prepare(GetStorageEventPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
+	if ptr.txnStmt, err = ptr.tx.Prepare(GetTransactionPreparedStmt(ptr.hatchetName)); err != nil {
+		return err
+	}
 	return err
 }
 
@@ -159,6 +240,46 @@ func (ptr *SQLite3DB) Close() error {
 			return err
 		}
 	}
+	if ptr.auditStmt != nil {
+		if err = ptr.auditStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.errorStmt != nil {
+		if err = ptr.errorStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.eventStmt != nil {
+		if err = ptr.eventStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.metricStmt != nil {
+		if err = ptr.metricStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.shardStmt != nil {
+		if err = ptr.shardStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.startupStmt != nil {
+		if err = ptr.startupStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.storageStmt != nil {
+		if err = ptr.storageStmt.Close(); err != nil {
+			return err
+		}
+	}
+	if ptr.txnStmt != nil {
+		if err = ptr.txnStmt.Close(); err != nil {
+			return err
+		}
+	}
 	defer ptr.db.Close()
 	return err
 }
@@ -170,9 +291,18 @@ func (ptr *SQLite3DB) Drop() error {
 	stmts := fmt.Sprintf(`
 			DROP TABLE IF EXISTS %v;
 			DROP TABLE IF EXISTS %v_audit;
+			DROP TABLE IF EXISTS %v_auditlog;
 			DROP TABLE IF EXISTS %v_clients;
 			DROP TABLE IF EXISTS %v_drivers;
+			DROP TABLE IF EXISTS %v_errors;
+			DROP TABLE IF EXISTS %v_events;
+			DROP TABLE IF EXISTS %v_metrics;
+			DROP TABLE IF EXISTS %v_migrations;
 			DROP TABLE IF EXISTS %v_ops;
+			DROP TABLE IF EXISTS %v_sharding;
+			DROP TABLE IF EXISTS %v_startup;
+			DROP TABLE IF EXISTS %v_storage;
+			DROP TABLE IF EXISTS %v_txns;
 
 			DROP INDEX IF EXISTS %v_idx_component_severity;
 			DROP INDEX IF EXISTS %v_idx_context_date;
@@ -185,13 +315,22 @@ func (ptr *SQLite3DB) Drop() error {
 			DROP INDEX IF EXISTS %v_idx_severity;
 
 			DROP INDEX IF EXISTS %v_audit_idx_type_value;
+			DROP INDEX IF EXISTS %v_auditlog_idx_category_atype;
 			DROP INDEX IF EXISTS %v_clients_idx_ip_accepted;
 			DROP INDEX IF EXISTS %v_clients_idx_ip_context;
 			DROP INDEX IF EXISTS %v_drivers_idx_driver_version;
 			DROP INDEX IF EXISTS %v_ops_idx_avgms;
-			DROP INDEX IF EXISTS %v_ops_idx_index;`,
+			DROP INDEX IF EXISTS %v_ops_idx_index;
+			DROP INDEX IF EXISTS %v_txns_idx_lsid_date;
+			DROP INDEX IF EXISTS %v_errors_idx_name_date;
+			DROP INDEX IF EXISTS %v_events_idx_date_marker;
+			DROP INDEX IF EXISTS %v_sharding_idx_type_date;
+			DROP INDEX IF EXISTS %v_startup_idx_marker_id;
+			DROP INDEX IF EXISTS %v_storage_idx_type_date;`,
 		hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName,
 		hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName,
+		hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName,
+		hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName, hatchetName,
 	)
 	if _, err = ptr.db.Exec(stmts); err != nil {
 		return err
@@ -201,6 +340,10 @@ func (ptr *SQLite3DB) Drop() error {
 	if _, err := ptr.db.Exec(stmt); err != nil {
 		return err
 	}
+	stmt = fmt.Sprintf(`DELETE FROM checkpoints WHERE name = '%v'`, ptr.hatchetName)
+	if _, err := ptr.db.Exec(stmt); err != nil {
+		return err
+	}
 	return err
 }
 
@@ -237,13 +380,21 @@ func (ptr *SQLite3DB) Rename(newName string) error {
 		DROP INDEX IF EXISTS %v_idx_severity;
 		DROP INDEX IF EXISTS %v_idx_appname_reslen;
 		DROP INDEX IF EXISTS %v_audit_idx_type_value;
+		DROP INDEX IF EXISTS %v_auditlog_idx_category_atype;
 		DROP INDEX IF EXISTS %v_clients_idx_ip_accepted;
 		DROP INDEX IF EXISTS %v_clients_idx_ip_context;
 		DROP INDEX IF EXISTS %v_drivers_idx_driver_version_ip;
 		DROP INDEX IF EXISTS %v_ops_idx_avgms;
-		DROP INDEX IF EXISTS %v_ops_idx_index;`,
+		DROP INDEX IF EXISTS %v_ops_idx_index;
+		DROP INDEX IF EXISTS %v_txns_idx_lsid_date;
+		DROP INDEX IF EXISTS %v_errors_idx_name_date;
+		DROP INDEX IF EXISTS %v_events_idx_date_marker;
+		DROP INDEX IF EXISTS %v_sharding_idx_type_date;
+		DROP INDEX IF EXISTS %v_startup_idx_marker_id;
+		DROP INDEX IF EXISTS %v_storage_idx_type_date;`,
+		oldName, oldName, oldName, oldName, oldName, oldName, oldName, oldName, oldName,
 		oldName, oldName, oldName, oldName, oldName, oldName, oldName, oldName, oldName,
-		oldName, oldName, oldName, oldName, oldName, oldName,
+		oldName, oldName, oldName, oldName,
 	)
 	if _, err = ptr.db.Exec(dropIndexes); err != nil {
 		return fmt.Errorf("failed to drop indexes: %v", err)
@@ -265,6 +416,17 @@ func (ptr *SQLite3DB) Rename(newName string) error {
 	if _, err = ptr.db.Exec(renameTables); err != nil {
 		return fmt.Errorf("failed to rename tables: %v", err)
 	}
+	// tables added in later versions may not exist in older hatchets
+	for _, suffix := range []string{"_auditlog", "_errors", "_events", "_metrics", "_migrations", "_sharding", "_startup",
+		"_storage", "_txns"} {
+		if !ptr.tableExists(oldName + suffix) {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %v%v RENAME TO %v%v;", oldName, suffix, newName, suffix)
+		if _, err = ptr.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to rename tables: %v", err)
+		}
+	}
 
 	// 3. Create new indexes with new names
 	createIndexes := []string{
@@ -283,6 +445,34 @@ func (ptr *SQLite3DB) Rename(newName string) error {
 		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_ops_idx_avgms ON %v_ops (avg_ms);", newName, newName),
 		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_ops_idx_index ON %v_ops (_index);", newName, newName),
 	}
+	if ptr.tableExists(newName + "_auditlog") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_auditlog_idx_category_atype ON %v_auditlog (category,atype);", newName, newName))
+	}
+	if ptr.tableExists(newName + "_errors") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_errors_idx_name_date ON %v_errors (name,date);", newName, newName))
+	}
+	if ptr.tableExists(newName + "_events") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_events_idx_date_marker ON %v_events (date,marker);", newName, newName))
+	}
+	if ptr.tableExists(newName + "_sharding") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_sharding_idx_type_date ON %v_sharding (type,date);", newName, newName))
+	}
+	if ptr.tableExists(newName + "_startup") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_startup_idx_marker_id ON %v_startup (marker,id);", newName, newName))
+	}
+	if ptr.tableExists(newName + "_storage") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_storage_idx_type_date ON %v_storage (type,date);", newName, newName))
+	}
+	if ptr.tableExists(newName + "_txns") {
+		createIndexes = append(createIndexes,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %v_txns_idx_lsid_date ON %v_txns (lsid,date);", newName, newName))
+	}
 	for _, stmt := range createIndexes {
 		if _, err = ptr.db.Exec(stmt); err != nil {
 			return fmt.Errorf("failed to create index: %v", err)
@@ -294,6 +484,10 @@ func (ptr *SQLite3DB) Rename(newName string) error {
 	if _, err = ptr.db.Exec(updateStmt); err != nil {
 		return fmt.Errorf("failed to update hatchet registry: %v", err)
 	}
+	updateStmt = fmt.Sprintf("UPDATE checkpoints SET name = '%v' WHERE name = '%v'", newName, oldName)
+	if _, err = ptr.db.Exec(updateStmt); err != nil {
+		return fmt.Errorf("failed to update checkpoints: %v", err)
+	}
 
 	// Update internal name
 	ptr.hatchetName = newName
@@ -304,9 +498,15 @@ func (ptr *SQLite3DB) Rename(newName string) error {
 func (ptr *SQLite3DB) InsertLog(index int, end string, doc *Logv2Info, stat *OpStat) error {
 	var err error
 	_, err = ptr.pstmt.Exec(index, end, doc.Severity, doc.Component, doc.Context,
-		doc.Msg, doc.Attributes.PlanSummary, BsonD2M(doc.Attr)["type"], doc.Attributes.NS, doc.Message,
+		doc.Msg, doc.Attributes.PlanSummary, doc.getAttrMap()["type"], doc.Attributes.NS, doc.Message,
 		stat.Op, stat.QueryPattern, stat.Index, doc.Attributes.Milli, doc.Attributes.Reslen,
-		doc.Attributes.AppName, doc.Marker)
+		doc.Attributes.AppName, doc.Marker, doc.Attributes.KeysExamined, doc.Attributes.DocsExamined,
+		doc.Attributes.NReturned, doc.Attributes.NMatched, doc.Attributes.NModified, doc.Attributes.NumYields,
+		doc.Attributes.QueryHash, doc.Attributes.PlanCacheKey, doc.Attributes.QueryShapeHash,
+		stat.HasSortStage, stat.UsedDisk, stat.PipelineShape, stat.SortShape, stat.Projection, stat.LimitSkip, stat.Hint,
+		stat.BytesRead, stat.CPUNanos, stat.FlowControlMicros, stat.LockWaitMicros, stat.RemoteOpWaitMillis,
+		stat.TimeReadingMicros, stat.UpdateShape, stat.WriteFlags, stat.KeysInserted, stat.KeysDeleted,
+		stat.NInserted, stat.NDeleted, stat.NUpserted)
 	return err
 }
 
@@ -325,17 +525,86 @@ func (ptr *SQLite3DB) InsertDriver(index int, doc *Logv2Info) error {
 	return err
 }
 
+// InsertAuditLog inserts an event of MongoDB auditLog
+func (ptr *SQLite3DB) InsertAuditLog(index int, end string, event *AuditEvent) error {
+	_, err := ptr.auditStmt.Exec(index, end, event.AType, event.Category, event.User, event.Roles,
+		event.Remote, event.Target, event.Param, event.Result, event.Marker)
+	return err
+}
+
+// InsertError inserts an error of a log line
+func (ptr *SQLite3DB) InsertError(index int, end string, logError *LogError) error {
+	_, err := ptr.errorStmt.Exec(index, end, logError.Code, logError.Name, logError.Category, logError.Namespace,
+		logError.Op, logError.AppName, logError.IP, logError.Component, logError.Marker)
+	return err
+}
+
+// InsertReplEvent inserts a replica set event
+func (ptr *SQLite3DB) InsertReplEvent(index int, end string, event *ReplEvent) error {
+	_, err := ptr.eventStmt.Exec(index, end, event.Type, event.Name, event.FromState, event.ToState, event.Detail,
+		event.Component, event.Context, event.Marker)
+	return err
+}
+
+// InsertShardEvent inserts a chunk migration, a range deletion, a balancer round or a resharding progress
+func (ptr *SQLite3DB) InsertShardEvent(index int, end string, event *ShardEvent) error {
+	_, err := ptr.shardStmt.Exec(index, end, event.Type, event.Name, event.Namespace, event.Donor, event.Recipient,
+		event.Milli, event.Status, event.Detail, event.Marker)
+	return err
+}
+
+// InsertStorageEvent inserts a checkpoint or an eviction warning of WiredTiger
+func (ptr *SQLite3DB) InsertStorageEvent(index int, end string, event *StorageEvent) error {
+	_, err := ptr.storageStmt.Exec(index, end, event.Type, event.Phase, event.Milli, event.Pages, event.MB,
+		event.Detail, event.Marker)
+	return err
+}
+
+// InsertStartupEvent inserts settings of a startup message, one row per setting
+func (ptr *SQLite3DB) InsertStartupEvent(index int, end string, event *StartupEvent) error {
+	for _, setting := range event.Settings {
+		if _, err := ptr.startupStmt.Exec(index, end, event.Type, setting.Name, setting.Value, event.Marker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertTransaction inserts a transaction, or a slow op of a transaction or with conflicts
+func (ptr *SQLite3DB) InsertTransaction(index int, end string, txn *Transaction) error {
+	_, err := ptr.txnStmt.Exec(index, end, txn.LSID, txn.TxnNumber, txn.Autocommit, txn.Namespace, txn.Op,
+		txn.Termination, txn.Reason, txn.Milli, txn.TimeActiveMicros, txn.TimeInactiveMicros, txn.WriteConflicts,
+		txn.PrepareConflictMillis, txn.WasPrepared, txn.Marker)
+	return err
+}
+
+// InsertMetric inserts or replaces a metric of FTDC
+func (ptr *SQLite3DB) InsertMetric(metric Metric) error {
+	_, err := ptr.metricStmt.Exec(metric.Date, metric.Type, metric.Name, metric.Value)
+	return err
+}
+
 func (ptr *SQLite3DB) InsertFailedMessages(m *FailedMessages) error {
 	var err error
 	for k, v := range m.counters {
 		str := strings.ReplaceAll(k, "'", "''")
-		stmt := fmt.Sprintf("INSERT INTO %v_audit (type, name, value) VALUES ('failed','%s', %d)", ptr.hatchetName, str, v)
+		// add to existing counts when logs are appended
+		stmt := fmt.Sprintf("UPDATE %v_audit SET value = value + %d WHERE type = 'failed' AND name = '%s'", ptr.hatchetName, v, str)
+		result, err := ptr.db.Exec(stmt)
+		if err != nil {
+			log.Println("error", err, "stmt", stmt, "(k,v)", k, v)
+			return err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			continue
+		}
+		stmt = fmt.Sprintf("INSERT INTO %v_audit (type, name, value) VALUES ('failed','%s', %d)", ptr.hatchetName, str, v)
 		if _, err = ptr.db.Exec(stmt); err != nil {
 			log.Println("error", err, "stmt", stmt, "(k,v)", k, v)
 			return err
 		}
 	}
-	return nil
+	return err
 }
 
 func (ptr *SQLite3DB) UpdateHatchetInfo(info HatchetInfo) error {
@@ -346,6 +615,56 @@ func (ptr *SQLite3DB) UpdateHatchetInfo(info HatchetInfo) error {
 	return err
 }
 
+// GetCheckpoint returns the checkpoint of a file identity
+func (ptr *SQLite3DB) GetCheckpoint(identity string) (*Checkpoint, error) {
+	checkpoint := Checkpoint{Identity: identity}
+	query := "SELECT name, filename, offset, line, date, marker FROM checkpoints WHERE identity = ?"
+	err := ptr.db.QueryRow(query, identity).Scan(&checkpoint.Name, &checkpoint.Filename,
+		&checkpoint.Offset, &checkpoint.Line, &checkpoint.Date, &checkpoint.Marker)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint saves the checkpoint of a file identity within the current
+// transaction, so that logs and the checkpoint are committed together
+func (ptr *SQLite3DB) SaveCheckpoint(checkpoint Checkpoint) error {
+	stmt := `INSERT OR REPLACE INTO checkpoints (identity, name, filename, offset, line, date, marker)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := ptr.tx.Exec(stmt, checkpoint.Identity, ptr.hatchetName, checkpoint.Filename,
+		checkpoint.Offset, checkpoint.Line, checkpoint.Date, checkpoint.Marker)
+	return err
+}
+
+// getOpsQuery returns a query of ops grouped by patterns, of logs of conditions, e.g. of a process
+// lifetime, in columns of the {hatchet}_ops table.  Ops of each pattern are counted by latency
+// buckets of histograms, merged into percentiles of the grouping shown by GetSlowOps.
+func (ptr *SQLite3DB) getOpsQuery(conds string) string {
+	sums := []string{}
+	totals := []string{}
+	for _, column := range SLOWOP_METRIC_COLUMNS {
+		sums = append(sums, fmt.Sprintf("SUM(%v) %v", column, column))
+		totals = append(totals, fmt.Sprintf("IFNULL(SUM(%v),0) %v", column, column))
+	}
+	bucket := "CASE"
+	for _, b := range getLatencyBuckets() {
+		bucket += fmt.Sprintf(" WHEN milli < %d THEN milli/%d*%d", b[0], b[1], b[1])
+	}
+	bucket += " ELSE milli END"
+	groups := SLOWOP_PATTERN + ", marker, query_hash, plan_cache_key, query_shape_hash"
+	return fmt.Sprintf(`SELECT op, SUM(count) count, ROUND(SUM(total_ms)*1.0/SUM(count),1) avg_ms, MAX(max_ms) max_ms,
+					SUM(total_ms) total_ms, ns, _index, SUM(reslen) reslen, filter, marker, query_hash, plan_cache_key,
+					query_shape_hash, pipeline, sort_shape, projection, limit_skip, hint, update_shape, write_flags,
+					GROUP_CONCAT(ms || ':' || count, ' ') histogram, %v
+				FROM (SELECT %v, %v ms, COUNT(*) count, SUM(milli) total_ms, MAX(milli) max_ms, SUM(reslen) reslen, %v
+					FROM %v WHERE op != "" %v GROUP BY %v, ms)
+				GROUP BY %v`, strings.Join(totals, ", "), groups, bucket, strings.Join(sums, ", "), ptr.hatchetName,
+		conds, groups, groups)
+}
+
 func (ptr *SQLite3DB) CreateMetaData() error {
 	log.Println("creating indexes and this may take minutes")
 	stmts, err := CreateIndexes(ptr.db, ptr.hatchetName)
@@ -373,10 +692,18 @@ func (ptr *SQLite3DB) CreateMetaData() error {
 		return err
 	}
 
+	// remove metadata of the previous run, failed messages are accumulated by InsertFailedMessages
+	stmt = fmt.Sprintf(`DELETE FROM %v_ops; DELETE FROM %v_migrations; DELETE FROM %v_audit WHERE type != 'failed';`,
+		ptr.hatchetName, ptr.hatchetName, ptr.hatchetName)
+	if _, err = ptr.db.Exec(stmt); err != nil {
+		return err
+	}
+
 	log.Printf("insert ops into %v_ops\n", ptr.hatchetName)
-	query := fmt.Sprintf(`INSERT INTO %v_ops
-			SELECT op, COUNT(*), ROUND(AVG(milli),1), MAX(milli), SUM(milli), ns, _index, SUM(reslen), filter, marker
-				FROM %v WHERE op != "" GROUP BY op, ns, filter, _index, marker`, ptr.hatchetName, ptr.hatchetName)
+	query := fmt.Sprintf(`INSERT INTO %v_ops (op, count, avg_ms, max_ms, total_ms, ns, _index, reslen, filter, marker,
+				query_hash, plan_cache_key, query_shape_hash, pipeline, sort_shape, projection, limit_skip, hint,
+				update_shape, write_flags, histogram, %v) %v`, ptr.hatchetName, strings.Join(SLOWOP_METRIC_COLUMNS, ", "),
+		ptr.getOpsQuery(""))
 	if ptr.verbose {
 		explain(ptr.db, query)
 	}
@@ -480,6 +807,45 @@ func (ptr *SQLite3DB) CreateMetaData() error {
 	if _, err = ptr.db.Exec(query); err != nil {
 		return err
 	}
+	return ptr.createShardingMetaData()
+}
+
+// createShardingMetaData summarizes chunk migrations by namespaces and donor/recipient pairs
+// into the migrations table, and counts of range deletions, balancer rounds and migration
+// decisions into the audit table
+func (ptr *SQLite3DB) createShardingMetaData() error {
+	var err error
+	log.Printf("insert migrations into %v_migrations\n", ptr.hatchetName)
+	query := fmt.Sprintf(`INSERT INTO %v_migrations (ns, donor, recipient, count, failed, avg_ms, max_ms, total_ms)
+		SELECT ns, donor, recipient, COUNT(*), SUM(CASE WHEN status = '%v' THEN 1 ELSE 0 END),
+				ROUND(AVG(milli),1), MAX(milli), SUM(milli)
+			FROM %v_sharding WHERE type = '%v' GROUP BY ns, donor, recipient`,
+		ptr.hatchetName, MIGRATION_FAILED, ptr.hatchetName, SHARDING_MIGRATION)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	if _, err = ptr.db.Exec(query); err != nil {
+		return err
+	}
+
+	for _, shardType := range []string{SHARDING_BALANCER, SHARDING_DECISION, SHARDING_RANGE_DELETION} {
+		name := "ns" // range deletions by namespaces
+		if shardType == SHARDING_BALANCER {
+			name = "name"
+		} else if shardType == SHARDING_DECISION {
+			name = "status"
+		}
+		log.Printf("insert [%v] into %v_audit\n", shardType, ptr.hatchetName)
+		query = fmt.Sprintf(`INSERT INTO %v_audit
+			SELECT '%v', %v, COUNT(*) count FROM %v_sharding WHERE type = '%v' GROUP by %v`,
+			ptr.hatchetName, shardType, name, ptr.hatchetName, shardType, name)
+		if ptr.verbose {
+			explain(ptr.db, query)
+		}
+		if _, err = ptr.db.Exec(query); err != nil {
+			return err
+		}
+	}
 	return err
 }
 
@@ -498,6 +864,8 @@ func CreateTables(db *sql.DB, hatchetName string) ([]string, error) {
 			merge integer,
 			created_at text);`,
 
+		CHECKPOINTS_TABLE,
+
 		`CREATE TABLE IF NOT EXISTS %v (
 			id integer not null,
 			date text,
@@ -515,13 +883,55 @@ func CreateTables(db *sql.DB, hatchetName string) ([]string, error) {
 			milli integer,
 			reslen integer,
 			appname text,
-			marker integer);`,
+			marker integer,
+			keys_examined integer,
+			docs_examined integer,
+			nreturned integer,
+			nmatched integer,
+			nmodified integer,
+			num_yields integer,
+			query_hash text,
+			plan_cache_key text,
+			query_shape_hash text,
+			has_sort_stage integer,
+			used_disk integer,
+			pipeline text,
+			sort_shape text,
+			projection text,
+			limit_skip text,
+			hint text,
+			bytes_read integer,
+			cpu_nanos integer,
+			flow_control_micros integer,
+			lock_wait_micros integer,
+			remote_wait_ms integer,
+			time_reading_micros integer,
+			update_shape text,
+			write_flags text,
+			keys_inserted integer,
+			keys_deleted integer,
+			ninserted integer,
+			ndeleted integer,
+			nupserted integer);`,
 
 		`CREATE TABLE IF NOT EXISTS %v_audit (
 			type text,
 			name text,
 			value integer );`,
 
+		`CREATE TABLE IF NOT EXISTS %v_auditlog (
+			id integer not null,
+			date text,
+			atype text,
+			category text,
+			user text,
+			roles text,
+			remote text,
+			target text,
+			param text,
+			result integer,
+			marker integer);`,
+
 		`CREATE TABLE IF NOT EXISTS %v_clients (
 			id integer not null,
 			ip text,
@@ -539,6 +949,48 @@ func CreateTables(db *sql.DB, hatchetName string) ([]string, error) {
 			version text,
 			marker integer);`,
 
+		`CREATE TABLE IF NOT EXISTS %v_errors (
+			id integer not null,
+			date text,
+			code integer,
+			name text,
+			category text,
+			ns text,
+			op text,
+			appname text,
+			ip text,
+			component text,
+			marker integer);`,
+
+		`CREATE TABLE IF NOT EXISTS %v_events (
+			id integer not null,
+			date text,
+			type text,
+			name text,
+			from_state text,
+			to_state text,
+			detail text,
+			component text,
+			context text,
+			marker integer);`,
+
+		`CREATE TABLE IF NOT EXISTS %v_metrics (
+			date text,
+			type text,
+			name text,
+			value numeric,
+			PRIMARY KEY (type, date, name));`,
+
+		`CREATE TABLE IF NOT EXISTS %v_migrations (
+			ns text,
+			donor text,
+			recipient text,
+			count integer,
+			failed integer,
+			avg_ms numeric,
+			max_ms integer,
+			total_ms integer);`,
+
 		`CREATE TABLE IF NOT EXISTS %v_ops (
 			op text,
 			count integer,
@@ -549,12 +1001,92 @@ func CreateTables(db *sql.DB, hatchetName string) ([]string, error) {
 			_index text,
 			reslen integer,
 			filter text,
+			marker integer,
+			keys_examined integer,
+			docs_examined integer,
+			nreturned integer,
+			nmatched integer,
+			nmodified integer,
+			num_yields integer,
+			histogram text,
+			query_hash text,
+			plan_cache_key text,
+			query_shape_hash text,
+			has_sort_stage integer,
+			used_disk integer,
+			pipeline text,
+			sort_shape text,
+			projection text,
+			limit_skip text,
+			hint text,
+			bytes_read integer,
+			cpu_nanos integer,
+			flow_control_micros integer,
+			lock_wait_micros integer,
+			remote_wait_ms integer,
+			time_reading_micros integer,
+			update_shape text,
+			write_flags text,
+			keys_inserted integer,
+			keys_deleted integer,
+			ninserted integer,
+			ndeleted integer,
+			nupserted integer);`,
+
+		`CREATE TABLE IF NOT EXISTS %v_sharding (
+			id integer not null,
+			date text,
+			type text,
+			name text,
+			ns text,
+			donor text,
+			recipient text,
+			milli integer,
+			status text,
+			detail text,
+			marker integer);`,
+
+		`CREATE TABLE IF NOT EXISTS %v_startup (
+			id integer not null,
+			date text,
+			type text,
+			name text,
+			value text,
+			marker integer);`,
+
+		`CREATE TABLE IF NOT EXISTS %v_storage (
+			id integer not null,
+			date text,
+			type text,
+			phase text,
+			milli integer,
+			pages integer,
+			mb integer,
+			detail text,
+			marker integer);`,
+
+		`CREATE TABLE IF NOT EXISTS %v_txns (
+			id integer not null,
+			date text,
+			lsid text,
+			txn_number integer,
+			autocommit text,
+			ns text,
+			op text,
+			termination text,
+			reason text,
+			milli integer,
+			time_active_micros integer,
+			time_inactive_micros integer,
+			write_conflicts integer,
+			prepare_conflict_ms integer,
+			was_prepared integer,
 			marker integer);`,
 	}
 	stmts := []string{}
 	for i, table := range tables {
 		stmt := table
-		if i > 0 {
+		if i > 1 {
 			stmt = fmt.Sprintf(table, hatchetName)
 		}
 		stmts = append(stmts, stmt)
@@ -580,12 +1112,19 @@ func CreateIndexes(db *sql.DB, hatchetName string) ([]string, error) {
 		"CREATE INDEX IF NOT EXISTS %v_idx_appname_reslen ON %v (appname,reslen);",
 
 		"CREATE INDEX IF NOT EXISTS %v_audit_idx_type_value ON %v_audit (type,value DESC);",
+		"CREATE INDEX IF NOT EXISTS %v_auditlog_idx_category_atype ON %v_auditlog (category,atype);",
 		"CREATE INDEX IF NOT EXISTS %v_clients_idx_ip_accepted ON %v_clients (ip,accepted);",
 		"CREATE INDEX IF NOT EXISTS %v_clients_idx_ip_context ON %v_clients (ip,context);",
 		"CREATE INDEX IF NOT EXISTS %v_drivers_idx_driver_version_ip ON %v_drivers (driver,version DESC,ip);",
+		"CREATE INDEX IF NOT EXISTS %v_errors_idx_name_date ON %v_errors (name,date);",
+		"CREATE INDEX IF NOT EXISTS %v_events_idx_date_marker ON %v_events (date,marker);",
 		"CREATE INDEX IF NOT EXISTS %v_ops_idx_avgms ON %v_ops (avg_ms);",
 
 		"CREATE INDEX IF NOT EXISTS %v_ops_idx_index ON %v_ops (_index);",
+		"CREATE INDEX IF NOT EXISTS %v_sharding_idx_type_date ON %v_sharding (type,date);",
+		"CREATE INDEX IF NOT EXISTS %v_startup_idx_marker_id ON %v_startup (marker,id);",
+		"CREATE INDEX IF NOT EXISTS %v_storage_idx_type_date ON %v_storage (type,date);",
+		"CREATE INDEX IF NOT EXISTS %v_txns_idx_lsid_date ON %v_txns (lsid,date);",
 	}
 	stmts := []string{}
 	for _, index := range indexes {
@@ -601,8 +1140,13 @@ func CreateIndexes(db *sql.DB, hatchetName string) ([]string, error) {
 // GetHatchetPreparedStmt returns prepared statement of the hatchet table
 func GetHatchetPreparedStmt(hatchetName string) string {
 	return fmt.Sprintf(`INSERT INTO %v (id, date, severity, component, context,
-		msg, plan, type, ns, message, op, filter, _index, milli, reslen, appname, marker)
-		VALUES(?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?)`, hatchetName)
+		msg, plan, type, ns, message, op, filter, _index, milli, reslen, appname, marker,
+		keys_examined, docs_examined, nreturned, nmatched, nmodified, num_yields,
+		query_hash, plan_cache_key, query_shape_hash, has_sort_stage, used_disk, pipeline,
+		sort_shape, projection, limit_skip, hint, bytes_read, cpu_nanos, flow_control_micros,
+		lock_wait_micros, remote_wait_ms, time_reading_micros, update_shape, write_flags,
+		keys_inserted, keys_deleted, ninserted, ndeleted, nupserted)
+		VALUES(?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?)`, hatchetName)
 }
 
 // GetClientPreparedStmt returns prepared statement of clients table
@@ -617,10 +1161,97 @@ func GetDriverPreparedStmt(hatchetName string) string {
 		VALUES(?,?,?,?,?)`, hatchetName)
 }
 
-func explain(db *sql.DB, query string) error {
+// GetAuditLogPreparedStmt returns prepared statement of auditlog table
+func GetAuditLogPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_auditlog (id, date, atype, category, user, roles, remote, target, param, result, marker)
+		VALUES(?,?,?,?,?, ?,?,?,?,?, ?)`, hatchetName)
+}
+
+// GetErrorPreparedStmt returns prepared statement of errors table
+func GetErrorPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_errors (id, date, code, name, category, ns, op, appname, ip, component, marker)
+		VALUES(?,?,?,?,?, ?,?,?,?,?, ?)`, hatchetName)
+}
+
+// GetReplEventPreparedStmt returns prepared statement of events table
+func GetReplEventPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_events (id, date, type, name, from_state, to_state, detail, component, context, marker)
+		VALUES(?,?,?,?,?, ?,?,?,?,?)`, hatchetName)
+}
+
+// GetMetricPreparedStmt returns prepared statement of metrics table
+func GetMetricPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT OR REPLACE INTO %v_metrics (date, type, name, value)
+		VALUES(?,?,?,?)`, hatchetName)
+}
+
+// GetShardEventPreparedStmt returns prepared statement of sharding table
+func GetShardEventPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_sharding (id, date, type, name, ns, donor, recipient, milli, status, detail, marker)
+		VALUES(?,?,?,?,?, ?,?,?,?,?, ?)`, hatchetName)
+}
+
+// GetStartupEventPreparedStmt returns prepared statement of startup table
+func GetStartupEventPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_startup (id, date, type, name, value, marker)
+		VALUES(?,?,?,?,?, ?)`, hatchetName)
+}
+
+// GetStorageEventPreparedStmt returns prepared statement of storage table
+func GetStorageEventPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_storage (id, date, type, phase, milli, pages, mb, detail, marker)
+		VALUES(?,?,?,?,?, ?,?,?,?)`, hatchetName)
+}
+
+// GetTransactionPreparedStmt returns prepared statement of txns table
+func GetTransactionPreparedStmt(hatchetName string) string {
+	return fmt.Sprintf(`INSERT INTO %v_txns (id, date, lsid, txn_number, autocommit, ns, op, termination, reason,
+		milli, time_active_micros, time_inactive_micros, write_conflicts, prepare_conflict_ms, was_prepared, marker)
+		VALUES(?,?,?,?,?, ?,?,?,?,?, ?,?,?,?,?, ?)`, hatchetName)
+}
+
+// addColumns adds integer columns missing from a table
+func (ptr *SQLite3DB) addColumns(table string, ctype string, columns []string) error {
+	rows, err := ptr.db.Query(fmt.Sprintf("PRAGMA table_info(%v)", table))
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var cid int
+		var name, ctype string
+		var notnull, pk int
+		var dflt interface{}
+		if err = rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	for _, column := range columns {
+		if existing[column] {
+			continue
+		}
+		log.Printf("migrating: adding %v column to %v table", column, table)
+		if _, err = ptr.db.Exec(fmt.Sprintf("ALTER TABLE %v ADD COLUMN %v %v", table, column, ctype)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableExists returns true if a table exists
+func (ptr *SQLite3DB) tableExists(table string) bool {
+	var count int
+	err := ptr.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&count)
+	return err == nil && count > 0
+}
+
+func explain(db *sql.DB, query string, args ...interface{}) error {
 	explainIt := "EXPLAIN QUERY PLAN " + query
 	log.Println(explainIt)
-	result, err := db.Query(explainIt)
+	result, err := db.Query(explainIt, args...)
 	if err != nil {
 		return err
 	}
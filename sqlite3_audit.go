@@ -101,6 +101,13 @@ func (ptr *SQLite3DB) GetAuditData() (map[string][]NameValues, error) {
 		rows.Close()
 	}
 
+	if err = ptr.getAuditLogData(data); err != nil {
+		return data, err
+	}
+	if err = ptr.getErrorData(data); err != nil {
+		return data, err
+	}
+
 	category = "ip"
 	query = fmt.Sprintf(`SELECT a.name ip, MAX(a.value) count, MAX(b.value) reslen, MAX(COALESCE(c.value, 0)) ended
 		FROM %v_audit a
@@ -206,3 +213,155 @@ func (ptr *SQLite3DB) GetAuditData() (map[string][]NameValues, error) {
 
 	return data, err
 }
+
+// getAuditLogData adds authentication failures, privilege changes, DDL events,
+// and activities by users from events of MongoDB auditLog
+func (ptr *SQLite3DB) getAuditLogData(data map[string][]NameValues) error {
+	if !ptr.tableExists(ptr.hatchetName + "_auditlog") { // hatchets of older versions
+		return nil
+	}
+	db := ptr.db
+	category := "authfail"
+	query := fmt.Sprintf(`SELECT CASE WHEN user = '' THEN 'unknown' ELSE user END, atype, remote, COUNT(*) count, MAX(date)
+		FROM %v_auditlog WHERE category = '%v' AND result != 0
+		GROUP BY user, atype, remote ORDER BY count DESC LIMIT %v;`, ptr.hatchetName, AUDIT_AUTH, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var doc NameValues
+		var atype, remote, last string
+		var count int
+		if err = rows.Scan(&doc.Name, &atype, &remote, &count, &last); err != nil {
+			rows.Close()
+			return err
+		}
+		doc.Values = append(doc.Values, atype, remote, count, last)
+		data[category] = append(data[category], doc)
+	}
+	rows.Close()
+
+	category = "privilege"
+	query = fmt.Sprintf(`SELECT atype, date, CASE WHEN user = '' THEN 'unknown' ELSE user END, target, result
+		FROM %v_auditlog WHERE category = '%v' ORDER BY date LIMIT %v;`, ptr.hatchetName, AUDIT_PRIVILEGE, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var doc NameValues
+		var date, user, target string
+		var result int
+		if err = rows.Scan(&doc.Name, &date, &user, &target, &result); err != nil {
+			rows.Close()
+			return err
+		}
+		doc.Values = append(doc.Values, date, user, target, result)
+		data[category] = append(data[category], doc)
+	}
+	rows.Close()
+
+	category = "ddl"
+	query = fmt.Sprintf(`SELECT atype, target, COUNT(*) count, MAX(date)
+		FROM %v_auditlog WHERE category = '%v' GROUP BY atype, target ORDER BY count DESC LIMIT %v;`,
+		ptr.hatchetName, AUDIT_DDL, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var doc NameValues
+		var target, last string
+		var count int
+		if err = rows.Scan(&doc.Name, &target, &count, &last); err != nil {
+			rows.Close()
+			return err
+		}
+		doc.Values = append(doc.Values, target, count, last)
+		data[category] = append(data[category], doc)
+	}
+	rows.Close()
+
+	category = "user"
+	query = fmt.Sprintf(`SELECT CASE WHEN user = '' THEN 'unknown' ELSE user END name, COUNT(*) count,
+			SUM(CASE WHEN result != 0 THEN 1 ELSE 0 END), COUNT(DISTINCT atype), MIN(date), MAX(date)
+		FROM %v_auditlog GROUP BY name ORDER BY count DESC LIMIT %v;`, ptr.hatchetName, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var doc NameValues
+		var count, failed, atypes int
+		var first, last string
+		if err = rows.Scan(&doc.Name, &count, &failed, &atypes, &first, &last); err != nil {
+			return err
+		}
+		doc.Values = append(doc.Values, count, failed, atypes, first, last)
+		data[category] = append(data[category], doc)
+	}
+	return nil
+}
+
+// getErrorData adds errors by names, and by namespaces, appNames and client IPs
+func (ptr *SQLite3DB) getErrorData(data map[string][]NameValues) error {
+	if !ptr.tableExists(ptr.hatchetName + "_errors") { // hatchets of older versions
+		return nil
+	}
+	db := ptr.db
+	category := "errors"
+	query := fmt.Sprintf(`SELECT name, MAX(code), category, COUNT(*) count, COUNT(DISTINCT ns), MAX(date)
+		FROM %v_errors GROUP BY name, category ORDER BY count DESC LIMIT %v;`, ptr.hatchetName, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var doc NameValues
+		var code, count, namespaces int
+		var errCategory, last string
+		if err = rows.Scan(&doc.Name, &code, &errCategory, &count, &namespaces, &last); err != nil {
+			rows.Close()
+			return err
+		}
+		doc.Values = append(doc.Values, code, errCategory, count, namespaces, last)
+		data[category] = append(data[category], doc)
+	}
+	rows.Close()
+
+	category = "error-sources"
+	query = fmt.Sprintf(`SELECT name, ns, CASE WHEN appname = '' THEN 'unknown' ELSE appname END app, ip, COUNT(*) count
+		FROM %v_errors GROUP BY name, ns, app, ip ORDER BY count DESC LIMIT %v;`, ptr.hatchetName, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var doc NameValues
+		var ns, appname, ip string
+		var count int
+		if err = rows.Scan(&doc.Name, &ns, &appname, &ip, &count); err != nil {
+			return err
+		}
+		doc.Values = append(doc.Values, ns, appname, ip, count)
+		data[category] = append(data[category], doc)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ package hatchet
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -20,40 +21,147 @@ type OpCount struct {
 	Filter    string  `bson:"filter"`
 }
 
-func (ptr *SQLite3DB) GetSlowOps(orderBy string, order string, collscan bool) ([]OpStat, error) {
+// GetSlowOps returns slow ops grouped by Hatchet query patterns, or by a column of
+// SLOWOP_GROUPS of which distinct indexes and patterns are separated by DISTINCT_SEPARATOR.
+// Options of duration=start,end and marker=n filter slow ops, e.g. of a process lifetime.
+func (ptr *SQLite3DB) GetSlowOps(orderBy string, order string, collscan bool, groupBy string, opts ...string) ([]OpStat, error) {
 	ops := []OpStat{}
 	db := ptr.db
-	query := fmt.Sprintf(`SELECT op, SUM(count) count, ROUND(AVG(avg_ms),1) avg_ms, MAX(max_ms) max_ms,
-			SUM(total_ms) total_ms, ns, _index "index", SUM(reslen) reslen, filter "query_pattern", MAX(marker) marker
-			FROM %v_ops GROUP BY op, ns, filter, _index ORDER BY %v %v`, ptr.hatchetName, orderBy, order)
+	// ops of a duration or a marker, e.g. of a process lifetime, are aggregated from logs
+	// instead of the {hatchet}_ops table of the whole hatchet
+	from := ptr.hatchetName + "_ops"
+	conds := ""
+	args := []interface{}{}
+	for _, opt := range opts {
+		toks := strings.Split(opt, "=")
+		if len(toks) < 2 || toks[1] == "" {
+			continue
+		}
+		if toks[0] == "duration" {
+			start, end, err := GetDuration(toks[1])
+			if err != nil {
+				return ops, err
+			}
+			conds += " AND date BETWEEN ? AND ?"
+			args = append(args, start, end)
+		} else if toks[0] == "marker" {
+			conds += fmt.Sprintf(" AND marker = %d", ToInt(toks[1]))
+		}
+	}
+	if conds != "" {
+		from = "(" + ptr.getOpsQuery(conds) + ")"
+	}
+	// examined per document returned or matched is sortable as in SetQueryTargeting, and
+	// percentiles are of histograms merged of the grouping, sorted after they are merged
+	ratio := "ROUND(IFNULL(SUM(%v),0)*1.0/MAX(IFNULL(SUM(nreturned),0)+IFNULL(SUM(nmatched),0),1),1)"
+	docsRatio := fmt.Sprintf(ratio, "docs_examined")
+	keysRatio := fmt.Sprintf(ratio, "keys_examined")
+	index := `_index "index"`
+	pattern := `filter "query_pattern"`
+	pipeline := `IFNULL(pipeline,"") pipeline`
+	shapes := `IFNULL(sort_shape,""), IFNULL(projection,""), IFNULL(limit_skip,""), IFNULL(hint,""),
+		IFNULL(update_shape,""), IFNULL(write_flags,"")`
+	groups := SLOWOP_PATTERN
+	wheres := []string{}
+	if groupBy != "" {
+		index = distinctConcat("_index") + ` "index"`
+		pattern = distinctConcat("filter") + ` "query_pattern"`
+		pipeline = `CASE WHEN COUNT(DISTINCT pipeline) = 1 THEN MAX(pipeline) ELSE "" END pipeline`
+		shapes = strings.Join([]string{distinctConcat("sort_shape"), distinctConcat("projection"),
+			distinctConcat("limit_skip"), distinctConcat("hint"), distinctConcat("update_shape"),
+			distinctConcat("write_flags")}, ", ")
+		groups = "op, ns, " + groupBy
+		wheres = append(wheres, fmt.Sprintf(`IFNULL(%v,"") != ""`, groupBy))
+	}
 	if collscan {
-		query = fmt.Sprintf(`SELECT op, SUM(count) count, ROUND(AVG(avg_ms),1) avg_ms, MAX(max_ms) max_ms,
-				SUM(total_ms) total_ms, ns, _index "index", SUM(reslen) reslen, filter "query_pattern", MAX(marker) marker
-				FROM %v_ops WHERE _index = "COLLSCAN" GROUP BY op, ns, filter, _index ORDER BY %v %v`, ptr.hatchetName, orderBy, order)
+		wheres = append(wheres, `_index = "COLLSCAN"`)
 	}
+	where := ""
+	if len(wheres) > 0 {
+		where = "WHERE " + strings.Join(wheres, " AND ")
+	}
+	sortBy := orderBy
+	if slices.Contains(PERCENTILE_COLUMNS, orderBy) {
+		sortBy = "max_ms"
+	}
+	query := fmt.Sprintf(`SELECT op, SUM(count) count, ROUND(SUM(total_ms)*1.0/SUM(count),1) avg_ms, MAX(max_ms) max_ms,
+			SUM(total_ms) total_ms, ns, %v, SUM(reslen) reslen, %v, MAX(marker) marker,
+			IFNULL(SUM(keys_examined),0) keys_examined, IFNULL(SUM(docs_examined),0) docs_examined,
+			IFNULL(SUM(nreturned),0) nreturned, IFNULL(SUM(nmatched),0) nmatched, IFNULL(SUM(nmodified),0) nmodified,
+			IFNULL(SUM(num_yields),0) num_yields,
+			IFNULL(GROUP_CONCAT(histogram, ' '),'') histogram,
+			%v docs_examined_returned, %v keys_examined_returned,
+			%v query_hash, %v plan_cache_key, %v query_shape_hash,
+			IFNULL(SUM(has_sort_stage),0) has_sort_stage, IFNULL(SUM(used_disk),0) used_disk, %v, %v,
+			IFNULL(SUM(bytes_read),0) bytes_read, IFNULL(SUM(cpu_nanos),0) cpu_nanos,
+			IFNULL(SUM(flow_control_micros),0) flow_control_micros, IFNULL(SUM(lock_wait_micros),0) lock_wait_micros,
+			IFNULL(SUM(remote_wait_ms),0) remote_wait_ms, IFNULL(SUM(time_reading_micros),0) time_reading_micros,
+			IFNULL(SUM(keys_inserted),0) keys_inserted, IFNULL(SUM(keys_deleted),0) keys_deleted,
+			IFNULL(SUM(ninserted),0) ninserted, IFNULL(SUM(ndeleted),0) ndeleted, IFNULL(SUM(nupserted),0) nupserted
+			FROM %v %v GROUP BY %v ORDER BY %v %v`, index, pattern, docsRatio, keysRatio,
+		distinctConcat("query_hash"), distinctConcat("plan_cache_key"), distinctConcat("query_shape_hash"),
+		pipeline, shapes, from, where, groups, sortBy, order)
 	if ptr.verbose {
-		explain(ptr.db, query)
+		explain(ptr.db, query, args...)
 	}
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return ops, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var op OpStat
+		var histogram string
 		if err = rows.Scan(&op.Op, &op.Count, &op.AvgMilli, &op.MaxMilli, &op.TotalMilli,
-			&op.Namespace, &op.Index, &op.Reslen, &op.QueryPattern, &op.Marker); err != nil {
+			&op.Namespace, &op.Index, &op.Reslen, &op.QueryPattern, &op.Marker,
+			&op.KeysExamined, &op.DocsExamined, &op.NReturned, &op.NMatched, &op.NModified, &op.NumYields,
+			&histogram, &op.DocsRatio, &op.KeysRatio,
+			&op.QueryHash, &op.PlanCacheKey, &op.QueryShapeHash,
+			&op.HasSortStage, &op.UsedDisk, &op.PipelineShape,
+			&op.SortShape, &op.Projection, &op.LimitSkip, &op.Hint, &op.UpdateShape, &op.WriteFlags,
+			&op.BytesRead, &op.CPUNanos, &op.FlowControlMicros, &op.LockWaitMicros, &op.RemoteOpWaitMillis,
+			&op.TimeReadingMicros, &op.KeysInserted, &op.KeysDeleted, &op.NInserted, &op.NDeleted,
+			&op.NUpserted); err != nil {
 			return ops, err
 		}
+		op.Histogram = parseHistogram(histogram)
+		SetPercentiles(&op)
+		SetPipeline(&op)
+		SetQueryTargeting(&op)
+		SetWriteAmplification(&op)
+		SetPlanFlip(&op, groupBy)
 		ops = append(ops, op)
 	}
+	if sortBy != orderBy {
+		SortByPercentile(ops, orderBy, order)
+	}
 	return ops, err
 }
 
+// parseHistogram parses latency buckets of histograms of ops, e.g. "100:2 120:1"
+func parseHistogram(histogram string) []LatencyBucket {
+	buckets := []LatencyBucket{}
+	for _, field := range strings.Fields(histogram) {
+		toks := strings.Split(field, ":")
+		if len(toks) == 2 {
+			buckets = append(buckets, LatencyBucket{Milli: ToInt(toks[0]), Count: ToInt(toks[1])})
+		}
+	}
+	return buckets
+}
+
+// distinctConcat concatenates distinct non-empty values of a column with DISTINCT_SEPARATOR,
+// of which GROUP_CONCAT(DISTINCT) only separates with commas that are also in values
+func distinctConcat(column string) string {
+	return fmt.Sprintf(`REPLACE(REPLACE(IFNULL(GROUP_CONCAT(DISTINCT NULLIF(REPLACE(%v,",",CHAR(31)),"")),""),",","%v"),CHAR(31),",")`,
+		column, DISTINCT_SEPARATOR)
+}
+
 func (ptr *SQLite3DB) GetLogs(opts ...string) ([]LegacyLog, error) {
 	docs := []LegacyLog{}
 	qheader := fmt.Sprintf(`SELECT date, severity, component, context, message, marker FROM %v`, ptr.hatchetName)
 	wheres := []string{}
+	args := []interface{}{}
 	search := ""
 	qlimit := LIMIT + 1
 	var offset, nlimit int
@@ -65,8 +173,14 @@ func (ptr *SQLite3DB) GetLogs(opts ...string) ([]LegacyLog, error) {
 				continue
 			}
 			if toks[0] == "duration" {
-				dates := strings.Split(toks[1], ",")
-				wheres = append(wheres, fmt.Sprintf(" date BETWEEN '%v' and '%v'", dates[0], dates[1]))
+				start, end, err := GetDuration(toks[1])
+				if err != nil {
+					return docs, err
+				}
+				wheres = append(wheres, " date BETWEEN ? and ?")
+				args = append(args, start, end)
+			} else if toks[0] == "marker" { // of a member of merged logs
+				wheres = append(wheres, fmt.Sprintf(" marker = %d", ToInt(toks[1])))
 			} else if toks[0] == "limit" {
 				offset, nlimit = GetOffsetLimit(toks[1])
 				qlimit = ToInt(nlimit) + 1
@@ -94,9 +208,9 @@ func (ptr *SQLite3DB) GetLogs(opts ...string) ([]LegacyLog, error) {
 	query := qheader + wclause + fmt.Sprintf(" ORDER BY date, marker LIMIT %v,%v", offset, qlimit)
 	db := ptr.db
 	if ptr.verbose {
-		explain(ptr.db, query)
+		explain(ptr.db, query, args...)
 	}
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return docs, err
 	}
@@ -118,7 +232,10 @@ func (ptr *SQLite3DB) GetLogs(opts ...string) ([]LegacyLog, error) {
 func (ptr *SQLite3DB) SearchLogs(opts ...string) ([]LegacyLog, error) {
 	qheader := fmt.Sprintf(`SELECT date, severity, component, context, message, marker FROM %v`, ptr.hatchetName)
 	docs := []LegacyLog{}
-	wheres := buildSearchWheres(opts)
+	wheres, args, err := buildSearchWheres(opts)
+	if err != nil {
+		return docs, err
+	}
 	qlimit := LIMIT + 1
 	var offset, nlimit int
 	for _, opt := range opts {
@@ -137,10 +254,10 @@ func (ptr *SQLite3DB) SearchLogs(opts ...string) ([]LegacyLog, error) {
 	}
 	query := qheader + wclause + fmt.Sprintf(" LIMIT %v,%v", offset, qlimit)
 	if ptr.verbose {
-		explain(ptr.db, query)
+		explain(ptr.db, query, args...)
 	}
 	db := ptr.db
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return docs, err
 	}
@@ -158,31 +275,41 @@ func (ptr *SQLite3DB) SearchLogs(opts ...string) ([]LegacyLog, error) {
 
 // CountLogs returns the total count of logs matching the search criteria
 func (ptr *SQLite3DB) CountLogs(opts ...string) (int, error) {
-	wheres := buildSearchWheres(opts)
+	wheres, args, err := buildSearchWheres(opts)
+	if err != nil {
+		return 0, err
+	}
 	wclause := ""
 	if len(wheres) > 0 {
 		wclause = " WHERE " + strings.Join(wheres, " AND")
 	}
 	query := fmt.Sprintf(`SELECT COUNT(*) FROM %v%v`, ptr.hatchetName, wclause)
 	if ptr.verbose {
-		explain(ptr.db, query)
+		explain(ptr.db, query, args...)
 	}
 	var count int
-	err := ptr.db.QueryRow(query).Scan(&count)
+	err = ptr.db.QueryRow(query, args...).Scan(&count)
 	return count, err
 }
 
-// buildSearchWheres builds WHERE clauses for search queries
-func buildSearchWheres(opts []string) []string {
+// buildSearchWheres builds WHERE clauses for search queries and their arguments
+func buildSearchWheres(opts []string) ([]string, []interface{}, error) {
 	wheres := []string{}
+	args := []interface{}{}
 	for _, opt := range opts {
 		toks := strings.Split(opt, "=")
 		if len(toks) < 2 || toks[1] == "" {
 			continue
 		}
 		if toks[0] == "duration" {
-			dates := strings.Split(toks[1], ",")
-			wheres = append(wheres, fmt.Sprintf(" date BETWEEN '%v' and '%v'", dates[0], dates[1]))
+			start, end, err := GetDuration(toks[1])
+			if err != nil {
+				return wheres, args, err
+			}
+			wheres = append(wheres, " date BETWEEN ? and ?")
+			args = append(args, start, end)
+		} else if toks[0] == "marker" {
+			wheres = append(wheres, fmt.Sprintf(" marker = %d", ToInt(toks[1])))
 		} else if toks[0] == "limit" {
 			// skip limit for WHERE clause
 			continue
@@ -201,7 +328,7 @@ func buildSearchWheres(opts []string) []string {
 			wheres = append(wheres, fmt.Sprintf(` %v = "%v"`, toks[0], EscapeString(toks[1])))
 		}
 	}
-	return wheres
+	return wheres, args, nil
 }
 
 func (ptr *SQLite3DB) GetSlowestLogs(topN int) ([]LegacyLog, error) {
@@ -228,7 +355,7 @@ func (ptr *SQLite3DB) GetSlowestLogs(topN int) ([]LegacyLog, error) {
 	return docs, err
 }
 
-func (ptr *SQLite3DB) GetAverageOpTime(op string, duration string) ([]OpCount, error) {
+func (ptr *SQLite3DB) GetAverageOpTime(op string, duration string, marker string) ([]OpCount, error) {
 	docs := []OpCount{}
 	db := ptr.db
 	durcond := ""
@@ -237,6 +364,9 @@ func (ptr *SQLite3DB) GetAverageOpTime(op string, duration string) ([]OpCount, e
 	if op != "" {
 		opcond = fmt.Sprintf("op = '%v'", op)
 	}
+	if marker != "" { // of a member of merged logs
+		opcond += fmt.Sprintf(" AND marker = %d", ToInt(marker))
+	}
 	if duration != "" {
 		toks := strings.Split(duration, ",")
 		durcond = fmt.Sprintf("AND date BETWEEN '%v' AND '%v'", toks[0], toks[1])
@@ -270,6 +400,291 @@ func (ptr *SQLite3DB) GetAverageOpTime(op string, duration string) ([]OpCount, e
 	return docs, err
 }
 
+// GetTimeBreakdown returns seconds of TIME_BREAKDOWN spent by slow ops over a period of time
+func (ptr *SQLite3DB) GetTimeBreakdown(op string, duration string) ([]Metric, error) {
+	docs := []Metric{}
+	durcond := ""
+	var substr string
+	opcond := "op != ''"
+	if op != "" {
+		opcond = fmt.Sprintf("op = '%v'", op)
+	}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		durcond = fmt.Sprintf("AND date BETWEEN '%v' AND '%v'", toks[0], toks[1])
+		substr = GetSQLDateSubString(toks[0], toks[1])
+	} else {
+		info := ptr.GetHatchetInfo()
+		substr = GetSQLDateSubString(info.Start, info.End)
+	}
+	toks := strings.Split(substr, "||")
+	groupby := substr
+	if len(toks) > 1 {
+		groupby = toks[0]
+	}
+	query := fmt.Sprintf(`SELECT %v dt, SUM(milli), IFNULL(SUM(cpu_nanos),0), IFNULL(SUM(time_reading_micros),0),
+			IFNULL(SUM(lock_wait_micros),0), IFNULL(SUM(flow_control_micros),0), IFNULL(SUM(remote_wait_ms),0)
+		FROM %v WHERE %v %v GROUP by %v ORDER BY dt;`, substr, ptr.hatchetName, opcond, durcond, groupby)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return docs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date string
+		var stat OpStat
+		if err = rows.Scan(&date, &stat.TotalMilli, &stat.CPUNanos, &stat.TimeReadingMicros,
+			&stat.LockWaitMicros, &stat.FlowControlMicros, &stat.RemoteOpWaitMillis); err != nil {
+			return docs, err
+		}
+		docs = append(docs, GetTimeBreakdownMetrics(date, stat)...)
+	}
+	return docs, err
+}
+
+// GetErrorRates returns counts of errors by names over a period of time, of a marker of merged
+// logs or of all markers if empty
+func (ptr *SQLite3DB) GetErrorRates(duration string, marker string) ([]Metric, error) {
+	docs := []Metric{}
+	if !ptr.tableExists(ptr.hatchetName + "_errors") { // hatchets of older versions
+		return docs, nil
+	}
+	wheres := []string{}
+	if marker != "" { // of a member of merged logs
+		wheres = append(wheres, fmt.Sprintf("marker = %d", ToInt(marker)))
+	}
+	var substr string
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		wheres = append(wheres, fmt.Sprintf("date BETWEEN '%v' AND '%v'", toks[0], toks[1]))
+		substr = GetSQLDateSubString(toks[0], toks[1])
+	} else {
+		info := ptr.GetHatchetInfo()
+		substr = GetSQLDateSubString(info.Start, info.End)
+	}
+	toks := strings.Split(substr, "||")
+	groupby := substr
+	if len(toks) > 1 {
+		groupby = toks[0]
+	}
+	where := ""
+	if len(wheres) > 0 {
+		where = "WHERE " + strings.Join(wheres, " AND ")
+	}
+	query := fmt.Sprintf(`SELECT %v dt, name, COUNT(*) FROM %v_errors %v GROUP by %v, name ORDER BY dt, name;`,
+		substr, ptr.hatchetName, where, groupby)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return docs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		doc := Metric{Type: "errors"}
+		if err = rows.Scan(&doc.Date, &doc.Name, &doc.Value); err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, err
+}
+
+// GetStorageMetrics returns average op time, the longest checkpoint running time and counts of
+// eviction warnings over a period of time, of the same time axis as GetAverageOpTime
+func (ptr *SQLite3DB) GetStorageMetrics(duration string) ([]Metric, error) {
+	docs := []Metric{}
+	if !ptr.tableExists(ptr.hatchetName + "_storage") { // hatchets of older versions
+		return docs, nil
+	}
+	durcond := ""
+	var substr string
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		durcond = fmt.Sprintf("AND date BETWEEN '%v' AND '%v'", toks[0], toks[1])
+		substr = GetSQLDateSubString(toks[0], toks[1])
+	} else {
+		info := ptr.GetHatchetInfo()
+		substr = GetSQLDateSubString(info.Start, info.End)
+	}
+	toks := strings.Split(substr, "||")
+	groupby := substr
+	if len(toks) > 1 {
+		groupby = toks[0]
+	}
+	query := fmt.Sprintf(`SELECT %v dt, '%v', ROUND(AVG(milli)/1000.0,3) FROM %v WHERE op != '' %v GROUP by %v
+		UNION ALL
+		SELECT %v dt, '%v', MAX(milli)/1000.0 FROM %v_storage WHERE type = '%v' AND milli > 0 %v GROUP by %v
+		UNION ALL
+		SELECT %v dt, '%v', COUNT(*) FROM %v_storage WHERE type = '%v' %v GROUP by %v
+		ORDER BY dt;`,
+		substr, SERIES_AVG_OP_TIME, ptr.hatchetName, durcond, groupby,
+		substr, SERIES_CHECKPOINT, ptr.hatchetName, STORAGE_CHECKPOINT, durcond, groupby,
+		substr, SERIES_EVICTION, ptr.hatchetName, STORAGE_EVICTION, durcond, groupby)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return docs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		doc := Metric{Type: "storage"}
+		if err = rows.Scan(&doc.Date, &doc.Name, &doc.Value); err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, err
+}
+
+// GetReplEvents returns replica set events of a type, or of all types if empty, of a marker or of
+// all markers if empty, ordered by date and marker so that events of members of merged logs line up
+func (ptr *SQLite3DB) GetReplEvents(eventType string, duration string, marker string) ([]ReplEvent, error) {
+	docs := []ReplEvent{}
+	if !ptr.tableExists(ptr.hatchetName + "_events") { // hatchets of older versions
+		return docs, nil
+	}
+	wheres := []string{}
+	if eventType != "" {
+		wheres = append(wheres, fmt.Sprintf("type = '%v'", strings.ReplaceAll(eventType, "'", "''")))
+	}
+	if marker != "" { // of a member of merged logs
+		wheres = append(wheres, fmt.Sprintf("marker = %d", ToInt(marker)))
+	}
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		wheres = append(wheres, fmt.Sprintf("date BETWEEN '%v' AND '%v'", toks[0], toks[1]))
+	}
+	where := ""
+	if len(wheres) > 0 {
+		where = "WHERE " + strings.Join(wheres, " AND ")
+	}
+	query := fmt.Sprintf(`SELECT date, type, name, from_state, to_state, detail, component, context, marker
+		FROM %v_events %v ORDER BY date, marker, id;`, ptr.hatchetName, where)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return docs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var doc ReplEvent
+		if err = rows.Scan(&doc.Date, &doc.Type, &doc.Name, &doc.FromState, &doc.ToState, &doc.Detail,
+			&doc.Component, &doc.Context, &doc.Marker); err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, err
+}
+
+// GetProcessLifetimes returns process lifetimes of each marker segmented by restarts, with their
+// shutdowns, fatal errors and unclean shutdowns
+func (ptr *SQLite3DB) GetProcessLifetimes() ([]ProcessLifetime, error) {
+	spans := []ProcessLifetime{}
+	query := fmt.Sprintf(`SELECT IFNULL(marker, 0), MIN(date), MAX(date) FROM %v GROUP BY marker ORDER BY marker;`,
+		ptr.hatchetName)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return spans, err
+	}
+	for rows.Next() {
+		var span ProcessLifetime
+		if err = rows.Scan(&span.Marker, &span.Start, &span.End); err != nil {
+			rows.Close()
+			return spans, err
+		}
+		spans = append(spans, span)
+	}
+	rows.Close()
+	if !ptr.tableExists(ptr.hatchetName + "_events") { // hatchets of older versions
+		return SegmentLifetimes(spans, nil, nil), nil
+	}
+
+	events := []ReplEvent{}
+	priors := []string{}
+	query = fmt.Sprintf(`SELECT e.date, e.type, e.detail, IFNULL(e.marker, 0),
+			CASE WHEN e.type = '%v' THEN IFNULL((SELECT MAX(l.date) FROM %v l
+				WHERE IFNULL(l.marker, 0) = IFNULL(e.marker, 0) AND l.date < e.date AND l.context != 'main'), '')
+			ELSE '' END
+		FROM %v_events e WHERE e.type IN ('%v') ORDER BY e.marker, e.date, e.id;`,
+		EVENT_RESTART, ptr.hatchetName, ptr.hatchetName, strings.Join(PROCESS_EVENT_TYPES, "','"))
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	if rows, err = ptr.db.Query(query); err != nil {
+		return spans, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event ReplEvent
+		var prior string
+		if err = rows.Scan(&event.Date, &event.Type, &event.Detail, &event.Marker, &prior); err != nil {
+			return spans, err
+		}
+		events = append(events, event)
+		priors = append(priors, prior)
+	}
+	return SegmentLifetimes(spans, events, priors), nil
+}
+
+// GetMetrics returns averaged FTDC metrics of a chart type over a period of time
+func (ptr *SQLite3DB) GetMetrics(chartType string, duration string) ([]Metric, error) {
+	docs := []Metric{}
+	if !ptr.tableExists(ptr.hatchetName + "_metrics") { // hatchets of older versions
+		return docs, nil
+	}
+	durcond := ""
+	var substr string
+	if duration != "" {
+		toks := strings.Split(duration, ",")
+		durcond = fmt.Sprintf("AND date BETWEEN '%v' AND '%v'", toks[0], toks[1])
+		substr = GetSQLDateSubString(toks[0], toks[1])
+	} else {
+		var start, end string
+		query := fmt.Sprintf("SELECT IFNULL(MIN(date), ''), IFNULL(MAX(date), '') FROM %v_metrics WHERE type = '%v'",
+			ptr.hatchetName, chartType)
+		if err := ptr.db.QueryRow(query).Scan(&start, &end); err != nil {
+			return docs, err
+		}
+		substr = GetSQLDateSubString(start, end)
+	}
+	toks := strings.Split(substr, "||")
+	groupby := substr
+	if len(toks) > 1 {
+		groupby = toks[0]
+	}
+	query := fmt.Sprintf(`SELECT %v dt, name, ROUND(AVG(value), 2) FROM %v_metrics
+		WHERE type = '%v' %v GROUP by %v, name ORDER BY dt;`, substr, ptr.hatchetName, chartType, durcond, groupby)
+	if ptr.verbose {
+		explain(ptr.db, query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return docs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		doc := Metric{Type: chartType}
+		if err = rows.Scan(&doc.Date, &doc.Name, &doc.Value); err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, err
+}
+
 func (ptr *SQLite3DB) GetHatchetInfo() HatchetInfo {
 	var info HatchetInfo
 	query := fmt.Sprintf("SELECT name, version, module, os, arch, start, end, merge FROM hatchet WHERE name = '%v'",
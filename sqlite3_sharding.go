@@ -0,0 +1,125 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * sqlite3_sharding.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// GetShardingStats returns chunk migrations by collections and donor/recipient pairs, stale
+// config errors, range deletions, balancer rounds, migration decisions and resharding progress
+func (ptr *SQLite3DB) GetShardingStats() (*ShardingStats, error) {
+	stats := &ShardingStats{}
+	if !ptr.tableExists(ptr.hatchetName + "_migrations") { // hatchets of older versions
+		return stats, nil
+	}
+	db := ptr.db
+	query := fmt.Sprintf(`SELECT ns, SUM(count), SUM(failed), ROUND(1.0*SUM(total_ms)/SUM(count),1) avg_ms,
+			MAX(max_ms), SUM(total_ms) total_ms
+		FROM %v_migrations GROUP BY ns ORDER BY total_ms DESC LIMIT %v;`, ptr.hatchetName, TOP_N)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var doc MigrationStat
+		if err = rows.Scan(&doc.Namespace, &doc.Count, &doc.Failed, &doc.AvgMilli, &doc.MaxMilli,
+			&doc.TotalMilli); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.Collections = append(stats.Collections, doc)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT ns, donor, recipient, count, failed, avg_ms, max_ms, total_ms
+		FROM %v_migrations ORDER BY total_ms DESC LIMIT %v;`, ptr.hatchetName, TOP_N)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var doc MigrationStat
+		if err = rows.Scan(&doc.Namespace, &doc.Donor, &doc.Recipient, &doc.Count, &doc.Failed, &doc.AvgMilli,
+			&doc.MaxMilli, &doc.TotalMilli); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.Pairs = append(stats.Pairs, doc)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT type, name, value FROM %v_audit WHERE type IN ('%v', '%v', '%v')
+		ORDER BY type, value DESC;`, ptr.hatchetName, SHARDING_BALANCER, SHARDING_DECISION, SHARDING_RANGE_DELETION)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var shardType string
+		var doc NameValue
+		if err = rows.Scan(&shardType, &doc.Name, &doc.Value); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		if shardType == SHARDING_BALANCER {
+			stats.Balancer = append(stats.Balancer, doc)
+		} else if shardType == SHARDING_DECISION {
+			stats.Decisions = append(stats.Decisions, doc)
+		} else {
+			stats.RangeDeletions = append(stats.RangeDeletions, doc)
+		}
+	}
+	rows.Close()
+
+	// the minute of a namespace is of the row of MAX(cnt), a bare column of SQLite aggregates
+	query = fmt.Sprintf(`SELECT ns, SUM(cnt) count, MAX(cnt), minute
+		FROM (SELECT ns, SUBSTR(date, 1, 16) minute, COUNT(*) cnt FROM %v_errors WHERE name IN ('%v')
+			GROUP BY ns, minute)
+		GROUP BY ns ORDER BY count DESC LIMIT %v;`, ptr.hatchetName, strings.Join(STALE_CONFIG_ERRORS, "','"), TOP_N)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var doc StaleConfigStat
+		if err = rows.Scan(&doc.Namespace, &doc.Count, &doc.Peak, &doc.PeakMinute); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.StaleConfig = append(stats.StaleConfig, doc)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT date, type, name, ns, detail, marker FROM %v_sharding WHERE type = '%v'
+		ORDER BY date, id LIMIT %v;`, ptr.hatchetName, SHARDING_RESHARDING, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var doc ShardEvent
+		if err = rows.Scan(&doc.Date, &doc.Type, &doc.Name, &doc.Namespace, &doc.Detail, &doc.Marker); err != nil {
+			return stats, err
+		}
+		stats.Resharding = append(stats.Resharding, doc)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * sqlite3_startup.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"log"
+)
+
+// GetStartupDigests returns process info, options, storage engine settings, startup warnings
+// and the feature compatibility version of each restart
+func (ptr *SQLite3DB) GetStartupDigests() ([]StartupDigest, error) {
+	digests := []StartupDigest{}
+	if !ptr.tableExists(ptr.hatchetName + "_startup") { // hatchets of older versions
+		return digests, nil
+	}
+	query := fmt.Sprintf(`SELECT marker, date, type, name, value FROM %v_startup ORDER BY marker, id, rowid;`,
+		ptr.hatchetName)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	rows, err := ptr.db.Query(query)
+	if err != nil {
+		return digests, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var marker int
+		var date, stype string
+		var setting StartupSetting
+		if err = rows.Scan(&marker, &date, &stype, &setting.Name, &setting.Value); err != nil {
+			return digests, err
+		}
+		digests = addStartupSetting(digests, marker, date, stype, setting)
+	}
+	return digests, nil
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * sqlite3_txns.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"log"
+)
+
+// GetTransactionStats returns abort reasons, long-running transactions, conflict hot-spot
+// namespaces and retry storms
+func (ptr *SQLite3DB) GetTransactionStats() (*TransactionStats, error) {
+	stats := &TransactionStats{}
+	if !ptr.tableExists(ptr.hatchetName + "_txns") { // hatchets of older versions
+		return stats, nil
+	}
+	db := ptr.db
+	query := fmt.Sprintf(`SELECT termination, COUNT(*) FROM %v_txns WHERE termination != '' GROUP BY termination;`,
+		ptr.hatchetName)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var termination string
+		var count int
+		if err = rows.Scan(&termination, &count); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		if termination == TXN_ABORTED {
+			stats.Aborted = count
+		} else if termination == TXN_COMMITTED {
+			stats.Committed = count
+		}
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT reason, COUNT(*) count FROM %v_txns WHERE termination = '%v'
+		GROUP BY reason ORDER BY count DESC LIMIT %v;`, ptr.hatchetName, TXN_ABORTED, LIMIT)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var doc NameValue
+		if err = rows.Scan(&doc.Name, &doc.Value); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.AbortReasons = append(stats.AbortReasons, doc)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT date, lsid, txn_number, autocommit, termination, reason, milli,
+			time_active_micros, time_inactive_micros, write_conflicts, prepare_conflict_ms, was_prepared
+		FROM %v_txns WHERE termination != '' ORDER BY milli DESC LIMIT %v;`, ptr.hatchetName, TOP_N)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var txn Transaction
+		if err = rows.Scan(&txn.Date, &txn.LSID, &txn.TxnNumber, &txn.Autocommit, &txn.Termination, &txn.Reason,
+			&txn.Milli, &txn.TimeActiveMicros, &txn.TimeInactiveMicros, &txn.WriteConflicts,
+			&txn.PrepareConflictMillis, &txn.WasPrepared); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.LongRunning = append(stats.LongRunning, txn)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT ns, COUNT(*), SUM(write_conflicts) conflicts, SUM(prepare_conflict_ms) prepare_ms, SUM(milli)
+		FROM %v_txns WHERE ns != '' AND (write_conflicts > 0 OR prepare_conflict_ms > 0)
+		GROUP BY ns ORDER BY conflicts DESC, prepare_ms DESC LIMIT %v;`, ptr.hatchetName, TOP_N)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var doc TxnHotSpot
+		if err = rows.Scan(&doc.Namespace, &doc.Count, &doc.WriteConflicts, &doc.PrepareConflictMillis,
+			&doc.TotalMilli); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.HotSpots = append(stats.HotSpots, doc)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf(`SELECT lsid, SUBSTR(date, 1, 16) minute, COUNT(*),
+			SUM(CASE WHEN termination = '%v' THEN 1 ELSE 0 END) aborted, COUNT(DISTINCT txn_number), SUM(write_conflicts)
+		FROM %v_txns WHERE lsid != '' GROUP BY lsid, minute HAVING aborted >= %v
+		ORDER BY aborted DESC, minute LIMIT %v;`, TXN_ABORTED, ptr.hatchetName, RETRY_STORM_ABORTS, TOP_N)
+	if ptr.verbose {
+		log.Println(query)
+	}
+	if rows, err = db.Query(query); err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var doc RetryStorm
+		if err = rows.Scan(&doc.LSID, &doc.Minute, &doc.Count, &doc.Aborted, &doc.TxnNumbers,
+			&doc.WriteConflicts); err != nil {
+			return stats, err
+		}
+		stats.RetryStorms = append(stats.RetryStorms, doc)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * startup.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	STARTUP_FCV     = "fcv"
+	STARTUP_OPTIONS = "options"
+	STARTUP_PROCESS = "process" // MongoDB starting, Build Info and Operating System
+	STARTUP_STORAGE = "storage"
+	STARTUP_WARNING = "warning"
+
+	STARTUP_PID = "pid" // first setting of a restart
+)
+
+// messages of startup events regardless of their contexts
+var startupMessages = map[string]bool{"MongoDB starting": true, "Build Info": true, "Operating System": true,
+	"Options set by command line": true, "Opening WiredTiger": true, "Wired Tiger Opening": true,
+	"Updated FCV document": true}
+
+// settings of the WiredTiger opening config kept in startup digests
+var wiredTigerSettings = []string{"cache_size", "compatibility", "eviction", "eviction_dirty_target",
+	"eviction_dirty_trigger", "file_manager", "log", "session_max", "statistics_log"}
+
+// lower-cased phrases of startup warnings and their short names
+var startupWarnings = [][]string{
+	{"access control", "access control disabled"},
+	{"bound to localhost", "bound to localhost"},
+	{"max_map_count", "vm.max_map_count"},
+	{"numa", "NUMA"},
+	{"rlimits", "ulimit"},
+	{"root user", "running as root"},
+	{"swappiness", "swappiness"},
+	{"transparent_hugepage", "THP"},
+	{"xfs", "XFS"},
+}
+
+// StartupSetting is a name and a value of a startup configuration
+type StartupSetting struct {
+	Name  string `json:"name" bson:"name"`
+	Value string `json:"value" bson:"value"`
+}
+
+// StartupEvent stores settings of a startup message, e.g. options set by command line
+type StartupEvent struct {
+	Date     string           `json:"date" bson:"date"`
+	Settings []StartupSetting `json:"settings" bson:"settings"`
+	Type     string           `json:"type" bson:"type"` // fcv, options, process, storage or warning
+
+	Marker int `json:"-" bson:"marker"`
+}
+
+// StartupDigest is the configuration of a process lifetime, from a restart to the next one
+type StartupDigest struct {
+	Date     string           `json:"date"` // of the restart, or of the first startup message
+	FCV      string           `json:"fcv"`
+	Marker   int              `json:"marker"`
+	Options  []StartupSetting `json:"options"`
+	Process  []StartupSetting `json:"process"`
+	Storage  []StartupSetting `json:"storage"`
+	Warnings []StartupSetting `json:"warnings"`
+}
+
+// GetStartupEvent returns the process info, options, storage engine settings, a startup warning or
+// the feature compatibility version of a log line, or nil if it is none of them
+func GetStartupEvent(doc *Logv2Info) *StartupEvent {
+	if doc.Context != "initandlisten" && !startupMessages[doc.Msg] &&
+		!strings.Contains(strings.ToLower(doc.Msg), "featurecompatibilityversion") {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	event := &StartupEvent{Marker: doc.Marker}
+	if doc.Msg == "MongoDB starting" {
+		event.Type = STARTUP_PROCESS
+		event.Settings = append(event.Settings, StartupSetting{STARTUP_PID, fmt.Sprint(attrMap["pid"])})
+		for _, key := range []string{"port", "dbPath", "architecture", "host"} {
+			if value, ok := attrMap[key]; ok {
+				event.Settings = append(event.Settings, StartupSetting{key, fmt.Sprint(value)})
+			}
+		}
+	} else if doc.Msg == "Build Info" {
+		buildInfo, _ := attrMap["buildInfo"].(bson.M)
+		event.Type = STARTUP_PROCESS
+		if version, ok := buildInfo["version"].(string); ok {
+			event.Settings = append(event.Settings, StartupSetting{"version", version})
+		}
+		if modules, ok := buildInfo["modules"].([]interface{}); ok && len(modules) > 0 {
+			event.Settings = append(event.Settings, StartupSetting{"modules", joinValues(modules)})
+		}
+		if env, ok := buildInfo["environment"].(bson.M); ok {
+			if distmod, ok := env["distmod"].(string); ok {
+				event.Settings = append(event.Settings, StartupSetting{"distmod", distmod})
+			}
+		}
+	} else if doc.Msg == "Operating System" {
+		event.Type = STARTUP_PROCESS
+		if osInfo, ok := attrMap["os"].(bson.M); ok {
+			event.Settings = append(event.Settings, StartupSetting{"os", strings.TrimSpace(fmt.Sprintf("%v %v",
+				osInfo["name"], osInfo["version"]))})
+		}
+	} else if doc.Msg == "Options set by command line" {
+		event.Type = STARTUP_OPTIONS
+		if options, ok := attrMap["options"].(bson.M); ok {
+			event.Settings = flattenOptions("", options, event.Settings)
+		}
+	} else if doc.Msg == "Opening WiredTiger" || doc.Msg == "Wired Tiger Opening" {
+		event.Type = STARTUP_STORAGE
+		config, _ := attrMap["config"].(string)
+		for _, entry := range splitWiredTigerConfig(config) {
+			name, value, _ := strings.Cut(entry, "=")
+			for _, setting := range wiredTigerSettings {
+				if name == setting {
+					event.Settings = append(event.Settings, StartupSetting{name, value})
+				}
+			}
+		}
+	} else if engine, ok := attrMap["storageEngine"].(string); ok && doc.Context == "initandlisten" {
+		event.Type = STARTUP_STORAGE
+		event.Settings = append(event.Settings, StartupSetting{"engine", engine})
+	} else if strings.Contains(strings.ToLower(doc.Msg), "featurecompatibilityversion") || doc.Msg == "Updated FCV document" {
+		event.Type = STARTUP_FCV
+		for _, key := range []string{"newVersion", "version", "featureCompatibilityVersion"} {
+			if value, ok := attrMap[key].(string); ok {
+				event.Settings = append(event.Settings, StartupSetting{STARTUP_FCV, value})
+				break
+			} else if value, ok := attrMap[key].(bson.M); ok && value["version"] != nil {
+				event.Settings = append(event.Settings, StartupSetting{STARTUP_FCV, fmt.Sprint(value["version"])})
+				break
+			}
+		}
+	} else if doc.Context == "initandlisten" && (doc.Severity == "W" || strings.HasPrefix(doc.Msg, "** WARNING:")) {
+		event.Type = STARTUP_WARNING
+		text := strings.TrimSpace(strings.TrimPrefix(doc.Msg, "** WARNING:"))
+		name := "other"
+		lower := strings.ToLower(text)
+		for _, warning := range startupWarnings {
+			if strings.Contains(lower, warning[0]) {
+				name = warning[1]
+				break
+			}
+		}
+		event.Settings = append(event.Settings, StartupSetting{name, text})
+	}
+	if len(event.Settings) == 0 {
+		return nil
+	}
+	return event
+}
+
+// flattenOptions appends options of nested documents as dotted names sorted alphabetically
+func flattenOptions(prefix string, options bson.M, settings []StartupSetting) []StartupSetting {
+	keys := []string{}
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		switch value := options[key].(type) {
+		case bson.M:
+			settings = flattenOptions(name, value, settings)
+		case []interface{}:
+			settings = append(settings, StartupSetting{name, joinValues(value)})
+		default:
+			settings = append(settings, StartupSetting{name, fmt.Sprint(value)})
+		}
+	}
+	return settings
+}
+
+// joinValues joins values of an array with commas
+func joinValues(values []interface{}) string {
+	strs := []string{}
+	for _, value := range values {
+		strs = append(strs, fmt.Sprint(value))
+	}
+	return strings.Join(strs, ", ")
+}
+
+// splitWiredTigerConfig splits a WiredTiger config by commas outside of parentheses, e.g.
+// create,cache_size=7680M,eviction=(threads_min=4,threads_max=4)
+func splitWiredTigerConfig(config string) []string {
+	entries := []string{}
+	depth, start := 0, 0
+	for i, c := range config {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			depth--
+		} else if c == ',' && depth == 0 {
+			entries = append(entries, config[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(config) {
+		entries = append(entries, config[start:])
+	}
+	return entries
+}
+
+// addStartupSetting adds a setting, ordered by markers and lines, to the digest of its process lifetime;
+// a new digest begins at a restart or at the first setting of a log of another marker
+func addStartupSetting(digests []StartupDigest, marker int, date string, stype string, setting StartupSetting) []StartupDigest {
+	n := len(digests)
+	if n == 0 || digests[n-1].Marker != marker || (stype == STARTUP_PROCESS && setting.Name == STARTUP_PID) {
+		digests = append(digests, StartupDigest{Date: date, Marker: marker})
+		n++
+	}
+	digest := &digests[n-1]
+	switch stype {
+	case STARTUP_FCV:
+		digest.FCV = setting.Value
+	case STARTUP_OPTIONS:
+		digest.Options = append(digest.Options, setting)
+	case STARTUP_PROCESS:
+		digest.Process = append(digest.Process, setting)
+	case STARTUP_STORAGE:
+		digest.Storage = append(digest.Storage, setting)
+	case STARTUP_WARNING:
+		digest.Warnings = append(digest.Warnings, setting)
+	}
+	return digests
+}
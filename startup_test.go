@@ -0,0 +1,108 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * startup_test.go
+ */
+
+package hatchet
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var startupLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":1001,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"node1"}}`,
+	`{"t":{"$date":"2024-03-18T10:00:00.001+00:00"},"s":"I","c":"CONTROL","id":23403,"ctx":"initandlisten","msg":"Build Info","attr":{"buildInfo":{"version":"6.0.5","modules":["enterprise"],"environment":{"distmod":"rhel80","distarch":"x86_64","target_arch":"x86_64"}}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:00.002+00:00"},"s":"I","c":"CONTROL","id":51765,"ctx":"initandlisten","msg":"Operating System","attr":{"os":{"name":"Red Hat Enterprise Linux release 8.6","version":"Kernel 4.18.0"}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:00.003+00:00"},"s":"I","c":"CONTROL","id":21951,"ctx":"initandlisten","msg":"Options set by command line","attr":{"options":{"net":{"bindIp":"*","port":27017},"replication":{"replSetName":"rs0"},"storage":{"dbPath":"/data/db","wiredTiger":{"engineConfig":{"cacheSizeGB":8}}}}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:00.004+00:00"},"s":"I","c":"STORAGE","id":22315,"ctx":"initandlisten","msg":"Opening WiredTiger","attr":{"config":"create,cache_size=8192M,session_max=33000,eviction=(threads_min=4,threads_max=4),config_base=false,statistics=(fast),log=(enabled=true,remove=true,path=journal,compressor=snappy),"}}`,
+	`{"t":{"$date":"2024-03-18T10:00:00.005+00:00"},"s":"W","c":"CONTROL","id":22120,"ctx":"initandlisten","msg":"Access control is not enabled for the database. Read and write access to data and configuration is unrestricted","tags":["startupWarnings"]}`,
+	`{"t":{"$date":"2024-03-18T10:00:00.006+00:00"},"s":"W","c":"CONTROL","id":22178,"ctx":"initandlisten","msg":"/sys/kernel/mm/transparent_hugepage/enabled is 'always'. We suggest setting it to 'never'","tags":["startupWarnings"]}`,
+	`{"t":{"$date":"2024-03-18T10:00:01.000+00:00"},"s":"I","c":"REPL","id":20459,"ctx":"ReplCoord-0","msg":"Setting featureCompatibilityVersion","attr":{"newVersion":"6.0"}}`,
+	`{"t":{"$date":"2024-03-18T10:05:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":100}}`,
+	`{"t":{"$date":"2024-03-18T11:00:00.000+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":2002,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"node1"}}`,
+	`{"t":{"$date":"2024-03-18T11:00:00.001+00:00"},"s":"I","c":"CONTROL","id":23403,"ctx":"initandlisten","msg":"Build Info","attr":{"buildInfo":{"version":"7.0.2","environment":{"distmod":"rhel80"}}}}`,
+	`{"t":{"$date":"2024-03-18T11:00:00.003+00:00"},"s":"I","c":"CONTROL","id":21951,"ctx":"initandlisten","msg":"Options set by command line","attr":{"options":{"net":{"bindIp":"localhost","port":27017},"security":{"authorization":"enabled"}}}}`,
+	`{"t":{"$date":"2024-03-18T11:00:00.005+00:00"},"s":"W","c":"STORAGE","id":22297,"ctx":"initandlisten","msg":"Using the XFS filesystem is strongly recommended with the WiredTiger storage engine","tags":["startupWarnings"]}`,
+}
+
+func TestGetStartupEvent(t *testing.T) {
+	types := []string{STARTUP_PROCESS, STARTUP_PROCESS, STARTUP_PROCESS, STARTUP_OPTIONS, STARTUP_STORAGE,
+		STARTUP_WARNING, STARTUP_WARNING, STARTUP_FCV, ""}
+	for i, str := range startupLogs[:len(types)] {
+		doc := Logv2Info{}
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		event := GetStartupEvent(&doc)
+		if types[i] == "" && event != nil {
+			t.Fatalf("expected no event of %v, got %+v", doc.Msg, event)
+		} else if types[i] != "" && (event == nil || event.Type != types[i]) {
+			t.Fatalf("expected %v event of line %d, got %+v", types[i], i+1, event)
+		}
+	}
+	doc := Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(startupLogs[4]), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	storage := GetStartupEvent(&doc).Settings
+	if len(storage) != 4 || storage[0] != (StartupSetting{"cache_size", "8192M"}) ||
+		storage[2] != (StartupSetting{"eviction", "(threads_min=4,threads_max=4)"}) {
+		t.Fatalf("unexpected WiredTiger settings %+v", storage)
+	}
+
+	line := `2021-07-25T09:55:00.000+0000 I  CONTROL  [initandlisten] options: { net: { bindIp: "0.0.0.0", port: 27017 }, replication: { replSetName: "rs0" } }`
+	if err := ParseTextLog(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+	options := GetStartupEvent(&doc).Settings
+	if len(options) != 3 || options[0] != (StartupSetting{"net.bindIp", "0.0.0.0"}) ||
+		options[2] != (StartupSetting{"replication.replSetName", "rs0"}) {
+		t.Fatalf("unexpected options of a legacy text log %+v", options)
+	}
+	doc = Logv2Info{}
+	line = `2021-07-25T09:55:00.000+0000 I  CONTROL  [initandlisten] MongoDB starting : pid=1 port=27017 dbpath=/data/db 64-bit host=localhost`
+	if err := ParseTextLog(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if event := GetStartupEvent(&doc); event == nil || event.Settings[0] != (StartupSetting{STARTUP_PID, "1"}) {
+		t.Fatalf("unexpected restart of a legacy text log %+v", event)
+	}
+}
+
+func TestGetStartupDigests(t *testing.T) {
+	dbase := analyzeTestLogs(t, "startup", startupLogs)
+	digests, err := dbase.GetStartupDigests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digests) != 2 {
+		t.Fatalf("expected a digest of each restart, got %+v", digests)
+	}
+	first, second := digests[0], digests[1]
+	if first.Date != "2024-03-18T10:00:00.000-0000" || first.FCV != "6.0" || len(first.Warnings) != 2 ||
+		first.Warnings[0].Name != "access control disabled" || first.Warnings[1].Name != "THP" ||
+		len(first.Options) != 5 || len(first.Storage) != 4 {
+		t.Fatalf("unexpected digest of the first restart %+v", first)
+	}
+	if second.Date != "2024-03-18T11:00:00.000-0000" || second.FCV != "" || len(second.Warnings) != 1 ||
+		second.Warnings[0].Name != "XFS" || second.Options[2] != (StartupSetting{"security.authorization", "enabled"}) {
+		t.Fatalf("unexpected digest of the second restart %+v", second)
+	}
+	if second.Process[0] != (StartupSetting{STARTUP_PID, "2002"}) || second.Process[5] != (StartupSetting{"version", "7.0.2"}) {
+		t.Fatalf("unexpected process of the second restart %+v", second.Process)
+	}
+
+	templ, err := GetAuditTablesTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "startup", "Info": HatchetInfo{}, "Summary": "",
+		"Data": map[string][]NameValues{}, "Startups": digests, "Version": "test"}
+	html := executeTestTemplate(t, templ, doc)
+	if !strings.Contains(html, "Startup Configuration") || !strings.Contains(html, "replication.replSetName") {
+		t.Fatalf("expected startup configuration in the audit template")
+	}
+}
@@ -7,6 +7,7 @@ package hatchet
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -37,7 +38,10 @@ func renderErrorPage(w http.ResponseWriter, r *http.Request, hatchetName string,
 func StatsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	/** APIs
 	 * /hatchets/{hatchet}/stats/audit
+	 * /hatchets/{hatchet}/stats/indexes
+	 * /hatchets/{hatchet}/stats/sharding
 	 * /hatchets/{hatchet}/stats/slowops
+	 * /hatchets/{hatchet}/stats/transactions
 	 */
 	hatchetName := params.ByName("hatchet")
 	attr := params.ByName("attr")
@@ -60,12 +64,42 @@ func StatsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Para
 			renderErrorPage(w, r, hatchetName, err.Error())
 			return
 		}
+		startups, err := dbase.GetStartupDigests()
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
 		templ, err := GetAuditTablesTemplate(download)
 		if err != nil {
 			renderErrorPage(w, r, hatchetName, err.Error())
 			return
 		}
-		doc := map[string]interface{}{"Hatchet": hatchetName, "Info": info, "Summary": summary, "Data": data, "Version": GetLogv2().version}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Info": info, "Summary": summary, "Data": data,
+			"Startups": startups, "Version": GetLogv2().version}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
+	} else if attr == "indexes" {
+		ops, err := dbase.GetSlowOps("total_ms", "DESC", false, "")
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		advices := GetIndexAdvice(ops)
+		if r.URL.Query().Get("script") == "true" {
+			w.Header().Set("Content-Type", "text/javascript")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v_indexes.js", hatchetName))
+			w.Write([]byte(GetCreateIndexesScript(advices)))
+			return
+		}
+		templ, err := GetIndexesTemplate(download)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Advices": advices, "Summary": summary, "Version": GetLogv2().version}
 		if err = templ.Execute(w, doc); err != nil {
 			renderErrorPage(w, r, hatchetName, err.Error())
 			return
@@ -91,17 +125,62 @@ func StatsHandler(w http.ResponseWriter, r *http.Request, params httprouter.Para
 				order = "DESC"
 			}
 		}
-		ops, err := dbase.GetSlowOps(orderBy, order, collscan)
+		groupBy := r.URL.Query().Get("groupBy") // queryHash, planCacheKey or queryShapeHash
+		if SLOWOP_GROUPS[groupBy] == "" {
+			groupBy = ""
+		}
+		duration := r.URL.Query().Get("duration") // of a process lifetime
+		marker := r.URL.Query().Get("marker")
+		ops, err := dbase.GetSlowOps(orderBy, order, collscan, SLOWOP_GROUPS[groupBy],
+			fmt.Sprintf("duration=%v", duration), fmt.Sprintf("marker=%v", marker))
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		templ, err := GetStatsTableTemplate(collscan, orderBy, groupBy, download)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "Merge": info.Merge, "Ops": ops,
+			"Stages": GetPipelineStageStats(ops), "Summary": summary, "Duration": duration, "Marker": marker,
+			"Version": GetLogv2().version}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
+	} else if attr == "transactions" {
+		stats, err := dbase.GetTransactionStats()
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		templ, err := GetTransactionsTemplate(download)
+		if err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "RetryStormAborts": RETRY_STORM_ABORTS, "Stats": stats,
+			"Summary": summary, "Version": GetLogv2().version}
+		if err = templ.Execute(w, doc); err != nil {
+			renderErrorPage(w, r, hatchetName, err.Error())
+			return
+		}
+		return
+	} else if attr == "sharding" {
+		stats, err := dbase.GetShardingStats()
 		if err != nil {
 			renderErrorPage(w, r, hatchetName, err.Error())
 			return
 		}
-		templ, err := GetStatsTableTemplate(collscan, orderBy, download)
+		templ, err := GetShardingTemplate(download)
 		if err != nil {
 			renderErrorPage(w, r, hatchetName, err.Error())
 			return
 		}
-		doc := map[string]interface{}{"Hatchet": hatchetName, "Merge": info.Merge, "Ops": ops, "Summary": summary, "Version": GetLogv2().version}
+		doc := map[string]interface{}{"Hatchet": hatchetName, "StaleConfigErrors": strings.Join(STALE_CONFIG_ERRORS, ", "),
+			"Stats": stats, "Summary": summary, "Version": GetLogv2().version}
 		if err = templ.Execute(w, doc); err != nil {
 			renderErrorPage(w, r, hatchetName, err.Error())
 			return
@@ -16,13 +16,13 @@ import (
 
 const MIN_MONGO_VER = "5.0"
 
-// GetStatsTableTemplate returns HTML
-func GetStatsTableTemplate(collscan bool, orderBy string, download string) (*template.Template, error) {
+// GetStatsTableTemplate returns HTML of slow ops grouped by query patterns, or by a key of SLOWOP_GROUPS
+func GetStatsTableTemplate(collscan bool, orderBy string, groupBy string, download string) (*template.Template, error) {
 	html := headers
 	if download == "" {
 		html = getContentHTML()
 	}
-	html += getStatsTable(collscan, orderBy, download)
+	html += getStatsTable(collscan, orderBy, groupBy, download)
 	if download == "" {
 		html += "</div><!-- end content-container -->"
 	}
@@ -40,24 +40,65 @@ func GetStatsTableTemplate(collscan bool, orderBy string, download string) (*tem
 		},
 		"getMarkerHTML": func(marker int) template.HTML {
 			return template.HTML(GetMarkerHTML(marker))
+		},
+		"getTimeBreakdownHTML": func(stat OpStat) template.HTML {
+			return template.HTML(getTimeBreakdownHTML(stat))
 		}}).Parse(html)
 }
 
-func getStatsTable(collscan bool, orderBy string, download string) string {
+// colors of TIME_BREAKDOWN
+var timeBreakdownColors = []string{"#1565c0", "#ef6c00", "#c62828", "#6a1b9a", "#2e7d32", "#bdbdbd"}
+
+// getTimeBreakdownHTML returns a stacked bar of time spent by ops of a pattern, or empty
+// if none of storage, locks, flowControl, remoteOpWaitMillis and cpuNanos is logged
+func getTimeBreakdownHTML(stat OpStat) string {
+	values := GetOpTimeBreakdown(stat)
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	if total == 0 || total == values[len(values)-1] {
+		return ""
+	}
+	printer := message.NewPrinter(language.English)
+	bar := ""
+	legends := []string{}
+	for i, v := range values {
+		if v == 0 {
+			continue
+		}
+		descr := printer.Sprintf("%v %v ms", TIME_BREAKDOWN[i], v)
+		if i == 1 && stat.BytesRead > 0 {
+			descr += printer.Sprintf(" (%d bytes read)", stat.BytesRead)
+		}
+		bar += fmt.Sprintf(`<div style='width: %.1f%%; background: %v;' title='%v'></div>`, 100*v/total, timeBreakdownColors[i], descr)
+		legends = append(legends, fmt.Sprintf(`<span style='color: %v;'>&#9632;</span> %v`, timeBreakdownColors[i], descr))
+	}
+	return `<div style='font-weight: bold; margin: 5px 0; color: #666;'>Time Breakdown:</div>
+				<div style='display: flex; height: 14px; width: 100%; border-radius: 3px; overflow: hidden;'>` + bar + `</div>
+				<div style='font-size: 0.85em; color: #666; margin: 3px 0;'>` + strings.Join(legends, ", ") + `</div>`
+}
+
+func getStatsTable(collscan bool, orderBy string, groupBy string, download string) string {
 	checked := ""
 	if collscan {
 		checked = "checked"
 	}
+	selected := map[string]string{groupBy: "selected"}
+	// of sorting links, of a process lifetime if any
+	params := fmt.Sprintf("%v&groupBy=%v&duration={{.Duration}}&marker={{.Marker}}", collscan, groupBy)
 	html := fmt.Sprintf(`
 <script>
 	function getSlowopsStats() {
 		var b = document.getElementById('collscan').checked;
-		loadData('/hatchets/{{.Hatchet}}/stats/slowops?orderBy=%v&COLLSCAN='+b);
+		var g = document.getElementById('groupBy').value;
+		loadData('/hatchets/{{.Hatchet}}/stats/slowops?orderBy=%v&COLLSCAN='+b+'&groupBy='+g+
+			'&duration={{.Duration}}&marker={{.Marker}}');
 	}
 	function downloadStats() {
         anchor = document.createElement('a');
         anchor.download = '{{.Hatchet}}_stats.html';
-        anchor.href = '/hatchets/{{.Hatchet}}/stats/slowops?type=stats&download=true';
+        anchor.href = '/hatchets/{{.Hatchet}}/stats/slowops?type=stats&download=true&duration={{.Duration}}&marker={{.Marker}}';
         anchor.dataset.downloadurl = ['text/html', anchor.download, anchor.href].join(':');
         anchor.click();
     }
@@ -163,8 +204,16 @@ func getStatsTable(collscan bool, orderBy string, download string) string {
 <!-- Header Bar -->
 <div style='display: flex; justify-content: space-between; align-items: center; margin-bottom: 15px;'>
 	<h2 style='margin: 0; color: #444; font-size: 1.4em;'><i class='fa fa-info-circle' style='color: #1565c0;'></i> Slow Query Patterns</h2>
+	<span>group by
+		<select id='groupBy' onchange='getSlowopsStats(); return false;'>
+			<option value=''>query pattern</option>
+			<option value='queryHash' ` + selected["queryHash"] + `>queryHash</option>
+			<option value='planCacheKey' ` + selected["planCacheKey"] + `>planCacheKey</option>
+			<option value='queryShapeHash' ` + selected["queryShapeHash"] + `>queryShapeHash</option>
+		</select>
 	<button id="download" onClick="downloadStats(); return false;"
 		class="download-btn"><i class="fa fa-download"></i> Download</button>
+	</span>
 </div>`
 	} else {
 		html += "<div align='center'>{{.Summary}}</div>"
@@ -172,13 +221,18 @@ func getStatsTable(collscan bool, orderBy string, download string) string {
 		desc = ""
 	}
 	html += `<table width='100%'><tr><th>#</th>`
-	html += fmt.Sprintf(`<th>op <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=op&COLLSCAN=%v'>%v</th>`, collscan, asc)
-	html += fmt.Sprintf(`<th>namespace <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=ns&order=ASC&COLLSCAN=%v'>%v</th>`, collscan, asc)
-	html += fmt.Sprintf(`<th>count <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=count&COLLSCAN=%v'>%v</th>`, collscan, desc)
-	html += fmt.Sprintf(`<th>avg ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=avg_ms&COLLSCAN=%v'>%v</th>`, collscan, desc)
-	html += fmt.Sprintf(`<th>max ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=max_ms&COLLSCAN=%v'>%v</th>`, collscan, desc)
-	html += fmt.Sprintf(`<th>total ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=total_ms&COLLSCAN=%v'>%v</th>`, collscan, desc)
-	html += fmt.Sprintf(`<th>reslen <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=reslen&COLLSCAN=%v'>%v</th>`, collscan, desc)
+	html += fmt.Sprintf(`<th>op <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=op&COLLSCAN=%v'>%v</th>`, params, asc)
+	html += fmt.Sprintf(`<th>namespace <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=ns&order=ASC&COLLSCAN=%v'>%v</th>`, params, asc)
+	html += fmt.Sprintf(`<th>count <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=count&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>avg ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=avg_ms&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>p50 ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=p50_ms&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>p95 ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=p95_ms&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>p99 ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=p99_ms&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>max ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=max_ms&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>total ms <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=total_ms&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th>reslen <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=reslen&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th title='keysExamined per document returned or matched'>keys/ret <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=keys_examined_returned&COLLSCAN=%v'>%v</th>`, params, desc)
+	html += fmt.Sprintf(`<th title='docsExamined per document returned or matched'>docs/ret <a class='sort' href='/hatchets/{{.Hatchet}}/stats/slowops?orderBy=docs_examined_returned&COLLSCAN=%v'>%v</th>`, params, desc)
 	if download == "" {
 		html += fmt.Sprintf(`<th valign='middle'>index <label title='Show COLLSCAN only' style='cursor: pointer; font-weight: normal; font-size: 0.85em;'><input type='checkbox' id='collscan' onchange='getSlowopsStats(); return false;' %v> only</label></th>`, checked)
 	} else {
@@ -194,13 +248,27 @@ func getStatsTable(collscan bool, orderBy string, download string) string {
 		{{else}}
 			<td align='right'>{{ add $n 1 }}</td>
 		{{end}}
-			<td style='white-space: nowrap;'>{{ $value.Op }}</td>
+			<td style='white-space: nowrap;'>{{ $value.Op }}
+			{{- if $value.UsedDisk }} <i class='fa fa-hdd-o' style='color:red;' title='used disk by {{ numPrinter $value.UsedDisk }} ops'></i>{{ end }}
+			{{- if $value.HasSortStage }} <i class='fa fa-sort-amount-desc' style='color:orange;' title='in-memory sort by {{ numPrinter $value.HasSortStage }} ops'></i>{{ end }}
+			{{- if $value.WriteAmplified }} <i class='fa fa-sitemap' style='color:red;' title='write amplification, {{ $value.KeysPerDoc }} index keys written per document'></i>{{ end }}
+			{{- if $value.UnboundedMulti }} <i class='fa fa-exclamation-triangle' style='color:red;' title='unbounded multi write, {{ $value.DocsPerWrite }} documents written per op'></i>{{ end }}</td>
 			<td class='break'>{{ $value.Namespace }}</td>
 			<td align='right'>{{ numPrinter $value.Count }}</td>
 			<td align='right'>{{ numPrinter $value.AvgMilli }}</td>
+			<td align='right'>{{ numPrinter $value.P50Milli }}</td>
+			<td align='right'>{{ numPrinter $value.P95Milli }}</td>
+			<td align='right'>{{ numPrinter $value.P99Milli }}</td>
 			<td align='right'>{{ numPrinter $value.MaxMilli }}</td>
 			<td align='right'>{{ numPrinter $value.TotalMilli }}</td>
 			<td align='right'>{{ numPrinter $value.Reslen }}</td>
+		{{ if $value.PoorlyTargeted }}
+			<td align='right' title='poorly targeted, {{ numPrinter $value.KeysExamined }} keys and {{ numPrinter $value.DocsExamined }} documents examined for {{ numPrinter (add $value.NReturned $value.NMatched) }} returned'><span style='color:red;'>{{ numPrinter $value.KeysRatio }}</span></td>
+			<td align='right' title='poorly targeted, {{ numPrinter $value.KeysExamined }} keys and {{ numPrinter $value.DocsExamined }} documents examined for {{ numPrinter (add $value.NReturned $value.NMatched) }} returned'><span style='color:red;'>{{ numPrinter $value.DocsRatio }}</span></td>
+		{{ else }}
+			<td align='right'>{{ numPrinter $value.KeysRatio }}</td>
+			<td align='right'>{{ numPrinter $value.DocsRatio }}</td>
+		{{ end }}
 		{{ if or (eq $value.Index "COLLSCAN") }}
 			<td><span style='color:red;'>{{ $value.Index }}</span></td>
 		{{ else if (hasPrefix $value.Index "ErrMsg:") }}
@@ -208,14 +276,22 @@ func getStatsTable(collscan bool, orderBy string, download string) string {
 				<div class='tooltip'><button class="exclamation"><i class="fa fa-exclamation"></i></button>
 					<span class="tooltiptext">{{$value.Index}}</span></div>
 				</td>
+		{{else if $value.PlanFlip }}
+			<td title='plan flip, planCacheKey {{ $value.PlanCacheKey }}'>{{ $value.Index }} <span style='color:red;'>(plan flip)</span></td>
 		{{else}}
 			<td>{{ $value.Index }}</td>
 		{{end}}
-			<td class='break'>{{ $value.QueryPattern }}</td>
+			<td class='break'>{{ $value.QueryPattern }}
+			{{- if $value.SortShape }}<br/><span style='color: #888; font-size: 0.85em;'>sort: {{ $value.SortShape }}</span>{{ end }}
+			{{- if $value.Projection }}<br/><span style='color: #888; font-size: 0.85em;'>projection: {{ $value.Projection }}</span>{{ end }}
+			{{- if $value.LimitSkip }}<br/><span style='color: #888; font-size: 0.85em;'>{{ $value.LimitSkip }}</span>{{ end }}
+			{{- if $value.Hint }}<br/><span style='color: #888; font-size: 0.85em;'>hint: {{ $value.Hint }}</span>{{ end }}
+			{{- if $value.UpdateShape }}<br/><span style='color: #888; font-size: 0.85em;'>update: {{ $value.UpdateShape }}</span>{{ end }}
+			{{- if $value.WriteFlags }}<br/><span style='color: #888; font-size: 0.85em;'>{{ $value.WriteFlags }}</span>{{ end }}</td>
 			<td align='center'><button id='btn-stats-{{$n}}' class='stats-json-btn' onclick='toggleStatsJson({{$n}})' title='View formatted'>{}</button></td>
 		</tr>
 		<tr id='json-stats-{{$n}}' class='stats-json-row'>
-			<td colspan='11' style='padding: 5px 10px;'>
+			<td colspan='16' style='padding: 5px 10px;'>
 				<div style='display: flex; gap: 20px;'>
 					<div style='flex: 1;'>
 						<div style='font-weight: bold; margin-bottom: 5px; color: #666;'>Index:</div>
@@ -225,11 +301,52 @@ func getStatsTable(collscan bool, orderBy string, download string) string {
 						<div style='font-weight: bold; margin-bottom: 5px; color: #666;'>Query Pattern:</div>
 						<pre class='stats-json-content' id='pattern-content-{{$n}}'>{{ $value.QueryPattern }}</pre>
 					</div>
+					<div style='flex: 1;'>
+						<div style='font-weight: bold; margin-bottom: 5px; color: #666;'>Hashes:</div>
+						<pre class='stats-json-content'>queryHash: {{ $value.QueryHash }}
+planCacheKey: {{ $value.PlanCacheKey }}
+queryShapeHash: {{ $value.QueryShapeHash }}</pre>
+					</div>
 				</div>
+			{{ getTimeBreakdownHTML $value }}
+			{{ if $value.DocsPerWrite }}
+				<div style='font-weight: bold; margin: 5px 0; color: #666;'>Writes:</div>
+				<table>
+					<tr><th>inserted</th><th>modified</th><th>deleted</th><th>upserted</th><th>keys inserted</th><th>keys deleted</th>
+						<th title='documents written per op, batch sizes of inserts'>docs/op</th><th title='index keys written per document'>keys/doc</th></tr>
+					<tr><td align='right'>{{ numPrinter $value.NInserted }}</td><td align='right'>{{ numPrinter $value.NModified }}</td>
+						<td align='right'>{{ numPrinter $value.NDeleted }}</td><td align='right'>{{ numPrinter $value.NUpserted }}</td>
+						<td align='right'>{{ numPrinter $value.KeysInserted }}</td><td align='right'>{{ numPrinter $value.KeysDeleted }}</td>
+						<td align='right'>{{ if $value.UnboundedMulti }}<span style='color:red;'>{{ $value.DocsPerWrite }}</span>{{ else }}{{ $value.DocsPerWrite }}{{ end }}</td>
+						<td align='right'>{{ if $value.WriteAmplified }}<span style='color:red;'>{{ $value.KeysPerDoc }}</span>{{ else }}{{ $value.KeysPerDoc }}{{ end }}</td></tr>
+				</table>
+			{{ end }}
+			{{ if $value.Pipeline }}
+				<div style='font-weight: bold; margin: 5px 0; color: #666;'>Pipeline Stages:</div>
+				<table>
+					<tr><th>#</th><th>stage</th><th>collection</th><th>shape</th><th>blocking</th></tr>
+				{{ range $i, $stage := $value.Pipeline }}
+					<tr><td align='right'>{{ add $i 1 }}</td><td>{{ $stage.Stage }}</td><td>{{ $stage.From }}</td><td class='break'>{{ $stage.Shape }}</td>
+						<td align='center'>{{ if $stage.Blocking }}<span style='color:red;'>yes</span>{{ end }}</td></tr>
+				{{ end }}
+				</table>
+			{{ end }}
 			</td>
 		</tr>
 {{end}}
 	</table>
+{{ if .Stages }}
+	<h3 style='color: #444;'>Aggregation Pipeline Stages</h3>
+	<table>
+		<tr><th>stage</th><th>pipelines</th><th>count</th><th>total ms</th><th title='ops of which the stage blocks, e.g. $group or $sort without an index'>blocking</th><th>used disk</th></tr>
+	{{ range $stage := .Stages }}
+		<tr><td>{{ $stage.Stage }}</td><td align='right'>{{ numPrinter $stage.Patterns }}</td><td align='right'>{{ numPrinter $stage.Count }}</td>
+			<td align='right'>{{ numPrinter $stage.TotalMilli }}</td>
+			<td align='right'>{{ if $stage.Blocking }}<span style='color:red;'>{{ numPrinter $stage.Blocking }}</span>{{ end }}</td>
+			<td align='right'>{{ if $stage.UsedDisk }}<span style='color:red;'>{{ numPrinter $stage.UsedDisk }}</span>{{ end }}</td></tr>
+	{{ end }}
+	</table>
+{{ end }}
 	</div>
 	<div align='center'><hr/><p/>{{.Version}}</div>
 </div>
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * storage.go
+ */
+
+package hatchet
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	STORAGE_CHECKPOINT = "checkpoint"
+	STORAGE_EVICTION   = "eviction"
+
+	CHECKPOINT_END      = "end"
+	CHECKPOINT_PROGRESS = "progress"
+	CHECKPOINT_START    = "start"
+
+	SERIES_AVG_OP_TIME = "avg op time"
+	SERIES_CHECKPOINT  = "checkpoint"
+	SERIES_EVICTION    = "eviction warnings"
+)
+
+// e.g. Checkpoint has been running for 21 seconds and wrote: 50000 pages (1000 MB)
+var checkpointRegex = regexp.MustCompile(`(?i)checkpoint (has been running|ran) for (\d+) seconds and wrote: (\d+) pages \((\d+) MB\)`)
+
+// e.g. [WT_VERB_CHECKPOINT_PROGRESS] of WiredTiger messages of 4.4 and older
+var wtCategoryRegex = regexp.MustCompile(`\[(WT_VERB_[A-Z_]+)\]`)
+
+// lower-cased phrases of cache pressure of WiredTiger messages
+var evictionPhrases = []string{"cache is full", "cache stuck", "evict"}
+
+// StorageEvent stores a checkpoint or an eviction warning of a WiredTiger message
+type StorageEvent struct {
+	Date   string `json:"date" bson:"date"`
+	Detail string `json:"detail" bson:"detail"` // WiredTiger message
+	MB     int    `json:"mb" bson:"mb"`
+	Milli  int    `json:"milli" bson:"milli"` // checkpoint running time
+	Pages  int    `json:"pages" bson:"pages"`
+	Phase  string `json:"phase" bson:"phase"` // start, progress or end of checkpoints
+	Type   string `json:"type" bson:"type"`   // checkpoint or eviction
+
+	Marker int `json:"-" bson:"marker"`
+}
+
+// GetStorageEvent returns a checkpoint or an eviction warning of a STORAGE or WiredTiger log
+// line, or nil if it is neither
+func GetStorageEvent(doc *Logv2Info) *StorageEvent {
+	c := doc.Component
+	if c != "STORAGE" && !strings.HasPrefix(c, "WT") {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	text, _ := attrMap["message"].(string)
+	category := ""
+	if message, ok := attrMap["message"].(bson.M); ok { // 5.0 and later
+		text, _ = message["msg"].(string)
+		category, _ = message["category"].(string)
+	}
+	if text == "" {
+		text = doc.Msg
+	}
+	if category == "" {
+		if matches := wtCategoryRegex.FindStringSubmatch(text); len(matches) > 1 {
+			category = matches[1]
+		}
+	}
+	event := &StorageEvent{Detail: text, Marker: doc.Marker}
+	lower := strings.ToLower(text)
+	if matches := checkpointRegex.FindStringSubmatch(text); len(matches) > 4 {
+		event.Type = STORAGE_CHECKPOINT
+		event.Phase = CHECKPOINT_PROGRESS
+		if strings.ToLower(matches[1]) == "ran" {
+			event.Phase = CHECKPOINT_END
+		}
+		seconds, _ := strconv.Atoi(matches[2])
+		event.Milli = seconds * 1000
+		event.Pages, _ = strconv.Atoi(matches[3])
+		event.MB, _ = strconv.Atoi(matches[4])
+		return event
+	} else if strings.Contains(lower, "saving checkpoint snapshot") {
+		event.Type = STORAGE_CHECKPOINT
+		event.Phase = CHECKPOINT_START
+		return event
+	} else if c == "WTCHKPT" || strings.HasPrefix(category, "WT_VERB_CHECKPOINT") {
+		event.Type = STORAGE_CHECKPOINT
+		return event
+	} else if c == "WTEVICT" || strings.HasPrefix(category, "WT_VERB_EVICT") {
+		event.Type = STORAGE_EVICTION
+		return event
+	}
+	for _, phrase := range evictionPhrases {
+		if strings.Contains(lower, phrase) {
+			event.Type = STORAGE_EVICTION
+			return event
+		}
+	}
+	return nil
+}
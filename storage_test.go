@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * storage_test.go
+ */
+
+package hatchet
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var storageLogs = []string{
+	`{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":100}}`,
+	`{"t":{"$date":"2024-03-18T10:00:01.000+00:00"},"s":"I","c":"WTCHKPT","id":22430,"ctx":"Checkpointer","msg":"WiredTiger message","attr":{"message":{"ts_sec":1710756001,"ts_usec":0,"thread":"1:0x7f","session_name":"WT_SESSION.checkpoint","category":"WT_VERB_CHECKPOINT_PROGRESS","category_id":6,"verbose_level":"DEBUG_1","verbose_level_id":1,"msg":"saving checkpoint snapshot min: 37, snapshot max: 37 snapshot count: 0"}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:22.000+00:00"},"s":"I","c":"STORAGE","id":22430,"ctx":"Checkpointer","msg":"WiredTiger message","attr":{"message":"[1710756022:000][1:0x7f], WT_SESSION.checkpoint: [WT_VERB_CHECKPOINT_PROGRESS] Checkpoint has been running for 21 seconds and wrote: 50000 pages (1000 MB)"}}`,
+	`{"t":{"$date":"2024-03-18T10:00:25.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn2","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"active"},"$db":"test"},"planSummary":"COLLSCAN","reslen":200,"durationMillis":2000}}`,
+	`{"t":{"$date":"2024-03-18T10:00:26.000+00:00"},"s":"I","c":"WTCHKPT","id":22430,"ctx":"Checkpointer","msg":"WiredTiger message","attr":{"message":{"ts_sec":1710756026,"thread":"1:0x7f","session_name":"WT_SESSION.checkpoint","category":"WT_VERB_CHECKPOINT_PROGRESS","msg":"Checkpoint ran for 25 seconds and wrote: 60000 pages (1200 MB)"}}}`,
+	`{"t":{"$date":"2024-03-18T10:00:27.000+00:00"},"s":"W","c":"STORAGE","id":22430,"ctx":"conn3","msg":"WiredTiger message","attr":{"message":"[1710756027:000][1:0x7f], eviction-server: [WT_VERB_EVICTSERVER] cache stuck for too long, giving up"}}`,
+	`{"t":{"$date":"2024-03-18T10:00:30.000+00:00"},"s":"I","c":"STORAGE","id":20320,"ctx":"conn4","msg":"createCollection","attr":{"namespace":"test.orders"}}`,
+}
+
+func TestGetStorageEvent(t *testing.T) {
+	types := []string{"", STORAGE_CHECKPOINT, STORAGE_CHECKPOINT, "", STORAGE_CHECKPOINT, STORAGE_EVICTION, ""}
+	phases := []string{"", CHECKPOINT_START, CHECKPOINT_PROGRESS, "", CHECKPOINT_END, "", ""}
+	for i, str := range storageLogs {
+		doc := Logv2Info{}
+		if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+			t.Fatal(err)
+		}
+		event := GetStorageEvent(&doc)
+		if types[i] == "" && event != nil {
+			t.Fatalf("expected no event of %v, got %+v", doc.Msg, event)
+		} else if types[i] != "" && (event == nil || event.Type != types[i] || event.Phase != phases[i]) {
+			t.Fatalf("expected %v %v event of line %d, got %+v", types[i], phases[i], i+1, event)
+		}
+	}
+	doc := Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(storageLogs[2]), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if event := GetStorageEvent(&doc); event.Milli != 21000 || event.Pages != 50000 || event.MB != 1000 {
+		t.Fatalf("unexpected checkpoint progress %+v", event)
+	}
+}
+
+func TestGetStorageMetrics(t *testing.T) {
+	dbase := analyzeTestLogs(t, "mongod_storage", storageLogs)
+	metrics, err := dbase.GetStorageMetrics("2024-03-18T10:00:00,2024-03-18T10:05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := GetMetricsTable(metrics)
+	columns := map[string]int{}
+	for i, name := range table.Names {
+		columns[name] = i
+	}
+	if len(table.Rows) != 2 || len(table.Names) != 3 || table.Rows[0].Values[columns[SERIES_AVG_OP_TIME]] != 0.1 {
+		t.Fatalf("unexpected storage metrics %+v", table)
+	}
+	values := table.Rows[1].Values
+	if values[columns[SERIES_AVG_OP_TIME]] != 2.0 || values[columns[SERIES_CHECKPOINT]] != 25.0 ||
+		values[columns[SERIES_EVICTION]] != 1.0 {
+		t.Fatalf("expected checkpoints and eviction warnings along with average op time, got %+v", table)
+	}
+	templ, err := GetChartTemplate(LINE_CHART)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "mongod_storage", "Metrics": table, "Chart": charts[T_STORAGE], "Summary": "",
+		"Start": "", "End": "", "VAxisLabel": "seconds, warnings"}
+	executeTestTemplate(t, templ, doc)
+}
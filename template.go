@@ -448,6 +448,18 @@ func getContentHTML() string {
   <button class="menu-item" data-page="stats" onclick="loadData('/hatchets/{{.Hatchet}}/stats/slowops'); return false;">
     <i class="fa fa-info"></i> Stats
   </button>
+  <button class="menu-item" data-page="indexes" onclick="loadData('/hatchets/{{.Hatchet}}/stats/indexes'); return false;">
+    <i class="fa fa-key"></i> Indexes
+  </button>
+  <button class="menu-item" data-page="transactions" onclick="loadData('/hatchets/{{.Hatchet}}/stats/transactions'); return false;">
+    <i class="fa fa-exchange"></i> Transactions
+  </button>
+  <button class="menu-item" data-page="sharding" onclick="loadData('/hatchets/{{.Hatchet}}/stats/sharding'); return false;">
+    <i class="fa fa-th-large"></i> Sharding
+  </button>
+  <button class="menu-item" data-page="events" onclick="loadData('/hatchets/{{.Hatchet}}/logs/events'); return false;">
+    <i class="fa fa-heartbeat"></i> Events
+  </button>
   <button class="menu-item" data-page="topn" onclick="loadData('/hatchets/{{.Hatchet}}/logs/slowops'); return false;">
     <i class="fa fa-list"></i> Top N
   </button>
@@ -485,7 +497,7 @@ func getContentHTML() string {
 	function refreshChart() {
 		var sd = document.getElementById('start').value;
 		var ed = document.getElementById('end').value;
-		loadData('/hatchets/{{.Hatchet}}/charts{{.Chart.URL}}&duration=' + sd + ',' + ed);
+		loadData('/hatchets/{{.Hatchet}}/charts{{.Chart.URL}}&duration=' + sd + ',' + ed + '&marker={{.Marker}}');
 	}
 
 	// Highlight active menu item based on URL
@@ -494,6 +506,10 @@ func getContentHTML() string {
 		var page = 'home';
 		if (path.includes('/stats/audit')) page = 'audit';
 		else if (path.includes('/stats/slowops')) page = 'stats';
+		else if (path.includes('/stats/indexes')) page = 'indexes';
+		else if (path.includes('/stats/transactions')) page = 'transactions';
+		else if (path.includes('/stats/sharding')) page = 'sharding';
+		else if (path.includes('/logs/events')) page = 'events';
 		else if (path.includes('/logs/slowops')) page = 'topn';
 		else if (path.includes('/logs/all')) page = 'search';
 		else if (path.includes('/charts/')) page = 'charts';
@@ -877,9 +893,13 @@ func getMainPage() string {
 <ul class="api">
 	<li>/</li>
 	<li>/hatchets/{hatchet}/charts/{chart}[?type={str}]</li>
-	<li>/hatchets/{hatchet}/logs/all[?component={str}&context={str}&duration={date},{date}&severity={str}&limit=[{offset},]{int}]</li>
+	<li>/hatchets/{hatchet}/logs/all[?component={str}&context={str}&duration={date},{date}&marker={int}&severity={str}&limit=[{offset},]{int}]</li>
+	<li>/hatchets/{hatchet}/logs/events[?type={str}&duration={date},{date}&marker={int}]</li>
 	<li>/hatchets/{hatchet}/logs/slowops[?topN={int}]</li>
-	<li>/hatchets/{hatchet}/stats/slowops[?COLLSCAN={bool}&orderBy={str}]</li>
+	<li>/hatchets/{hatchet}/stats/indexes[?script={bool}]</li>
+	<li>/hatchets/{hatchet}/stats/sharding</li>
+	<li>/hatchets/{hatchet}/stats/slowops[?COLLSCAN={bool}&orderBy={str}&groupBy={queryHash|planCacheKey|queryShapeHash}&duration={date},{date}&marker={int}]</li>
+	<li>/hatchets/{hatchet}/stats/transactions</li>
 </ul>
 
 <h3 style='margin-top: 24px;'>API</h3>
@@ -888,10 +908,14 @@ func getMainPage() string {
 	<li><b>GET</b> /api/hatchet/v1.0/upload/status/{name} - Check upload processing status</li>
 	<li><b>POST</b> /api/hatchet/v1.0/rename?old={name}&new={name} - Rename a hatchet</li>
 	<li><b>DELETE</b> /api/hatchet/v1.0/delete?name={name} - Delete a hatchet</li>
-	<li>/api/hatchet/v1.0/hatchets/{hatchet}/logs/all[?component={str}&context={str}&duration={date},{date}&severity={str}&limit=[{offset},]{int}]</li>
+	<li>/api/hatchet/v1.0/hatchets/{hatchet}/logs/all[?component={str}&context={str}&duration={date},{date}&marker={int}&severity={str}&limit=[{offset},]{int}]</li>
+	<li>/api/hatchet/v1.0/hatchets/{hatchet}/logs/events[?type={str}&duration={date},{date}&marker={int}]</li>
 	<li>/api/hatchet/v1.0/hatchets/{hatchet}/logs/slowops[?topN={int}]</li>
 	<li>/api/hatchet/v1.0/hatchets/{hatchet}/stats/audit</li>
-	<li>/api/hatchet/v1.0/hatchets/{hatchet}/stats/slowops[?COLLSCAN={bool}&orderBy={str}]</li>
+	<li>/api/hatchet/v1.0/hatchets/{hatchet}/stats/indexes</li>
+	<li>/api/hatchet/v1.0/hatchets/{hatchet}/stats/sharding</li>
+	<li>/api/hatchet/v1.0/hatchets/{hatchet}/stats/slowops[?COLLSCAN={bool}&orderBy={str}&groupBy={queryHash|planCacheKey|queryShapeHash}&duration={date},{date}&marker={int}]</li>
+	<li>/api/hatchet/v1.0/hatchets/{hatchet}/stats/transactions</li>
 	<li>/api/hatchet/v1.0/mongodb/{version}/drivers/{driver}?compatibleWith={driver version}</li>
 </ul>
 </div>
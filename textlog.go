@@ -0,0 +1,549 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * textlog.go
+ */
+
+package hatchet
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// legacy text logs of MongoDB before 4.4, e.g.
+// 2021-07-25T09:56:00.691+0000 I  COMMAND  [conn12] command test.users command: find { find: "users" } ... 105ms
+var (
+	reTextLog        = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2}))\s+([IWEF]|D\d?)\s+(\S+)\s+\[([^\]]+)\]\s?(.*)$`)
+	reTextSlowOp     = regexp.MustCompile(`^(command|query|getmore|insert|update|remove|killcursors) (\S+) (.*?)\s*(\d+)ms$`)
+	reTextAccepted   = regexp.MustCompile(`^connection accepted from (\S+:\d+) #(\d+) \((\d+) connections? now open\)`)
+	reTextEnded      = regexp.MustCompile(`^end connection (\S+:\d+) \((\d+) connections? now open\)`)
+	reTextMetadata   = regexp.MustCompile(`^received client metadata from (\S+:\d+) (conn\d+): (\{.*\})\s*$`)
+	reTextAuthorized = regexp.MustCompile(`^Successfully authenticated as principal (\S+) on (\S+)(?: from client (\S+:\d+))?`)
+	reTextVersion    = regexp.MustCompile(`^db version v(\S+)`)
+	reTextStarting   = regexp.MustCompile(`^MongoDB starting : pid=(\d+) port=(\d+) dbpath=(\S+) (\S+) host=(\S+)`)
+	reTextOptions    = regexp.MustCompile(`^options: (\{.*\})\s*$`)
+	reTextWiredTiger = regexp.MustCompile(`^wiredtiger_open config: (\S+)`)
+	textTimeLayouts  = []string{"2006-01-02T15:04:05.999-0700", "2006-01-02T15:04:05.999-07:00", "2006-01-02T15:04:05.999Z"}
+)
+
+// ParseLogLine parses a logv2 JSON log or a legacy text log
+func ParseLogLine(line string, doc *Logv2Info) error {
+	doc.attrMap = nil
+	if strings.HasPrefix(line, "{") {
+		return bson.UnmarshalExtJSON([]byte(line), false, doc)
+	}
+	return ParseTextLog(line, doc)
+}
+
+// ParseTextLog parses a legacy text log of MongoDB before 4.4 into the same
+// fields as logv2, so that slow ops, connections and drivers are analyzed
+// the same way
+func ParseTextLog(line string, doc *Logv2Info) error {
+	doc.attrMap = nil
+	matches := reTextLog.FindStringSubmatch(line)
+	if matches == nil {
+		return errors.New("not a MongoDB text log")
+	}
+	var err error
+	for _, layout := range textTimeLayouts {
+		if doc.Timestamp, err = time.Parse(layout, matches[1]); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	doc.Severity = matches[2]
+	doc.Component = matches[3]
+	doc.Context = matches[4]
+	msg := matches[5]
+	doc.Msg = msg
+
+	if m := reTextSlowOp.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "Slow query"
+		doc.Attr = parseTextSlowOp(m[1], m[2], m[3])
+		doc.Attr = append(doc.Attr, bson.E{Key: "durationMillis", Value: ToInt(m[4])})
+	} else if m := reTextAccepted.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "Connection accepted"
+		doc.Attr = bson.D{{Key: "remote", Value: m[1]}, {Key: "connectionId", Value: ToInt(m[2])},
+			{Key: "connectionCount", Value: ToInt(m[3])}}
+	} else if m := reTextEnded.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "Connection ended"
+		doc.Attr = bson.D{{Key: "remote", Value: m[1]}, {Key: "connectionCount", Value: ToInt(m[2])}}
+	} else if m := reTextMetadata.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "client metadata"
+		doc.Attr = bson.D{{Key: "remote", Value: m[1]}, {Key: "client", Value: m[2]}}
+		parser := &textParser{str: m[3]}
+		if metadata, err := parser.parseDocument(); err == nil {
+			doc.Attr = append(doc.Attr, bson.E{Key: "doc", Value: metadata})
+		}
+	} else if m := reTextAuthorized.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "Authentication succeeded"
+		doc.Attr = bson.D{{Key: "principalName", Value: m[1]}, {Key: "authenticationDatabase", Value: m[2]}}
+		if m[3] != "" {
+			doc.Attr = append(doc.Attr, bson.E{Key: "remote", Value: m[3]})
+		}
+	} else if m := reTextVersion.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "Build Info"
+		doc.Attr = bson.D{{Key: "buildInfo", Value: bson.D{{Key: "version", Value: m[1]}}}}
+	} else if m := reTextStarting.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "MongoDB starting"
+		doc.Attr = bson.D{{Key: "pid", Value: ToInt(m[1])}, {Key: "port", Value: ToInt(m[2])},
+			{Key: "dbPath", Value: m[3]}, {Key: "architecture", Value: m[4]}, {Key: "host", Value: m[5]}}
+	} else if m := reTextOptions.FindStringSubmatch(msg); m != nil {
+		parser := &textParser{str: m[1]}
+		if options, err := parser.parseDocument(); err == nil {
+			doc.Msg = "Options set by command line"
+			doc.Attr = bson.D{{Key: "options", Value: options}}
+		}
+	} else if m := reTextWiredTiger.FindStringSubmatch(msg); m != nil {
+		doc.Msg = "Opening WiredTiger"
+		doc.Attr = bson.D{{Key: "config", Value: m[1]}}
+	}
+	return nil
+}
+
+// parseTextSlowOp converts attributes of a legacy slow op to logv2 attributes
+func parseTextSlowOp(op string, ns string, str string) bson.D {
+	attrs := bson.D{}
+	var command, query, update, originatingCommand interface{}
+	parser := &textParser{str: str}
+	for {
+		parser.skipSpaces()
+		if parser.eof() {
+			break
+		}
+		key := parser.parseAttrKey()
+		if key == "" {
+			parser.skipToken() // unrecognized token
+			continue
+		}
+		parser.skipSpaces()
+		var value interface{}
+		var err error
+		if key == "planSummary" {
+			value = parser.parsePlanSummary()
+		} else if key == "command" && !strings.ContainsRune("{[", rune(parser.peek())) {
+			parser.skipToken() // command name, e.g. command: find { find: "users" }
+			parser.skipSpaces()
+			value, err = parser.parseValue()
+		} else {
+			value, err = parser.parseValue()
+		}
+		if err != nil {
+			break // truncated or unsupported
+		}
+		switch key {
+		case "command":
+			command = value
+		case "query":
+			query = value
+		case "update":
+			update = value
+		case "originatingCommand":
+			originatingCommand = value
+		default:
+			attrs = append(attrs, bson.E{Key: key, Value: value})
+		}
+	}
+
+	opType := "command"
+	coll := ns[strings.Index(ns, ".")+1:]
+	switch op {
+	case cmdInsert, cmdUpdate, cmdRemove:
+		opType = op
+		if command == nil {
+			command = bson.D{{Key: "q", Value: query}, {Key: "u", Value: update}}
+		}
+	case "query":
+		if command == nil {
+			command = bson.D{{Key: cmdFind, Value: coll}, {Key: "filter", Value: query}}
+		}
+	case "getmore":
+		if command == nil {
+			command = bson.D{{Key: cmdGetMore, Value: int64(0)}, {Key: "collection", Value: coll}}
+		}
+		if originatingCommand == nil && query != nil {
+			originatingCommand = bson.D{{Key: cmdFind, Value: coll}, {Key: "filter", Value: query}}
+		}
+	}
+	results := bson.D{{Key: "type", Value: opType}, {Key: "ns", Value: ns}}
+	if command != nil {
+		results = append(results, bson.E{Key: "command", Value: command})
+	}
+	if originatingCommand != nil {
+		results = append(results, bson.E{Key: "originatingCommand", Value: originatingCommand})
+	}
+	return append(results, attrs...)
+}
+
+// textParser parses documents printed in legacy text logs, for example
+// { find: "users", filter: { _id: ObjectId('5f1b...') }, $db: "test" }
+type textParser struct {
+	str string
+	pos int
+}
+
+func (ptr *textParser) eof() bool {
+	return ptr.pos >= len(ptr.str)
+}
+
+func (ptr *textParser) peek() byte {
+	if ptr.eof() {
+		return 0
+	}
+	return ptr.str[ptr.pos]
+}
+
+func (ptr *textParser) skipSpaces() {
+	for !ptr.eof() && (ptr.str[ptr.pos] == ' ' || ptr.str[ptr.pos] == '\t') {
+		ptr.pos++
+	}
+}
+
+func (ptr *textParser) skipToken() {
+	for !ptr.eof() && ptr.str[ptr.pos] != ' ' {
+		ptr.pos++
+	}
+}
+
+func (ptr *textParser) expect(c byte) error {
+	ptr.skipSpaces()
+	if ptr.peek() != c {
+		return fmt.Errorf("expected '%c' at %d", c, ptr.pos)
+	}
+	ptr.pos++
+	return nil
+}
+
+// parseAttrKey returns a key followed by a colon, or an empty string
+func (ptr *textParser) parseAttrKey() string {
+	start := ptr.pos
+	for !ptr.eof() && isTextKeyChar(ptr.str[ptr.pos]) {
+		ptr.pos++
+	}
+	if ptr.pos == start || ptr.peek() != ':' {
+		ptr.pos = start
+		return ""
+	}
+	key := ptr.str[start:ptr.pos]
+	ptr.pos++
+	return key
+}
+
+// parsePlanSummary returns a plan summary, e.g. IXSCAN { a: 1 }, IXSCAN { b: 1 }
+func (ptr *textParser) parsePlanSummary() string {
+	start := ptr.pos
+	for {
+		ptr.skipToken()
+		end := ptr.pos
+		ptr.skipSpaces()
+		if ptr.peek() == '{' {
+			if _, err := ptr.parseDocument(); err != nil {
+				return ptr.str[start:]
+			}
+			end = ptr.pos
+		}
+		if strings.HasPrefix(ptr.str[end:], ", ") && end+2 < len(ptr.str) &&
+			ptr.str[end+2] >= 'A' && ptr.str[end+2] <= 'Z' {
+			ptr.pos = end + 2
+			continue
+		}
+		ptr.pos = end
+		return strings.TrimSuffix(ptr.str[start:end], ",")
+	}
+}
+
+// parseDocument parses a document into bson.D
+func (ptr *textParser) parseDocument() (bson.D, error) {
+	doc := bson.D{}
+	if err := ptr.expect('{'); err != nil {
+		return doc, err
+	}
+	for {
+		ptr.skipSpaces()
+		if ptr.peek() == '}' {
+			ptr.pos++
+			return doc, nil
+		}
+		if strings.HasPrefix(ptr.str[ptr.pos:], "...") { // truncated
+			ptr.pos += 3
+			continue
+		}
+		key, err := ptr.parseKey()
+		if err != nil {
+			return doc, err
+		}
+		if err = ptr.expect(':'); err != nil {
+			return doc, err
+		}
+		value, err := ptr.parseValue()
+		if err != nil {
+			return doc, err
+		}
+		doc = append(doc, bson.E{Key: key, Value: value})
+		ptr.skipSpaces()
+		if ptr.peek() == ',' {
+			ptr.pos++
+		} else if ptr.peek() != '}' {
+			return doc, fmt.Errorf("expected '}' at %d", ptr.pos)
+		}
+	}
+}
+
+func (ptr *textParser) parseArray() (bson.A, error) {
+	arr := bson.A{}
+	if err := ptr.expect('['); err != nil {
+		return arr, err
+	}
+	for {
+		ptr.skipSpaces()
+		if ptr.peek() == ']' {
+			ptr.pos++
+			return arr, nil
+		}
+		if strings.HasPrefix(ptr.str[ptr.pos:], "...") { // truncated
+			ptr.pos += 3
+			continue
+		}
+		value, err := ptr.parseValue()
+		if err != nil {
+			return arr, err
+		}
+		arr = append(arr, value)
+		ptr.skipSpaces()
+		if ptr.peek() == ',' {
+			ptr.pos++
+		} else if ptr.peek() != ']' {
+			return arr, fmt.Errorf("expected ']' at %d", ptr.pos)
+		}
+	}
+}
+
+func (ptr *textParser) parseKey() (string, error) {
+	ptr.skipSpaces()
+	if c := ptr.peek(); c == '"' || c == '\'' {
+		return ptr.parseString()
+	}
+	start := ptr.pos
+	for !ptr.eof() && isTextKeyChar(ptr.str[ptr.pos]) {
+		ptr.pos++
+	}
+	if ptr.pos == start {
+		return "", fmt.Errorf("expected a key at %d", ptr.pos)
+	}
+	return ptr.str[start:ptr.pos], nil
+}
+
+func (ptr *textParser) parseString() (string, error) {
+	quote := ptr.str[ptr.pos]
+	ptr.pos++
+	var buf strings.Builder
+	for !ptr.eof() {
+		c := ptr.str[ptr.pos]
+		ptr.pos++
+		if c == '\\' && !ptr.eof() {
+			buf.WriteByte(ptr.str[ptr.pos])
+			ptr.pos++
+		} else if c == quote {
+			return buf.String(), nil
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), errors.New("unterminated string")
+}
+
+func (ptr *textParser) parseRegex() (primitive.Regex, error) {
+	ptr.pos++
+	var buf strings.Builder
+	for !ptr.eof() {
+		c := ptr.str[ptr.pos]
+		ptr.pos++
+		if c == '\\' && !ptr.eof() {
+			buf.WriteByte(c)
+			buf.WriteByte(ptr.str[ptr.pos])
+			ptr.pos++
+		} else if c == '/' {
+			start := ptr.pos
+			for !ptr.eof() && ptr.str[ptr.pos] >= 'a' && ptr.str[ptr.pos] <= 'z' {
+				ptr.pos++
+			}
+			return primitive.Regex{Pattern: buf.String(), Options: ptr.str[start:ptr.pos]}, nil
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return primitive.Regex{}, errors.New("unterminated regex")
+}
+
+// parseValue parses a value, e.g. a document, an array, a string, a number,
+// or a shell type like ObjectId('...'), new Date(1627206960000) and Timestamp(1627206960, 1)
+func (ptr *textParser) parseValue() (interface{}, error) {
+	ptr.skipSpaces()
+	switch ptr.peek() {
+	case '{':
+		return ptr.parseDocument()
+	case '[':
+		return ptr.parseArray()
+	case '"', '\'':
+		return ptr.parseString()
+	case '/':
+		return ptr.parseRegex()
+	case 0:
+		return nil, errors.New("unexpected end of document")
+	}
+	start := ptr.pos
+	for !ptr.eof() && isTextKeyChar(ptr.str[ptr.pos]) {
+		ptr.pos++
+	}
+	token := ptr.str[start:ptr.pos]
+	if token == "new" {
+		ptr.skipSpaces()
+		start = ptr.pos
+		for !ptr.eof() && isTextKeyChar(ptr.str[ptr.pos]) {
+			ptr.pos++
+		}
+		token = ptr.str[start:ptr.pos]
+	}
+	if ptr.peek() == '(' {
+		args, err := ptr.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return toShellValue(token, args), nil
+	}
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected '%c' at %d", ptr.peek(), ptr.pos)
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "undefined":
+		return nil, nil
+	case "MinKey":
+		return primitive.MinKey{}, nil
+	case "MaxKey":
+		return primitive.MaxKey{}, nil
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		if n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int32(n), nil
+		}
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f, nil
+	}
+	return token, nil
+}
+
+// parseArgs returns comma-separated arguments of a shell type
+func (ptr *textParser) parseArgs() ([]string, error) {
+	ptr.pos++ // (
+	args := []string{}
+	for {
+		ptr.skipSpaces()
+		if ptr.peek() == ')' {
+			ptr.pos++
+			return args, nil
+		}
+		var arg string
+		var err error
+		if c := ptr.peek(); c == '"' || c == '\'' {
+			if arg, err = ptr.parseString(); err != nil {
+				return args, err
+			}
+		} else {
+			start := ptr.pos
+			for !ptr.eof() && ptr.str[ptr.pos] != ',' && ptr.str[ptr.pos] != ')' {
+				ptr.pos++
+			}
+			arg = strings.TrimSpace(ptr.str[start:ptr.pos])
+		}
+		args = append(args, arg)
+		ptr.skipSpaces()
+		if ptr.peek() == ',' {
+			ptr.pos++
+		} else if ptr.peek() != ')' {
+			return args, fmt.Errorf("expected ')' at %d", ptr.pos)
+		}
+	}
+}
+
+// toShellValue converts a shell type to its BSON value
+func toShellValue(name string, args []string) interface{} {
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	switch name {
+	case "ObjectId":
+		if oid, err := primitive.ObjectIDFromHex(arg); err == nil {
+			return oid
+		}
+	case "Date", "ISODate":
+		if ms, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			return primitive.DateTime(ms)
+		}
+		if t, err := time.Parse(time.RFC3339Nano, arg); err == nil {
+			return primitive.NewDateTimeFromTime(t)
+		}
+	case "Timestamp":
+		if len(args) == 2 {
+			return primitive.Timestamp{T: uint32(toNumber(args[0])), I: uint32(toNumber(args[1]))}
+		}
+	case "NumberInt":
+		return int32(toNumber(arg))
+	case "NumberLong":
+		if n, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			return n
+		}
+	case "NumberDecimal":
+		if d, err := primitive.ParseDecimal128(arg); err == nil {
+			return d
+		}
+	case "BinData":
+		if len(args) == 2 {
+			subtype := byte(toNumber(args[0]))
+			if data, err := hex.DecodeString(args[1]); err == nil {
+				return primitive.Binary{Subtype: subtype, Data: data}
+			}
+			if data, err := base64.StdEncoding.DecodeString(args[1]); err == nil {
+				return primitive.Binary{Subtype: subtype, Data: data}
+			}
+		}
+	case "UUID":
+		if data, err := hex.DecodeString(strings.ReplaceAll(arg, "-", "")); err == nil {
+			return primitive.Binary{Subtype: 4, Data: data}
+		}
+	}
+	return fmt.Sprintf("%v(%v)", name, strings.Join(args, ", "))
+}
+
+func toNumber(str string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	return n
+}
+
+func isTextKeyChar(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c == '-' || c == '+' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * textlog_test.go
+ */
+
+package hatchet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseTextLogSlowOps(t *testing.T) {
+	tests := []struct {
+		line    string
+		op      string
+		ns      string
+		pattern string
+		index   string
+		milli   int
+		reslen  int
+	}{
+		{
+			line: `2021-07-25T09:56:00.691+0000 I  COMMAND  [conn12] command test.users appName: "MongoDB Shell" command: find { find: "users", filter: { name: "simagix", age: { $gt: 30 } }, sort: { age: -1 }, lsid: { id: UUID("7a9d6bd0-4c4e-4b4c-9a35-1c4a2c4c9c1a") }, $clusterTime: { clusterTime: Timestamp(1627206960, 1), signature: { hash: BinData(0, 0000000000000000000000000000000000000000), keyId: 0 } }, $db: "test" } planSummary: IXSCAN { name: 1, age: -1 } keysExamined:10 docsExamined:10 cursorExhausted:1 numYields:0 nreturned:10 reslen:1532 locks:{ Global: { acquireCount: { r: 1 } } } storage:{} protocol:op_msg 105ms`,
+			op:   "find", ns: "test.users", pattern: `{ age:{ $gt:1 }, name:1 }`, index: "{ name:1, age:-1 }", milli: 105, reslen: 1532,
+		},
+		{
+			line: `2021-07-25T09:56:01.000-0400 I  WRITE    [conn13] update test.users command: { q: { name: "simagix" }, u: { $set: { score: 1.5, ref: ObjectId('5f1b1c2d3e4f5a6b7c8d9e0f') } }, multi: false, upsert: false } planSummary: IDHACK keysExamined:1 docsExamined:1 nMatched:1 nModified:1 numYields:0 locks:{} 12ms`,
+			op:   "update", ns: "test.users", pattern: `{ name:1 }`, index: "IDHACK", milli: 12,
+		},
+		{
+			line: `2018-07-25T09:56:02.000+0000 I  WRITE    [conn14] remove test.logs query: { date: { $lt: new Date(1532512562000) } } planSummary: COLLSCAN keysExamined:0 docsExamined:1000 ndeleted:10 numYields:7 locks:{} 250ms`,
+			op:   "remove", ns: "test.logs", pattern: `{ date:{ $lt:1 } }`, index: "COLLSCAN", milli: 250,
+		},
+		{
+			line: `2021-07-25T09:56:03.000+0000 I  COMMAND  [conn15] command test.orders command: aggregate { aggregate: "orders", pipeline: [ { $match: { status: "A" } }, { $group: { _id: "$cust_id", total: { $sum: "$amount" } } } ], cursor: {}, $db: "test" } planSummary: COLLSCAN keysExamined:0 docsExamined:5000 numYields:39 nreturned:5 reslen:410 locks:{} protocol:op_msg 1203ms`,
+			op:   "aggregate", ns: "test.orders", pattern: `{ status:1 }`, index: "COLLSCAN", milli: 1203, reslen: 410,
+		},
+	}
+	for _, test := range tests {
+		doc := Logv2Info{}
+		if err := ParseLogLine(test.line, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Msg != "Slow query" {
+			t.Fatalf("expected slow query, got %v", doc.Msg)
+		}
+		stat, err := AnalyzeSlowOp(&doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Op != test.op || stat.Namespace != test.ns || stat.QueryPattern != test.pattern ||
+			stat.Index != test.index || stat.TotalMilli != test.milli || stat.Reslen != test.reslen {
+			t.Fatalf("unexpected %+v", stat)
+		}
+	}
+}
+
+func TestParseTextLogConnections(t *testing.T) {
+	doc := Logv2Info{}
+	line := `2021-07-25T09:56:00.691+0000 I  NETWORK  [listener] connection accepted from 127.0.0.1:53532 #12 (3 connections now open)`
+	if err := ParseLogLine(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+	AddLegacyString(&doc)
+	if doc.Client == nil || doc.Client.IP != "127.0.0.1" || doc.Client.Accepted != 1 || doc.Client.Conns != 3 {
+		t.Fatalf("unexpected client %+v", doc.Client)
+	}
+
+	doc = Logv2Info{}
+	line = `2021-07-25T09:56:00.700+0000 I  NETWORK  [conn12] received client metadata from 127.0.0.1:53532 conn12: { driver: { name: "PyMongo", version: "3.11.0" }, os: { type: "Linux", name: "Linux", architecture: "x86_64" }, platform: "CPython 3.8.5.final.0" }`
+	if err := ParseLogLine(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+	AddLegacyString(&doc)
+	if doc.Client == nil || doc.Client.Driver != "PyMongo" || doc.Client.Version != "3.11.0" {
+		t.Fatalf("unexpected client %+v", doc.Client)
+	}
+
+	doc = Logv2Info{}
+	line = `2021-07-25T09:55:00.000+0000 I  CONTROL  [initandlisten] db version v4.2.8`
+	if err := ParseLogLine(line, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Msg != "Build Info" || BsonD2M(doc.Attr)["buildInfo"].(bson.M)["version"] != "4.2.8" {
+		t.Fatalf("unexpected build info %v", doc.Attr)
+	}
+}
+
+func TestTextParserValues(t *testing.T) {
+	parser := &textParser{str: `{ a: NumberLong(5000000000), b: /^abc/i, c: [ 1, 2.5, "x" ], "d.e": true, f: null, g: MinKey, _id: ObjectId('5f1b1c2d3e4f5a6b7c8d9e0f'), h: ... }`}
+	doc, err := parser.parseDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := BsonD2M(doc)
+	if m["a"] != int64(5000000000) || m["b"] != (primitive.Regex{Pattern: "^abc", Options: "i"}) ||
+		len(m["c"].([]interface{})) != 3 || m["d.e"] != true || m["f"] != nil ||
+		m["_id"].(primitive.ObjectID).Hex() != "5f1b1c2d3e4f5a6b7c8d9e0f" {
+		t.Fatalf("unexpected %v", m)
+	}
+}
+
+func TestIsMongoDBTextLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "mongod.log")
+	line := `2021-07-25T09:55:00.000+0000 I  CONTROL  [initandlisten] MongoDB starting : pid=1 port=27017 dbpath=/data/db 64-bit host=localhost`
+	if err := os.WriteFile(filename, []byte(line+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !isMongoDBLog(filename) {
+		t.Error("expected a legacy text log to be a MongoDB log")
+	}
+}
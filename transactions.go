@@ -0,0 +1,147 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * transactions.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TXN_ABORTED   = "aborted"
+	TXN_COMMITTED = "committed"
+
+	RETRY_STORM_ABORTS = 3 // aborted transactions of a session within a minute
+)
+
+// Transaction stores a "transaction" log line, or a slow op of a transaction, a retryable
+// write, or with write or prepare conflicts
+type Transaction struct {
+	Autocommit            string `json:"autocommit" bson:"autocommit"` // false of multi-document transactions
+	Date                  string `json:"date" bson:"date"`
+	LSID                  string `json:"lsid" bson:"lsid"` // lsid.id of the session
+	Milli                 int    `json:"milli" bson:"milli"`
+	Namespace             string `json:"ns" bson:"ns"`
+	Op                    string `json:"op" bson:"op"`
+	PrepareConflictMillis int    `json:"prepare_conflict_ms" bson:"prepare_conflict_ms"`
+	Reason                string `json:"reason" bson:"reason"`           // error name of aborted transactions and failed ops
+	Termination           string `json:"termination" bson:"termination"` // committed or aborted, of transaction lines only
+	TimeActiveMicros      int    `json:"time_active_micros" bson:"time_active_micros"`
+	TimeInactiveMicros    int    `json:"time_inactive_micros" bson:"time_inactive_micros"`
+	TxnNumber             int    `json:"txn_number" bson:"txn_number"`
+	WasPrepared           bool   `json:"was_prepared" bson:"was_prepared"`
+	WriteConflicts        int    `json:"write_conflicts" bson:"write_conflicts"`
+
+	Marker int `json:"-" bson:"marker"`
+}
+
+// TxnHotSpot stores write and prepare conflicts of a namespace
+type TxnHotSpot struct {
+	Count                 int    `json:"count"` // number of ops with conflicts
+	Namespace             string `json:"ns"`
+	PrepareConflictMillis int    `json:"prepare_conflict_ms"`
+	TotalMilli            int    `json:"total_ms"`
+	WriteConflicts        int    `json:"write_conflicts"`
+}
+
+// RetryStorm stores aborted transactions of a session within a minute
+type RetryStorm struct {
+	Aborted        int    `json:"aborted"`
+	Count          int    `json:"count"` // number of transactions and ops
+	LSID           string `json:"lsid"`
+	Minute         string `json:"minute"`
+	TxnNumbers     int    `json:"txn_numbers"` // number of distinct txnNumber
+	WriteConflicts int    `json:"write_conflicts"`
+}
+
+// TransactionStats is the transactions report of a hatchet
+type TransactionStats struct {
+	Aborted      int           `json:"aborted"`
+	Committed    int           `json:"committed"`
+	AbortReasons []NameValue   `json:"abort_reasons"`
+	HotSpots     []TxnHotSpot  `json:"hot_spots"`
+	LongRunning  []Transaction `json:"long_running"`
+	RetryStorms  []RetryStorm  `json:"retry_storms"`
+}
+
+// GetTransaction returns transaction info of a log line, or nil if it is neither of a
+// transaction, a retryable write nor with write or prepare conflicts
+func GetTransaction(doc *Logv2Info, stat *OpStat) *Transaction {
+	isTxnLog := doc.Component == "TXN" && doc.Msg == "transaction"
+	if !isTxnLog && (stat == nil || stat.Namespace == "") {
+		return nil
+	}
+	attrMap := doc.getAttrMap()
+	txn := &Transaction{Marker: doc.Marker}
+	if isTxnLog {
+		params, _ := attrMap["parameters"].(bson.M)
+		setSessionInfo(txn, params)
+		txn.Milli = ToInt(attrMap["durationMillis"])
+		txn.Termination, _ = attrMap["terminationCause"].(string)
+		txn.TimeActiveMicros = ToInt(attrMap["timeActiveMicros"])
+		txn.TimeInactiveMicros = ToInt(attrMap["timeInactiveMicros"])
+		txn.WasPrepared, _ = attrMap["wasPrepared"].(bool)
+		txn.WriteConflicts = ToInt(attrMap["writeConflicts"])
+		txn.PrepareConflictMillis = ToInt(attrMap["prepareConflictDurationMillis"])
+		if txn.Termination == TXN_ABORTED {
+			txn.Reason = getAbortReason(attrMap)
+		}
+		return txn
+	}
+	command, _ := attrMap["command"].(bson.M)
+	_, isTxn := command["txnNumber"]
+	txn.WriteConflicts = ToInt(attrMap["writeConflicts"])
+	txn.PrepareConflictMillis = ToInt(attrMap["prepareConflictDurationMillis"])
+	if !isTxn && txn.WriteConflicts == 0 && txn.PrepareConflictMillis == 0 {
+		return nil
+	}
+	setSessionInfo(txn, command)
+	txn.Milli = doc.Attributes.Milli
+	txn.Namespace = stat.Namespace
+	txn.Op = stat.Op
+	txn.Reason, _ = attrMap["errName"].(string)
+	return txn
+}
+
+// setSessionInfo sets lsid, txnNumber and autocommit from transaction parameters or a command
+func setSessionInfo(txn *Transaction, params bson.M) {
+	if params == nil {
+		return
+	}
+	if lsid, ok := params["lsid"].(bson.M); ok {
+		txn.LSID = getUUIDString(lsid["id"])
+	}
+	txn.TxnNumber = ToInt(params["txnNumber"])
+	if autocommit, ok := params["autocommit"].(bool); ok {
+		txn.Autocommit = fmt.Sprintf("%v", autocommit)
+	}
+}
+
+// getAbortReason returns the error name of an aborted transaction
+func getAbortReason(attrMap bson.M) string {
+	for _, key := range []string{"errName", "abortCause", "terminationDetails", "errMsg"} {
+		if reason, ok := attrMap[key].(string); ok && reason != "" {
+			return reason
+		}
+	}
+	return "unknown"
+}
+
+// getUUIDString formats a UUID of an lsid, e.g. 7e8a2c5e-1c3f-4a0e-9e6b-2f0c8d7b1a11
+func getUUIDString(v interface{}) string {
+	if id, ok := v.(primitive.Binary); ok {
+		b := id.Data
+		if len(b) == 16 {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+		}
+		return fmt.Sprintf("%x", b)
+	} else if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
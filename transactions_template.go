@@ -0,0 +1,136 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * transactions_template.go
+ */
+
+package hatchet
+
+import (
+	"html/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// GetTransactionsTemplate returns HTML of the transactions report
+func GetTransactionsTemplate(download string) (*template.Template, error) {
+	html := headers
+	if download == "" {
+		html = getContentHTML()
+	}
+	html += `
+<script>
+	function downloadTransactions() {
+		anchor = document.createElement('a');
+		anchor.download = '{{.Hatchet}}_transactions.html';
+		anchor.href = '/hatchets/{{.Hatchet}}/stats/transactions?download=true';
+		anchor.dataset.downloadurl = ['text/html', anchor.download, anchor.href].join(':');
+		anchor.click();
+	}
+</script>
+<div align='left'>`
+	if download == "" {
+		html += `
+<!-- Header Bar -->
+<div style='display: flex; justify-content: space-between; align-items: center; margin-bottom: 15px;'>
+	<h2 style='margin: 0; color: #444; font-size: 1.4em;'><i class='fa fa-exchange' style='color: #1565c0;'></i> Transactions</h2>
+	<button id="download" onClick="downloadTransactions(); return false;"
+		class="download-btn"><i class="fa fa-download"></i> Download</button>
+</div>`
+	} else {
+		html += "<div align='center'>{{.Summary}}</div>"
+	}
+	html += `
+{{ $stats := .Stats }}
+<p>{{ numPrinter $stats.Committed }} committed and {{ numPrinter $stats.Aborted }} aborted transactions were logged.</p>
+
+<h3>Abort Reasons</h3>
+{{ if $stats.AbortReasons }}
+	<table>
+		<tr><th>#</th><th>reason</th><th>count</th></tr>
+	{{ range $n, $value := $stats.AbortReasons }}
+		<tr><td align='right'>{{ add $n 1 }}</td><td>{{ $value.Name }}</td><td align='right'>{{ numPrinter $value.Value }}</td></tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No aborted transaction is found.</p>
+{{ end }}
+
+<h3>Long-Running Transactions</h3>
+{{ if $stats.LongRunning }}
+	<table width='100%'>
+		<tr><th>#</th><th>date</th><th>lsid</th><th>txnNumber</th><th>termination</th><th>duration ms</th>
+			<th>active &micro;s</th><th>inactive &micro;s</th><th>write conflicts</th><th>prepared</th></tr>
+	{{ range $n, $value := $stats.LongRunning }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td>{{ $value.Date }}</td>
+			<td class='break'>{{ $value.LSID }}</td>
+			<td align='right'>{{ $value.TxnNumber }}</td>
+			<td>{{ if eq $value.Termination "aborted" }}<span style='color:red;' title='{{ $value.Reason }}'>{{ $value.Termination }}</span>{{ else }}{{ $value.Termination }}{{ end }}</td>
+			<td align='right'>{{ numPrinter $value.Milli }}</td>
+			<td align='right'>{{ numPrinter $value.TimeActiveMicros }}</td>
+			<td align='right'>{{ numPrinter $value.TimeInactiveMicros }}</td>
+			<td align='right'>{{ numPrinter $value.WriteConflicts }}</td>
+			<td>{{ if $value.WasPrepared }}yes{{ end }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No transaction is logged.</p>
+{{ end }}
+
+<h3>Conflict Hot Spots</h3>
+{{ if $stats.HotSpots }}
+	<table>
+		<tr><th>#</th><th>namespace</th><th>ops</th><th>write conflicts</th><th>prepare conflict ms</th><th>total ms</th></tr>
+	{{ range $n, $value := $stats.HotSpots }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td class='break'>{{ $value.Namespace }}</td>
+			<td align='right'>{{ numPrinter $value.Count }}</td>
+			<td align='right'>{{ numPrinter $value.WriteConflicts }}</td>
+			<td align='right'>{{ numPrinter $value.PrepareConflictMillis }}</td>
+			<td align='right'>{{ numPrinter $value.TotalMilli }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No write or prepare conflict is found.</p>
+{{ end }}
+
+<h3>Retry Storms</h3>
+<p>Sessions aborting {{ .RetryStormAborts }} or more transactions within a minute.</p>
+{{ if $stats.RetryStorms }}
+	<table>
+		<tr><th>#</th><th>minute</th><th>lsid</th><th>aborted</th><th>txnNumbers</th><th>logs</th><th>write conflicts</th></tr>
+	{{ range $n, $value := $stats.RetryStorms }}
+		<tr>
+			<td align='right'>{{ add $n 1 }}</td>
+			<td>{{ $value.Minute }}</td>
+			<td class='break'>{{ $value.LSID }}</td>
+			<td align='right'>{{ numPrinter $value.Aborted }}</td>
+			<td align='right'>{{ numPrinter $value.TxnNumbers }}</td>
+			<td align='right'>{{ numPrinter $value.Count }}</td>
+			<td align='right'>{{ numPrinter $value.WriteConflicts }}</td>
+		</tr>
+	{{ end }}
+	</table>
+{{ else }}
+	<p>No retry storm is found.</p>
+{{ end }}
+	<div align='center'><hr/><p/>{{.Version}}</div>
+</div>`
+	if download == "" {
+		html += "</div><!-- end content-container -->"
+	}
+	html += "</body></html>"
+	return template.New("hatchet").Funcs(template.FuncMap{
+		"add": func(a int, b int) int {
+			return a + b
+		},
+		"numPrinter": func(n interface{}) string {
+			printer := message.NewPrinter(language.English)
+			return printer.Sprintf("%v", ToInt(n))
+		}}).Parse(html)
+}
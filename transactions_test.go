@@ -0,0 +1,103 @@
+/*
+ * Copyright 2022-present Kuei-chun Chen. All rights reserved.
+ * transactions_test.go
+ */
+
+package hatchet
+
+import (
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const txnLSID = "7e8a2c5e-1c3f-4a0e-9e6b-2f0c8d7b1a11"
+
+const txnLog = `{"t":{"$date":"2024-03-18T10:00:0%d.000+00:00"},"s":"I","c":"TXN","id":51802,"ctx":"conn7","msg":"transaction","attr":{"parameters":{"lsid":{"id":{"$uuid":"` + txnLSID + `"},"uid":{"$binary":{"base64":"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=","subType":"0"}}},"txnNumber":%d,"autocommit":false,"readConcern":{"level":"snapshot"}},"terminationCause":"%v",%v"timeActiveMicros":%d,"timeInactiveMicros":%d,"numYields":0,"wasPrepared":false,"durationMillis":%d}}`
+
+const txnOpLog = `{"t":{"$date":"2024-03-18T10:00:08.000+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn7","msg":"Slow query","attr":{"type":"update","ns":"test.orders","command":{"q":{"_id":1},"u":{"$inc":{"qty":-1}},"multi":false,"upsert":false},"planSummary":"IDHACK","keysExamined":1,"docsExamined":1,"nMatched":1,"nModified":1,"writeConflicts":5,"prepareConflictDurationMillis":20,"numYields":0,"durationMillis":150}}`
+
+const txnCmdLog = `{"t":{"$date":"2024-03-18T10:00:09.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn7","msg":"Slow query","attr":{"type":"command","ns":"test.orders","command":{"find":"orders","filter":{"status":"A"},"lsid":{"id":{"$uuid":"` + txnLSID + `"}},"txnNumber":4,"autocommit":false,"$db":"test"},"planSummary":"COLLSCAN","docsExamined":100,"nreturned":1,"durationMillis":120}}`
+
+func TestGetTransaction(t *testing.T) {
+	doc := Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(fmt.Sprintf(txnLog, 1, 3, TXN_ABORTED, `"errName":"WriteConflict",`, 900, 100, 1)), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ := AnalyzeSlowOp(&doc)
+	txn := GetTransaction(&doc, stat)
+	if txn == nil || txn.LSID != txnLSID || txn.TxnNumber != 3 || txn.Autocommit != "false" || txn.Termination != TXN_ABORTED ||
+		txn.Reason != "WriteConflict" || txn.TimeActiveMicros != 900 || txn.TimeInactiveMicros != 100 || txn.Milli != 1 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+
+	doc = Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(txnCmdLog), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ = AnalyzeSlowOp(&doc)
+	if txn = GetTransaction(&doc, stat); txn == nil || txn.LSID != txnLSID || txn.TxnNumber != 4 || txn.Namespace != "test.orders" ||
+		txn.Op != "find" || txn.Termination != "" || txn.Milli != 120 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+
+	doc = Logv2Info{}
+	if err := bson.UnmarshalExtJSON([]byte(txnOpLog), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ = AnalyzeSlowOp(&doc)
+	if txn = GetTransaction(&doc, stat); txn == nil || txn.LSID != "" || txn.WriteConflicts != 5 || txn.PrepareConflictMillis != 20 ||
+		txn.Namespace != "test.orders" || txn.Op != "update" {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+
+	doc = Logv2Info{}
+	str := `{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn1","msg":"Slow query","attr":{"type":"command","ns":"test.users","command":{"find":"users","filter":{"status":"A"},"$db":"test"},"planSummary":"COLLSCAN","durationMillis":100}}`
+	if err := bson.UnmarshalExtJSON([]byte(str), false, &doc); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ = AnalyzeSlowOp(&doc)
+	if txn = GetTransaction(&doc, stat); txn != nil {
+		t.Fatalf("expected no transaction, got %+v", txn)
+	}
+}
+
+func TestGetTransactionStats(t *testing.T) {
+	lines := []string{
+		fmt.Sprintf(txnLog, 1, 1, TXN_COMMITTED, "", 5000, 200, 2500),
+		fmt.Sprintf(txnLog, 2, 2, TXN_ABORTED, `"errName":"WriteConflict",`, 300, 10, 30),
+		fmt.Sprintf(txnLog, 3, 3, TXN_ABORTED, `"errName":"WriteConflict",`, 300, 10, 40),
+		fmt.Sprintf(txnLog, 4, 4, TXN_ABORTED, `"errName":"NoSuchTransaction",`, 300, 10, 50),
+		txnOpLog,
+		txnCmdLog,
+	}
+	dbase := analyzeTestLogs(t, "txns", lines)
+	stats, err := dbase.GetTransactionStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Committed != 1 || stats.Aborted != 3 {
+		t.Fatalf("expected 1 committed and 3 aborted, got %+v", stats)
+	}
+	if len(stats.AbortReasons) != 2 || stats.AbortReasons[0].Name != "WriteConflict" || stats.AbortReasons[0].Value != 2 {
+		t.Fatalf("unexpected abort reasons %+v", stats.AbortReasons)
+	}
+	if len(stats.LongRunning) != 4 || stats.LongRunning[0].Milli != 2500 || stats.LongRunning[0].TimeActiveMicros != 5000 {
+		t.Fatalf("unexpected long-running transactions %+v", stats.LongRunning)
+	}
+	if len(stats.HotSpots) != 1 || stats.HotSpots[0].Namespace != "test.orders" || stats.HotSpots[0].WriteConflicts != 5 ||
+		stats.HotSpots[0].PrepareConflictMillis != 20 {
+		t.Fatalf("unexpected hot spots %+v", stats.HotSpots)
+	}
+	if len(stats.RetryStorms) != 1 || stats.RetryStorms[0].LSID != txnLSID || stats.RetryStorms[0].Aborted != 3 ||
+		stats.RetryStorms[0].Count != 5 || stats.RetryStorms[0].TxnNumbers != 4 {
+		t.Fatalf("unexpected retry storms %+v", stats.RetryStorms)
+	}
+	templ, err := GetTransactionsTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]interface{}{"Hatchet": "txns", "RetryStormAborts": RETRY_STORM_ABORTS, "Stats": stats, "Summary": "", "Version": ""}
+	executeTestTemplate(t, templ, doc)
+}
@@ -108,7 +108,7 @@ func UploadHandler(w http.ResponseWriter, r *http.Request, params httprouter.Par
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status": "error",
-			"error":  "Not a valid MongoDB log file. File must contain MongoDB logv2 JSON format.",
+			"error":  "Not a valid MongoDB log file. File must contain MongoDB logv2 JSON or legacy text format.",
 		})
 		return
 	}
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"fmt"
+	"io"
 	"math/rand"
 	"path/filepath"
 	"regexp"
@@ -18,6 +19,7 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/klauspost/compress/zstd"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -31,6 +33,7 @@ var (
 	reNSMatch     = regexp.MustCompile(`^[^\d][^$.\n\s@]*\.[^.\n\s@]*([.][^.\n\s@]*)?$`)
 	reSSNMatch    = regexp.MustCompile(`\d{3}-\d{2}-\d{4}`)
 	reAlpha       = regexp.MustCompile("[a-zA-Z]")
+	reDateMatch   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(T[\d:.]*)?(Z|[+-]\d{2}:?\d{2})?$`)
 	reNonDigitPls = regexp.MustCompile("[^0-9+]+")
 	rePhoneMatch  = regexp.MustCompile(`(?:\+?\d{1,3}[- ]?)?\d{10,14}|(\+\d{1,3}\s?)?\(\d{3}\)\s?\d{3}[- ]?\d{4}|\d{3}[- ]?\d{3}[- ]?\d{4}`)
 )
@@ -235,6 +238,16 @@ func GetOffsetLimit(str string) (int, int) {
 	return 0, 0
 }
 
+// GetDuration returns start and end dates of a duration of start,end, e.g.
+// 2024-03-18T10:00:00.000-0000,2024-03-18T11:00:00.000-0000
+func GetDuration(duration string) (string, string, error) {
+	dates := strings.Split(duration, ",")
+	if len(dates) != 2 || !reDateMatch.MatchString(dates[0]) || !reDateMatch.MatchString(dates[1]) {
+		return "", "", fmt.Errorf("invalid duration %v", duration)
+	}
+	return dates[0], dates[1], nil
+}
+
 func getDateTimeStr(tm time.Time) string {
 	dt := tm.Format("2006-01-02T15:04:05.000-0000")
 	return dt
@@ -264,6 +277,47 @@ func GetBufioReader(data []byte) (*bufio.Reader, error) {
 	return bufio.NewReader(bytes.NewReader(data)), nil
 }
 
+// NewStreamReader returns a reader of a stream, decompressed on the fly if gzip or zstd
+func NewStreamReader(stream io.Reader) (*bufio.Reader, error) {
+	reader := bufio.NewReader(stream)
+	buf, err := reader.Peek(4)
+	if err != nil && len(buf) < 2 {
+		return reader, err
+	}
+	if buf[0] == 0x1f && buf[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(gzipReader), nil
+	} else if bytes.Equal(buf, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		zstdReader, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(&zstdStreamReader{decoder: zstdReader}), nil
+	}
+	return reader, nil
+}
+
+// zstdStreamReader closes its decoder once the stream is read
+type zstdStreamReader struct {
+	decoder *zstd.Decoder
+	err     error
+}
+
+func (ptr *zstdStreamReader) Read(p []byte) (int, error) {
+	if ptr.err != nil {
+		return 0, ptr.err
+	}
+	n, err := ptr.decoder.Read(p)
+	if err != nil {
+		ptr.err = err
+		ptr.decoder.Close()
+	}
+	return n, err
+}
+
 func ContainsCreditCardNo(card string) bool {
 	cardNo := []byte{}
 	for i := range card {
@@ -383,6 +437,18 @@ func BsonD2M(d bson.D) bson.M {
 	return m
 }
 
+// hasAnyKey returns true if a bson.D has any of keys at the top level
+func hasAnyKey(d bson.D, keys []string) bool {
+	for _, elem := range d {
+		for _, key := range keys {
+			if elem.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // convertBsonValue recursively converts bson.D and bson.A to map and slice
 func convertBsonValue(v interface{}) interface{} {
 	switch val := v.(type) {
@@ -3,8 +3,12 @@
 package hatchet
 
 import (
+	"bytes"
+	"io"
+	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -167,6 +171,34 @@ func TestGetOffsetLimit(t *testing.T) {
 	}
 }
 
+func TestGetDuration(t *testing.T) {
+	start, end, err := GetDuration("2024-03-18T10:00:00.000-0000,2024-03-18T11:00:00")
+	if err != nil || start != "2024-03-18T10:00:00.000-0000" || end != "2024-03-18T11:00:00" {
+		t.Fatal("unexpected", start, end, err)
+	}
+	for _, duration := range []string{"2024-03-18T10:00:00", "2024-03-18,2024-03-19,2024-03-20", "2024-03-18,2024-03-19' OR 1=1"} {
+		if _, _, err = GetDuration(duration); err == nil {
+			t.Fatal("expected an error of duration", duration)
+		}
+	}
+}
+
+func TestNewStreamReaderZstd(t *testing.T) {
+	logs := strings.Repeat(`{"t":{"$date":"2024-03-18T10:00:00.000+00:00"},"s":"I","c":"NETWORK","id":22943,"msg":"Connection accepted"}`+"\n", 100)
+	encoder, _ := zstd.NewWriter(nil)
+	reader, err := NewStreamReader(bytes.NewReader(encoder.EncodeAll([]byte(logs), nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil || string(content) != logs {
+		t.Fatalf("expected %d bytes of logs, got %d, %v", len(logs), len(content), err)
+	}
+	if _, err = reader.ReadByte(); err != io.EOF {
+		t.Fatal("expected EOF after the decoder is closed, got", err)
+	}
+}
+
 func TestContainsCreditCardNo(t *testing.T) {
 	validCases := []struct {
 		input    string